@@ -1,29 +1,24 @@
 package api
 
 import (
-	"fmt"
+	"reflect"
+	"sort"
+	"sync"
 
 	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/adapter"
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+	"github.com/woongchantonylee/go-bluetooth/props"
 	log "github.com/sirupsen/logrus"
 )
 
 // const baseAdvertismentPath = "/org/bluez/%s/apps/advertisement%d"
 const BaseAdvertismentPath = "/%s/advertisement/%d"
 
-var advertisingCount int = -1
-
+// nextAdvertismentPath reserve the first free advertisement path for the adapter
 func nextAdvertismentPath(adapterID string) dbus.ObjectPath {
-	advertisingCount++
-	return dbus.ObjectPath(fmt.Sprintf(BaseAdvertismentPath, adapterID, advertisingCount))
-}
-
-func decreaseAdvertismentCounter() {
-	advertisingCount--
-	if advertisingCount < -1 {
-		advertisingCount = -1
-	}
+	return bluez.NewObjectPath(BaseAdvertismentPath, adapterID)
 }
 
 type Advertisement struct {
@@ -32,6 +27,10 @@ type Advertisement struct {
 	iprops        *DBusProperties
 	conn          *dbus.Conn
 	props         *advertising.LEAdvertisement1Properties
+	onRelease     func(a *Advertisement)
+
+	lock    sync.Mutex
+	removed bool
 }
 
 func (a *Advertisement) DBusConn() *dbus.Conn {
@@ -58,17 +57,108 @@ func (a *Advertisement) Interface() string {
 	return advertising.LEAdvertisement1Interface
 }
 
-func NewAdvertisement(adapterID string, props *advertising.LEAdvertisement1Properties) (*Advertisement, error) {
+// Release is called by BlueZ when the advertisement is removed by the
+// adapter, eg. when Timeout expires or the adapter is powered off
+func (a *Advertisement) Release() *dbus.Error {
+	log.Debugf("Advertisement.Release %s", a.path)
+	if a.onRelease != nil {
+		a.onRelease(a)
+	}
+	return nil
+}
 
-	adv := new(Advertisement)
+// OnRelease set the callback called when BlueZ release the advertisement
+func (a *Advertisement) OnRelease(fx func(a *Advertisement)) {
+	a.onRelease = fx
+}
 
-	adv.props = props
-	adv.path = nextAdvertismentPath(adapterID)
+// SetProperties replace the advertisement properties. Once exposed, the
+// changes are signalled with PropertiesChanged and BlueZ refreshes the data
+// of the registered advertisement in place.
+func (a *Advertisement) SetProperties(p *advertising.LEAdvertisement1Properties) error {
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.removed {
+		return ErrAdvertisementNotFound
+	}
+
+	old := a.iprops.propsConfig[a.Interface()]
+
+	a.props = p
+	err := a.iprops.AddProperties(a.Interface(), p)
+	if err != nil {
+		return err
+	}
+
+	if a.iprops.Instance() == nil {
+		return nil
+	}
+	a.iprops.Expose(a.path)
+
+	return a.emitChanged(old)
+}
+
+// emitChanged signal the properties changed since old
+func (a *Advertisement) emitChanged(old map[string]*props.PropInfo) error {
+
+	current := a.iprops.propsConfig[a.Interface()]
+
+	changed := map[string]dbus.Variant{}
+	for name, info := range current {
+		if info.Skip || info.Value == nil {
+			continue
+		}
+		if prev, ok := old[name]; ok && !prev.Skip && reflect.DeepEqual(prev.Value, info.Value) {
+			continue
+		}
+		changed[name] = dbus.MakeVariant(info.Value)
+	}
+
+	invalidated := []string{}
+	for name, info := range old {
+		if info.Skip {
+			continue
+		}
+		if cur, ok := current[name]; !ok || cur.Skip {
+			invalidated = append(invalidated, name)
+		}
+	}
+	sort.Strings(invalidated)
+
+	if len(changed) == 0 && len(invalidated) == 0 {
+		return nil
+	}
+
+	return a.conn.Emit(a.path, bluez.PropertiesChanged, a.Interface(), changed, invalidated)
+}
+
+// Remove unexport the advertisement and release its object path
+func (a *Advertisement) Remove() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.removed = true
+	defer bluez.ReleaseObjectPath(a.path)
+	return RemoveDBusService(a)
+}
+
+func NewAdvertisement(adapterID string, props *advertising.LEAdvertisement1Properties) (*Advertisement, error) {
 
 	conn, err := dbus.SystemBus()
 	if err != nil {
 		return nil, err
 	}
+
+	return newAdvertisement(conn, adapterID, props)
+}
+
+// newAdvertisement create an advertisement exported on conn
+func newAdvertisement(conn *dbus.Conn, adapterID string, props *advertising.LEAdvertisement1Properties) (*Advertisement, error) {
+
+	adv := new(Advertisement)
+
+	adv.props = props
 	adv.conn = conn
 
 	om, err := NewDBusObjectManager(conn)
@@ -83,56 +173,53 @@ func NewAdvertisement(adapterID string, props *advertising.LEAdvertisement1Prope
 	}
 	adv.iprops = iprops
 
+	adv.path = nextAdvertismentPath(adapterID)
+
 	return adv, nil
 }
 
 // Expose to bluez an advertisment instance via the adapter advertisement manager
 func ExposeAdvertisement(adapterID string, props *advertising.LEAdvertisement1Properties, discoverableTimeout uint32) (func(), error) {
 
-	log.Tracef("Retrieving adapter instance %s", adapterID)
-	a, err := GetAdapter(adapterID)
-	if err != nil {
-		return nil, err
-	}
-
-	adv, err := NewAdvertisement(adapterID, props)
+	err := props.Fit()
 	if err != nil {
 		return nil, err
 	}
 
-	err = ExposeDBusService(adv)
+	log.Tracef("Retrieving adapter instance %s", adapterID)
+	a, err := GetAdapter(adapterID)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Debug("Setup adapter")
-	err = a.SetDiscoverable(true)
+	adv, err := NewAdvertisement(adapterID, props)
 	if err != nil {
 		return nil, err
 	}
 
-	err = a.SetDiscoverableTimeout(discoverableTimeout)
-	if err != nil {
-		return nil, err
-	}
-	err = a.SetPowered(true)
+	err = exposeAdvertisement(a, adv, discoverableTimeout)
 	if err != nil {
+		removeErr := adv.Remove()
+		if removeErr != nil {
+			log.Warn(removeErr)
+		}
 		return nil, err
 	}
 
 	log.Trace("Registering LEAdvertisement1 instance")
 	advManager, err := advertising.NewLEAdvertisingManager1FromAdapterID(adapterID)
-	if err != nil {
-		return nil, err
+	if err == nil {
+		err = advManager.RegisterAdvertisement(adv.Path(), map[string]interface{}{})
 	}
-
-	err = advManager.RegisterAdvertisement(adv.Path(), map[string]interface{}{})
 	if err != nil {
+		removeErr := adv.Remove()
+		if removeErr != nil {
+			log.Warn(removeErr)
+		}
 		return nil, err
 	}
 
 	cancel := func() {
-		decreaseAdvertismentCounter()
 		err := advManager.UnregisterAdvertisement(adv.Path())
 		if err != nil {
 			log.Warn(err)
@@ -141,7 +228,33 @@ func ExposeAdvertisement(adapterID string, props *advertising.LEAdvertisement1Pr
 		if err != nil {
 			log.Warn(err)
 		}
+		err = adv.Remove()
+		if err != nil {
+			log.Warn(err)
+		}
 	}
 
 	return cancel, nil
 }
+
+// exposeAdvertisement export the advertisement and setup the adapter
+func exposeAdvertisement(a *adapter.Adapter1, adv *Advertisement, discoverableTimeout uint32) error {
+
+	err := ExposeDBusService(adv)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("Setup adapter")
+	err = a.SetDiscoverable(true)
+	if err != nil {
+		return err
+	}
+
+	err = a.SetDiscoverableTimeout(discoverableTimeout)
+	if err != nil {
+		return err
+	}
+
+	return a.SetPowered(true)
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoAdvertisingInstance is returned when the adapter has no free advertising slot
+var ErrNoAdvertisingInstance = errors.New("No advertising instances available")
+
+// ErrAdvertisementNotFound is returned when the advertisement is not handled by the manager
+var ErrAdvertisementNotFound = errors.New("Advertisement not found")
+
+// NewAdvertisementManager create a manager for the advertisements of an adapter
+func NewAdvertisementManager(adapterID string) (*AdvertisementManager, error) {
+
+	a, err := GetAdapter(adapterID)
+	if err != nil {
+		return nil, err
+	}
+
+	advManager, err := advertising.NewLEAdvertisingManager1FromAdapterID(adapterID)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &AdvertisementManager{
+		adapterID:      adapterID,
+		adapter:        a,
+		advManager:     advManager,
+		conn:           conn,
+		advertisements: make(map[dbus.ObjectPath]*Advertisement),
+		rotations:      make(map[dbus.ObjectPath]chan bool),
+	}
+
+	return m, nil
+}
+
+// advertisingManager is the part of LEAdvertisingManager1 used by the manager
+type advertisingManager interface {
+	RegisterAdvertisement(advertisement dbus.ObjectPath, options map[string]interface{}) error
+	UnregisterAdvertisement(advertisement dbus.ObjectPath) error
+	GetSupportedInstances() (byte, error)
+	GetActiveInstances() (byte, error)
+	Close()
+}
+
+// advertisingAdapter is the part of Adapter1 used by the manager
+type advertisingAdapter interface {
+	SetPowered(v bool) error
+}
+
+// AdvertisementManager register and keep track of multiple advertisement
+// sets on an adapter, up to LEAdvertisingManager1.SupportedInstances
+type AdvertisementManager struct {
+	adapterID      string
+	adapter        advertisingAdapter
+	advManager     advertisingManager
+	conn           *dbus.Conn
+	lock           sync.Mutex
+	advertisements map[dbus.ObjectPath]*Advertisement
+	rotations      map[dbus.ObjectPath]chan bool
+	onRelease      func(adv *Advertisement)
+}
+
+// SupportedInstances return the number of advertising slots still available on the adapter
+func (m *AdvertisementManager) SupportedInstances() (int, error) {
+	v, err := m.advManager.GetSupportedInstances()
+	return int(v), err
+}
+
+// ActiveInstances return the number of advertisement registered on the adapter
+func (m *AdvertisementManager) ActiveInstances() (int, error) {
+	v, err := m.advManager.GetActiveInstances()
+	return int(v), err
+}
+
+// GetAdvertisements return the advertisements registered by the manager
+func (m *AdvertisementManager) GetAdvertisements() []*Advertisement {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	list := []*Advertisement{}
+	for _, adv := range m.advertisements {
+		list = append(list, adv)
+	}
+	return list
+}
+
+// OnRelease set a callback called when BlueZ drops one of the advertisements
+func (m *AdvertisementManager) OnRelease(fx func(adv *Advertisement)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.onRelease = fx
+}
+
+// Add expose and register a new advertisement set. A LocalName too long
+// to fit is shortened in place, see LEAdvertisement1Properties.Fit
+func (m *AdvertisementManager) Add(props *advertising.LEAdvertisement1Properties) (*Advertisement, error) {
+
+	err := props.Fit()
+	if err != nil {
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	available, err := m.SupportedInstances()
+	if err != nil {
+		return nil, err
+	}
+	if available == 0 {
+		return nil, ErrNoAdvertisingInstance
+	}
+
+	err = m.adapter.SetPowered(true)
+	if err != nil {
+		return nil, err
+	}
+
+	adv, err := newAdvertisement(m.conn, m.adapterID, props)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ExposeDBusService(adv)
+	if err == nil {
+		err = m.advManager.RegisterAdvertisement(adv.Path(), map[string]interface{}{})
+	}
+	if err != nil {
+		removeErr := adv.Remove()
+		if removeErr != nil {
+			log.Warn(removeErr)
+		}
+		return nil, err
+	}
+
+	adv.OnRelease(m.release)
+	m.advertisements[adv.Path()] = adv
+
+	return adv, nil
+}
+
+// Update replace the payload of a registered advertisement in place. The
+// advertisement keeps its object path and slot, BlueZ refreshes the data
+// on PropertiesChanged. props is fitted as in Add.
+func (m *AdvertisementManager) Update(adv *Advertisement, props *advertising.LEAdvertisement1Properties) error {
+
+	m.lock.Lock()
+	_, ok := m.advertisements[adv.Path()]
+	m.lock.Unlock()
+	if !ok {
+		return ErrAdvertisementNotFound
+	}
+
+	err := props.Fit()
+	if err != nil {
+		return err
+	}
+
+	return adv.SetProperties(props)
+}
+
+// Remove unregister and unexport an advertisement
+func (m *AdvertisementManager) Remove(adv *Advertisement) error {
+
+	m.stopRotation(adv.Path())
+
+	m.lock.Lock()
+	_, ok := m.advertisements[adv.Path()]
+	delete(m.advertisements, adv.Path())
+	m.lock.Unlock()
+	if !ok {
+		return ErrAdvertisementNotFound
+	}
+
+	err := m.advManager.UnregisterAdvertisement(adv.Path())
+	if err != nil {
+		log.Warnf("UnregisterAdvertisement %s: %s", adv.Path(), err)
+	}
+
+	return adv.Remove()
+}
+
+// Rotate register an advertisement cycling through payloads, switching to
+// the next one every interval. Use Remove to stop the rotation.
+func (m *AdvertisementManager) Rotate(interval time.Duration, payloads ...*advertising.LEAdvertisement1Properties) (*Advertisement, error) {
+
+	if len(payloads) == 0 {
+		return nil, errors.New("At least one payload is required")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("Invalid rotation interval %s", interval)
+	}
+
+	adv, err := m.Add(payloads[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payloads) == 1 {
+		return adv, nil
+	}
+
+	done := make(chan bool)
+	m.lock.Lock()
+	m.rotations[adv.Path()] = done
+	m.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		i := 0
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				i = (i + 1) % len(payloads)
+				err := m.Update(adv, payloads[i])
+				if err != nil {
+					log.Warnf("Advertisement rotation %s: %s", adv.Path(), err)
+					if err == ErrAdvertisementNotFound {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return adv, nil
+}
+
+// Close unregister all the advertisements
+func (m *AdvertisementManager) Close() {
+	for _, adv := range m.GetAdvertisements() {
+		err := m.Remove(adv)
+		if err != nil {
+			log.Warnf("Remove advertisement %s: %s", adv.Path(), err)
+		}
+	}
+	m.advManager.Close()
+}
+
+func (m *AdvertisementManager) stopRotation(path dbus.ObjectPath) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if done, ok := m.rotations[path]; ok {
+		close(done)
+		delete(m.rotations, path)
+	}
+}
+
+// release handle the Release callback of an advertisement
+func (m *AdvertisementManager) release(adv *Advertisement) {
+
+	m.stopRotation(adv.Path())
+
+	m.lock.Lock()
+	_, ok := m.advertisements[adv.Path()]
+	delete(m.advertisements, adv.Path())
+	onRelease := m.onRelease
+	m.lock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	err := adv.Remove()
+	if err != nil {
+		log.Warnf("Remove released advertisement %s: %s", adv.Path(), err)
+	}
+
+	if onRelease != nil {
+		onRelease(adv)
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAdvertisingManager record the calls of the manager
+type fakeAdvertisingManager struct {
+	lock  sync.Mutex
+	calls []string
+}
+
+func (f *fakeAdvertisingManager) call(name string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls = append(f.calls, name)
+}
+
+func (f *fakeAdvertisingManager) Calls() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.calls...)
+}
+
+func (f *fakeAdvertisingManager) RegisterAdvertisement(advertisement dbus.ObjectPath, options map[string]interface{}) error {
+	f.call("Register")
+	return nil
+}
+
+func (f *fakeAdvertisingManager) UnregisterAdvertisement(advertisement dbus.ObjectPath) error {
+	f.call("Unregister")
+	return nil
+}
+
+func (f *fakeAdvertisingManager) GetSupportedInstances() (byte, error) {
+	return 4, nil
+}
+
+func (f *fakeAdvertisingManager) GetActiveInstances() (byte, error) {
+	return 0, nil
+}
+
+func (f *fakeAdvertisingManager) Close() {}
+
+type fakeAdvertisingAdapter struct{}
+
+func (a fakeAdvertisingAdapter) SetPowered(v bool) error {
+	return nil
+}
+
+// newSessionConn open a private session bus connection, skipping the test
+// when the session bus is not available
+func newSessionConn(t *testing.T) *dbus.Conn {
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+	if err = conn.Auth(nil); err == nil {
+		err = conn.Hello()
+	}
+	if err != nil {
+		conn.Close()
+		t.Skipf("session bus: %s", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func newTestAdvertisementManager(t *testing.T) (*AdvertisementManager, *fakeAdvertisingManager) {
+	fake := &fakeAdvertisingManager{}
+	return &AdvertisementManager{
+		adapterID:      "hci0",
+		adapter:        fakeAdvertisingAdapter{},
+		advManager:     fake,
+		conn:           newSessionConn(t),
+		advertisements: make(map[dbus.ObjectPath]*Advertisement),
+		rotations:      make(map[dbus.ObjectPath]chan bool),
+	}, fake
+}
+
+// watchLocalName return the LocalName changes of the advertisement at path
+func watchLocalName(t *testing.T, conn *dbus.Conn, path dbus.ObjectPath) chan string {
+
+	match := "type='signal',interface='" + bluez.PropertiesInterface + "',path='" + string(path) + "'"
+	err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Store()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	names := make(chan string, 16)
+	go func() {
+		for sig := range signals {
+			if sig.Name != bluez.PropertiesChanged || sig.Path != path || len(sig.Body) < 2 {
+				continue
+			}
+			changed, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			if name, ok := changed["LocalName"].Value().(string); ok {
+				names <- name
+			}
+		}
+	}()
+	return names
+}
+
+func nextLocalName(t *testing.T, names chan string) string {
+	select {
+	case name := <-names:
+		return name
+	case <-time.After(2 * time.Second):
+		t.Fatal("PropertiesChanged not received")
+	}
+	return ""
+}
+
+func TestRotateInterval(t *testing.T) {
+	m := &AdvertisementManager{}
+	props := &advertising.LEAdvertisement1Properties{
+		Type: advertising.AdvertisementTypeBroadcast,
+	}
+
+	_, err := m.Rotate(0, props, props)
+	assert.NotNil(t, err)
+	_, err = m.Rotate(-1, props)
+	assert.NotNil(t, err)
+	_, err = m.Rotate(1)
+	assert.NotNil(t, err)
+}
+
+func TestAdvertisementManagerUpdate(t *testing.T) {
+
+	m, fake := newTestAdvertisementManager(t)
+
+	adv, err := m.Add(&advertising.LEAdvertisement1Properties{
+		Type:      advertising.AdvertisementTypeBroadcast,
+		LocalName: "first",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := watchLocalName(t, newSessionConn(t), adv.Path())
+
+	err = m.Update(adv, &advertising.LEAdvertisement1Properties{
+		Type:      advertising.AdvertisementTypeBroadcast,
+		LocalName: "second",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "second", nextLocalName(t, names))
+
+	// the advertisement is updated in place
+	assert.Equal(t, []string{"Register"}, fake.Calls())
+
+	assert.Nil(t, m.Remove(adv))
+	assert.Equal(t, []string{"Register", "Unregister"}, fake.Calls())
+	assert.Equal(t, ErrAdvertisementNotFound, m.Update(adv, &advertising.LEAdvertisement1Properties{
+		Type: advertising.AdvertisementTypeBroadcast,
+	}))
+}
+
+func TestAdvertisementManagerRotate(t *testing.T) {
+
+	m, fake := newTestAdvertisementManager(t)
+
+	payloads := []*advertising.LEAdvertisement1Properties{}
+	for _, name := range []string{"first", "second", "third"} {
+		payloads = append(payloads, &advertising.LEAdvertisement1Properties{
+			Type:      advertising.AdvertisementTypeBroadcast,
+			LocalName: name,
+		})
+	}
+
+	adv, err := m.Rotate(20*time.Millisecond, payloads...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := watchLocalName(t, newSessionConn(t), adv.Path())
+
+	// the rotation cycles through the payloads
+	first := nextLocalName(t, names)
+	second := nextLocalName(t, names)
+	assert.NotEqual(t, first, second)
+
+	assert.Nil(t, m.Remove(adv))
+	assert.Equal(t, []string{"Register", "Unregister"}, fake.Calls())
+}
@@ -5,8 +5,10 @@ import (
 	"strings"
 
 	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	log "github.com/sirupsen/logrus"
 )
 
 const appleBit = 0x004C
@@ -14,17 +16,24 @@ const appleBit = 0x004C
 type BeaconType string
 
 const (
-	BeaconTypeEddystone = "eddystone"
-	BeaconTypeIBeacon   = "ibeacon"
+	BeaconTypeEddystone    = "eddystone"
+	BeaconTypeIBeacon      = "ibeacon"
+	BeaconTypeAltBeacon    = "altbeacon"
+	BeaconTypeRuuvi        = "ruuvi"
+	BeaconTypeFindMy       = "findmy"
+	BeaconTypeMicrosoftCDP = "microsoft-cdp"
 )
 
 type Beacon struct {
 	Name      string
 	iBeacon   BeaconIBeacon
 	eddystone BeaconEddystone
-	props     *advertising.LEAdvertisement1Properties
-	Type      BeaconType
-	Device    *device.Device1
+	// info and frames of the formats handled by registered decoders
+	info   interface{}
+	frames []byte
+	props  *advertising.LEAdvertisement1Properties
+	Type   BeaconType
+	Device *device.Device1
 }
 
 func NewBeacon(dev *device.Device1) (Beacon, error) {
@@ -45,7 +54,8 @@ func (b *Beacon) IsIBeacon() bool {
 	return b.Type == BeaconTypeIBeacon
 }
 
-// WatchDeviceChanges watch for properties changes
+// WatchDeviceChanges watch for properties changes, the channel receive the
+// result of Parse when the advertised data change and is closed once ctx is done
 func (b *Beacon) WatchDeviceChanges(ctx context.Context) (chan bool, error) {
 
 	propchanged, err := b.Device.WatchProperties()
@@ -56,31 +66,57 @@ func (b *Beacon) WatchDeviceChanges(ctx context.Context) (chan bool, error) {
 	ch := make(chan bool)
 
 	go func() {
-		for {
+		defer close(ch)
+		runDeviceWatch(ctx, b.Device, propchanged, func(changed *bluez.PropertyChanged) bool {
+			if changed.Name != "ManufacturerData" && changed.Name != "ServiceData" {
+				return true
+			}
 			select {
-			case changed := <-propchanged:
-
-				if changed == nil {
-					ctx.Done()
-					return
-				}
-
-				if changed.Name == "ManufacturerData" || changed.Name == "ServiceData" {
-					ch <- b.Parse()
-				}
-
-				break
+			case ch <- b.Parse():
+				return true
 			case <-ctx.Done():
-				propchanged <- nil
-				close(ch)
-				break
+				return false
 			}
-		}
+		})
 	}()
 
 	return ch, nil
 }
 
+// runDeviceWatch call fx for each property change until ctx is done or fx
+// return false, then stop watching the device
+func runDeviceWatch(ctx context.Context, dev *device.Device1, propchanged chan *bluez.PropertyChanged, fx func(changed *bluez.PropertyChanged) bool) {
+	for {
+		select {
+		case changed := <-propchanged:
+			if changed == nil {
+				// unwatched elsewhere
+				return
+			}
+			if !fx(changed) {
+				unwatchDevice(dev, propchanged)
+				return
+			}
+		case <-ctx.Done():
+			unwatchDevice(dev, propchanged)
+			return
+		}
+	}
+}
+
+// unwatchDevice stop a device properties watch. UnwatchProperties signals the
+// end of the watch on the channel itself, so it is drained meanwhile.
+func unwatchDevice(dev *device.Device1, propchanged chan *bluez.PropertyChanged) {
+	go func() {
+		err := dev.UnwatchProperties(propchanged)
+		if err != nil {
+			log.Warnf("Failed to unwatch %s: %s", dev.Path(), err)
+		}
+	}()
+	for range propchanged {
+	}
+}
+
 // GetEddystone return eddystone beacon information
 func (b *Beacon) GetEddystone() BeaconEddystone {
 	return b.eddystone
@@ -91,13 +127,30 @@ func (b *Beacon) GetIBeacon() BeaconIBeacon {
 	return b.iBeacon
 }
 
-// GetFrames return the bytes content
+// GetInfo return the information of a beacon decoded by a registered decoder,
+// eg. BeaconAltBeacon
+func (b *Beacon) GetInfo() interface{} {
+	return b.info
+}
+
+// GetFrames return the bytes content of the parsed frame, or nil when the
+// beacon has no frame
 func (b *Beacon) GetFrames() []byte {
+	if b.frames != nil {
+		return b.frames
+	}
+	if b.props == nil {
+		return nil
+	}
 	var data interface{}
+	var ok bool
 	if b.IsIBeacon() {
-		data = b.props.ManufacturerData[appleBit].([]byte)
+		data, ok = b.props.ManufacturerData[appleBit]
 	} else {
-		data = b.props.ServiceData[eddystoneSrvcUid].([]byte)
+		data, ok = b.props.ServiceData[eddystoneSrvcUid]
+	}
+	if !ok {
+		return nil
 	}
 	if dataBytes, ok := b.getBytesFromData(data); ok {
 		return dataBytes
@@ -111,10 +164,7 @@ func (b *Beacon) Parse() bool {
 	if b.Device != nil {
 
 		props := b.Device.Properties
-		if b.parserEddystone(props.UUIDs, props.ServiceData) {
-			return true
-		}
-		if b.parserIBeacon(props.ManufacturerData) {
+		if b.parse(props.UUIDs, props.ServiceData, props.ManufacturerData) {
 			return true
 		}
 
@@ -122,10 +172,7 @@ func (b *Beacon) Parse() bool {
 
 	if b.props != nil {
 		props := b.props
-		if b.parserEddystone(props.ServiceUUIDs, props.ServiceData) {
-			return true
-		}
-		if b.parserIBeacon(props.ManufacturerData) {
+		if b.parse(props.ServiceUUIDs, props.ServiceData, props.ManufacturerData) {
 			return true
 		}
 	}
@@ -133,14 +180,34 @@ func (b *Beacon) Parse() bool {
 	return false
 }
 
+func (b *Beacon) parse(UUIDs []string, serviceData map[string]interface{}, manufacturerData map[uint16]interface{}) bool {
+	if b.parserEddystone(UUIDs, serviceData) {
+		return true
+	}
+	if b.parserIBeacon(manufacturerData) {
+		return true
+	}
+	return b.parseRegistered(b.toAdvertisementData(UUIDs, serviceData, manufacturerData))
+}
+
 func (b *Beacon) parserIBeacon(manufacturerData map[uint16]interface{}) bool {
 	if len(manufacturerData) == 0 {
 		return false
 	}
 	if frames, ok := manufacturerData[appleBit]; ok {
-		b.Type = BeaconTypeIBeacon
 		if frameBytes, ok := b.getBytesFromData(frames); ok {
-			b.iBeacon = b.ParseIBeacon(frameBytes)
+			// other Apple frames are left to the registered decoders
+			if len(frameBytes) < 2 || frameBytes[0] != 0x02 || frameBytes[1] != 0x15 {
+				return false
+			}
+			info, err := b.ParseIBeacon(frameBytes)
+			if err != nil {
+				log.Debugf("Invalid iBeacon frame: %s", err)
+				return false
+			}
+			b.Type = BeaconTypeIBeacon
+			b.iBeacon = info
+			b.frames = frameBytes
 			return true
 		}
 	}
@@ -157,10 +224,18 @@ func (b *Beacon) parserEddystone(UUIDs []string, serviceData map[string]interfac
 
 		if strings.ToUpper(uuid) == eddystoneSrvcUid {
 			if data, ok := serviceData[srcUUID]; ok {
-				// log.Debug("Found Eddystone")
+				frames, ok := b.getBytesFromData(data)
+				if !ok {
+					return false
+				}
+				info, err := b.ParseEddystone(frames)
+				if err != nil {
+					log.Debugf("Invalid Eddystone frame: %s", err)
+					return false
+				}
 				b.Type = BeaconTypeEddystone
-				// log.Debugf("Eddystone data: %d", data)
-				b.eddystone = b.ParseEddystone(data.([]byte))
+				b.eddystone = info
+				b.frames = frames
 				return true
 			}
 		}
@@ -0,0 +1,127 @@
+package beacon
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+)
+
+const altBeaconCode = 0xBEAC
+
+// BeaconAltBeacon AltBeacon information
+type BeaconAltBeacon struct {
+	ManufacturerID uint16
+	// ID is the 20 bytes beacon identifier
+	ID string
+	// ID1, ID2 and ID3 split the identifier as a 16 bytes UUID, major and minor
+	ID1           string
+	ID2           uint16
+	ID3           uint16
+	ReferenceRSSI int
+	Reserved      byte
+}
+
+// AltBeacon specifications https://github.com/AltBeacon/spec
+// Byte(s) 	Name 						Notes
+// 0-1 			MFG ID 					Company identifier, little endian
+// ---- Bluez data starts here ----
+// 2-3 			Beacon Code 		0xBEAC
+// 4-23 		Beacon ID 			20 bytes identifier
+// 24 			Reference RSSI 	Signed RSSI at 1m
+// 25 			MFG RSVD 				Reserved for use by the manufacturer
+func decodeAltBeacon(data *AdvertisementData) (interface{}, []byte, bool) {
+	for _, id := range data.ManufacturerIDs() {
+		frames := data.ManufacturerData[id]
+		info, err := ParseAltBeacon(id, frames)
+		if err == nil {
+			return info, frames, true
+		}
+	}
+	return nil, nil, false
+}
+
+// ParseAltBeacon parse the manufacturer data of an AltBeacon
+func ParseAltBeacon(manufacturerID uint16, frames []byte) (BeaconAltBeacon, error) {
+
+	info := BeaconAltBeacon{}
+
+	if len(frames) != 24 {
+		return info, fmt.Errorf("AltBeacon: expected 24 bytes, got %d", len(frames))
+	}
+	if binary.BigEndian.Uint16(frames[0:2]) != altBeaconCode {
+		return info, fmt.Errorf("AltBeacon: invalid beacon code %X", frames[0:2])
+	}
+
+	info.ManufacturerID = manufacturerID
+	info.ID = strings.ToUpper(hex.EncodeToString(frames[2:22]))
+	info.ID1 = strings.ToUpper(hex.EncodeToString(frames[2:18]))
+	info.ID2 = binary.BigEndian.Uint16(frames[18:20])
+	info.ID3 = binary.BigEndian.Uint16(frames[20:22])
+	info.ReferenceRSSI = byteToInt(frames[22])
+	info.Reserved = frames[23]
+
+	return info, nil
+}
+
+func encodeAltBeacon(i interface{}) (*advertising.LEAdvertisement1Properties, error) {
+
+	info, ok := i.(BeaconAltBeacon)
+	if !ok {
+		return nil, fmt.Errorf("AltBeacon: expected BeaconAltBeacon, got %T", i)
+	}
+
+	id := strings.Replace(info.ID, "-", "", -1)
+	if id == "" {
+		id = strings.Replace(info.ID1, "-", "", -1) + fmt.Sprintf("%04X%04X", info.ID2, info.ID3)
+	}
+
+	idBytes, err := hex.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("AltBeacon: invalid ID: %s", err)
+	}
+	if len(idBytes) != 20 {
+		return nil, fmt.Errorf("AltBeacon: ID must be 20 bytes, got %d", len(idBytes))
+	}
+	if info.ReferenceRSSI < -128 || info.ReferenceRSSI > 127 {
+		return nil, fmt.Errorf("AltBeacon: reference RSSI %d out of range", info.ReferenceRSSI)
+	}
+
+	frames := []byte{0xBE, 0xAC}
+	frames = append(frames, idBytes...)
+	frames = append(frames, byte(int8(info.ReferenceRSSI)), info.Reserved)
+
+	props := new(advertising.LEAdvertisement1Properties)
+	props.AddManifacturerData(info.ManufacturerID, frames)
+
+	return props, nil
+}
+
+// CreateAltBeacon create a beacon in the AltBeacon format, the identifier
+// is composed by an UUID (id1) followed by id2 and id3
+func CreateAltBeacon(manufacturerID uint16, id1 string, id2, id3 uint16, referenceRSSI int) (*Beacon, error) {
+	id1 = strings.ToUpper(strings.Replace(id1, "-", "", -1))
+	return CreateBeacon(BeaconTypeAltBeacon, BeaconAltBeacon{
+		ManufacturerID: manufacturerID,
+		ID:             id1 + fmt.Sprintf("%04X%04X", id2, id3),
+		ID1:            id1,
+		ID2:            id2,
+		ID3:            id3,
+		ReferenceRSSI:  referenceRSSI,
+	})
+}
+
+// GetAltBeacon return AltBeacon information
+func (b *Beacon) GetAltBeacon() BeaconAltBeacon {
+	if info, ok := b.info.(BeaconAltBeacon); ok {
+		return info
+	}
+	return BeaconAltBeacon{}
+}
+
+// IsAltBeacon return if the type of beacon is AltBeacon
+func (b *Beacon) IsAltBeacon() bool {
+	return b.Type == BeaconTypeAltBeacon
+}
@@ -0,0 +1,126 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAltBeacon(t *testing.T) {
+
+	id1 := "AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD"
+
+	b, err := CreateAltBeacon(0x0118, id1, 1, 2, -59)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 24, len(b.GetFrames()))
+
+	b1 := Beacon{
+		Device: &device.Device1{
+			Properties: &device.Device1Properties{
+				ManufacturerData: b.props.ManufacturerData,
+			},
+		},
+	}
+
+	isBeacon := b1.Parse()
+	assert.True(t, isBeacon)
+	assert.True(t, b1.IsAltBeacon())
+
+	info := b1.GetAltBeacon()
+	assert.Equal(t, uint16(0x0118), info.ManufacturerID)
+	assert.Equal(t, id1, info.ID1)
+	assert.Equal(t, id1+"00010002", info.ID)
+	assert.Equal(t, uint16(1), info.ID2)
+	assert.Equal(t, uint16(2), info.ID3)
+	assert.Equal(t, -59, info.ReferenceRSSI)
+}
+
+func TestParseFindMyAndCDP(t *testing.T) {
+
+	findMy := append([]byte{0x12, 0x19, 0x10}, make([]byte, 24)...)
+	findMy[26] = 0x01
+
+	cdp := []byte{
+		0x01, 0x09, 0x20, 0x00,
+		0x01, 0x02, 0x03, 0x04,
+		0xAA, 0xBB, 0xCC, 0xDD, 0xAA, 0xBB, 0xCC, 0xDD,
+		0xAA, 0xBB, 0xCC, 0xDD, 0xAA, 0xBB, 0xCC, 0xDD,
+	}
+
+	b := Beacon{
+		Device: &device.Device1{
+			Properties: &device.Device1Properties{
+				ManufacturerData: map[uint16]interface{}{
+					appleBit: findMy,
+				},
+			},
+		},
+	}
+	assert.True(t, b.Parse())
+	assert.Equal(t, BeaconTypeFindMy, string(b.Type))
+	assert.Equal(t, byte(0x10), b.GetFindMy().Status)
+	assert.Equal(t, byte(0x01), b.GetFindMy().Hint)
+
+	b.Device.Properties.ManufacturerData = map[uint16]interface{}{
+		microsoftManufacturerID: cdp,
+	}
+	assert.True(t, b.Parse())
+	assert.Equal(t, BeaconTypeMicrosoftCDP, string(b.Type))
+	assert.Equal(t, byte(9), b.GetMicrosoftCDP().DeviceType)
+	assert.Equal(t, "01020304", b.GetMicrosoftCDP().Salt)
+}
+
+func TestRegisterDecoder(t *testing.T) {
+
+	customType := BeaconType("custom")
+	RegisterDecoder(customType, DecoderFunc(func(data *AdvertisementData) (interface{}, []byte, bool) {
+		frames, ok := data.ManufacturerData[0xFFFF]
+		return "custom", frames, ok
+	}))
+
+	b := Beacon{
+		Device: &device.Device1{
+			Properties: &device.Device1Properties{
+				ManufacturerData: map[uint16]interface{}{
+					0xFFFF: []byte{0x01},
+				},
+			},
+		},
+	}
+
+	assert.True(t, b.Parse())
+	assert.Equal(t, customType, b.Type)
+	assert.Equal(t, "custom", b.GetInfo())
+	assert.Equal(t, []byte{0x01}, b.GetFrames())
+}
+
+func TestDecodeAltBeaconOrder(t *testing.T) {
+
+	b1, err := CreateAltBeacon(0x0118, "AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD", 1, 2, -59)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := CreateAltBeacon(0x0059, "11112222333344441111222233334444", 3, 4, -59)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := &AdvertisementData{
+		ManufacturerData: map[uint16][]byte{
+			0x0118: b1.GetFrames(),
+			0x0059: b2.GetFrames(),
+		},
+	}
+	assert.Equal(t, []uint16{0x0059, 0x0118}, data.ManufacturerIDs())
+
+	// the lowest manufacturer ID is always decoded first
+	for i := 0; i < 20; i++ {
+		info, _, ok := decodeAltBeacon(data)
+		assert.True(t, ok)
+		assert.Equal(t, uint16(0x0059), info.(BeaconAltBeacon).ManufacturerID)
+	}
+}
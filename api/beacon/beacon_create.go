@@ -3,6 +3,7 @@ package beacon
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"strings"
 
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
@@ -27,6 +28,9 @@ func CreateIBeacon(uuid string, major uint16, minor uint16, measuredPower uint16
 	if err != nil {
 		return nil, err
 	}
+	if len(uuidBytes) != 16 {
+		return nil, fmt.Errorf("iBeacon: expected a 16 bytes UUID, got %d", len(uuidBytes))
+	}
 	frames = append(frames, uuidBytes...)
 
 	// major 18,19
@@ -92,6 +96,7 @@ func CreateEddystoneURL(url string, txPower int) (*Beacon, error) {
 	b.props.AddServiceData(eddystoneSrvcUid, []byte(frames))
 	b.Type = BeaconTypeEddystone
 	b.eddystone = BeaconEddystone{
+		Frame:             eddystone.URL,
 		URL:               url,
 		CalibratedTxPower: txPower,
 	}
@@ -116,6 +121,7 @@ func CreateEddystoneTLM(batt uint16, temp float32, advCnt, secCnt uint32) (*Beac
 
 	b.Type = BeaconTypeEddystone
 	b.eddystone = BeaconEddystone{
+		Frame:               eddystone.TLM,
 		TLMVersion:          0,
 		TLMTemperature:      temp,
 		TLMAdvertisingPDU:   advCnt,
@@ -143,6 +149,7 @@ func CreateEddystoneUID(namespace, instance string, txPwr int) (*Beacon, error)
 
 	b.Type = BeaconTypeEddystone
 	b.eddystone = BeaconEddystone{
+		Frame:             eddystone.UID,
 		UID:               namespace,
 		InstanceUID:       instance,
 		CalibratedTxPower: txPwr,
@@ -1,9 +1,10 @@
 package beacon
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
-	log "github.com/sirupsen/logrus"
 	eddystone "github.com/suapapa/go_eddystone"
 )
 
@@ -24,31 +25,52 @@ type BeaconEddystone struct {
 	TLMTemperature      float32
 	TLMAdvertisingPDU   uint32
 	TLMLastRebootedTime uint32
+
+	// eddystone-tlm encrypted, see DecryptEddystoneTLM
+	TLMEncrypted bool
+	ETLM         []byte
+	ETLMSalt     uint16
+	ETLMMIC      uint16
+
+	// eddystone-eid
+	EID string
 }
 
-func (b *Beacon) ParseEddystone(frames []byte) BeaconEddystone {
+// ParseEddystone parse an Eddystone service data frame
+func (b *Beacon) ParseEddystone(frames []byte) (BeaconEddystone, error) {
+
 	info := BeaconEddystone{}
+
+	if len(frames) == 0 {
+		return info, errors.New("Eddystone: empty frame")
+	}
+
 	frameHeader := eddystone.Header(frames[0])
 
+	var err error
 	switch frameHeader {
 	case eddystone.UID:
-		info.Frame = frameHeader
-		parseEddystoneUID(&info, frames)
+		err = parseEddystoneUID(&info, frames)
 	case eddystone.TLM:
-		info.Frame = frameHeader
-		parseEddystoneTLM(&info, frames)
-	case eddystone.URL:
-		info.Frame = frameHeader
-		err := parseEddystoneURL(&info, frames)
-		if err != nil {
-			log.Warn(err)
+		if len(frames) > 1 && frames[1] == eddystoneETLMVersion {
+			err = parseEddystoneETLM(&info, frames)
+		} else {
+			err = parseEddystoneTLM(&info, frames)
 		}
+	case eddystone.URL:
+		err = parseEddystoneURL(&info, frames)
 	case eddystone.EID:
-		// TODO
+		err = parseEddystoneEID(&info, frames)
+	default:
+		return info, fmt.Errorf("Eddystone: unknown frame type 0x%02X", frames[0])
+	}
 
+	if err != nil {
+		return info, err
 	}
 
-	return info
+	info.Frame = frameHeader
+	return info, nil
 }
 
 // eddystone-uid
@@ -74,12 +96,19 @@ func (b *Beacon) ParseEddystone(frames []byte) BeaconEddystone {
 // 17	          BID[5]
 // 18	          RFU	Reserved for future use, must be0x00
 // 19	          RFU	Reserved for future use, must be0x00
-func parseEddystoneUID(info *BeaconEddystone, frames []byte) {
+func parseEddystoneUID(info *BeaconEddystone, frames []byte) error {
+	// RFU bytes are optional
+	if len(frames) < 18 {
+		return fmt.Errorf("Eddystone-UID: expected at least 18 bytes, got %d", len(frames))
+	}
+
 	ns, instance, tx := eddystone.ParseUIDFrame(frames)
 
 	info.CalibratedTxPower = tx
 	info.UID = strings.ToUpper(ns)
 	info.InstanceUID = strings.ToUpper(instance)
+
+	return nil
 }
 
 // eddystone-tlm (plain)
@@ -99,7 +128,14 @@ func parseEddystoneUID(info *BeaconEddystone, frames []byte) {
 // 11	           SEC_CNT[1]
 // 12	           SEC_CNT[2]
 // 13	           SEC_CNT[3]
-func parseEddystoneTLM(info *BeaconEddystone, frames []byte) {
+func parseEddystoneTLM(info *BeaconEddystone, frames []byte) error {
+	if len(frames) < 14 {
+		return fmt.Errorf("Eddystone-TLM: expected 14 bytes, got %d", len(frames))
+	}
+	if frames[1] != 0x00 {
+		return fmt.Errorf("Eddystone-TLM: unsupported version %d", frames[1])
+	}
+
 	batt, temp, advCnt, secCnt := eddystone.ParseTLMFrame(frames)
 
 	info.TLMVersion = int(frames[1] & 0xff)
@@ -107,6 +143,8 @@ func parseEddystoneTLM(info *BeaconEddystone, frames []byte) {
 	info.TLMTemperature = temp
 	info.TLMAdvertisingPDU = advCnt
 	info.TLMLastRebootedTime = secCnt
+
+	return nil
 }
 
 // Byte offset	Field	Description
@@ -141,6 +179,19 @@ func parseEddystoneTLM(info *BeaconEddystone, frames []byte) {
 // 14..32	   0x0e..0x20    Reserved for Future Use
 // 127..255	 0x7F..0xFF    Reserved for Future Use
 func parseEddystoneURL(info *BeaconEddystone, frames []byte) error {
+	if len(frames) < 3 || len(frames) > 20 {
+		return fmt.Errorf("Eddystone-URL: expected 3 to 20 bytes, got %d", len(frames))
+	}
+	if frames[2] > 0x03 {
+		return fmt.Errorf("Eddystone-URL: invalid scheme prefix 0x%02X", frames[2])
+	}
+	for _, c := range frames[3:] {
+		// the expansion codes stop at 0x0d, the rest is reserved
+		if (c >= 0x0e && c <= 0x20) || c >= 0x7f {
+			return fmt.Errorf("Eddystone-URL: invalid byte 0x%02X", c)
+		}
+	}
+
 	url, tx, err := eddystone.ParseURLFrame(frames)
 	if err != nil {
 		return err
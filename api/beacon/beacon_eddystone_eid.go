@@ -0,0 +1,230 @@
+package beacon
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/woongchantonylee/go-bluetooth/api"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+	log "github.com/sirupsen/logrus"
+	eddystone "github.com/suapapa/go_eddystone"
+)
+
+// EddystoneEIDConfig identify an Eddystone-EID beacon
+// https://github.com/google/eddystone/tree/master/eddystone-eid
+type EddystoneEIDConfig struct {
+	// IdentityKey is the 16 bytes AES-128 key shared with the resolver
+	IdentityKey []byte
+	// RotationExponent K, the EID changes every 2^K seconds (0-15)
+	RotationExponent byte
+	// Epoch is the origin of the beacon time counter
+	Epoch time.Time
+}
+
+// TimeCounter return the beacon time counter, in seconds since Epoch
+func (c EddystoneEIDConfig) TimeCounter(t time.Time) uint32 {
+	if t.Before(c.Epoch) {
+		return 0
+	}
+	return uint32(t.Sub(c.Epoch) / time.Second)
+}
+
+// RotationPeriod return the EID rotation period, 2^K seconds
+func (c EddystoneEIDConfig) RotationPeriod() time.Duration {
+	return time.Duration(1<<c.RotationExponent) * time.Second
+}
+
+// NextRotation return the time at which the EID broadcast at t changes
+func (c EddystoneEIDConfig) NextRotation(t time.Time) time.Time {
+	period := uint32(1) << c.RotationExponent
+	counter := c.TimeCounter(t)
+	next := (counter/period + 1) * period
+	return c.Epoch.Add(time.Duration(next) * time.Second)
+}
+
+// ComputeEID return the 8 bytes ephemeral identifier broadcast at time t
+func (c EddystoneEIDConfig) ComputeEID(t time.Time) ([]byte, error) {
+	return ComputeEddystoneEID(c.IdentityKey, c.RotationExponent, c.TimeCounter(t))
+}
+
+// ComputeEddystoneEID return the 8 bytes ephemeral identifier for a time counter
+func ComputeEddystoneEID(identityKey []byte, rotationExponent byte, timeCounter uint32) ([]byte, error) {
+	return eddystone.ComputeEIDValue(identityKey, timeCounter, rotationExponent)
+}
+
+// eddystone-eid
+// https://github.com/google/eddystone/tree/master/eddystone-eid
+// Byte offset  Field	Description
+// 0            Frame Type	Value = 0x30
+// 1	          Ranging Data	Calibrated Tx power at 0 m
+// 2-9	        EID	8-byte Ephemeral Identifier
+func parseEddystoneEID(info *BeaconEddystone, frames []byte) error {
+	if len(frames) < 10 {
+		return fmt.Errorf("Eddystone-EID: expected 10 bytes, got %d", len(frames))
+	}
+	info.CalibratedTxPower = byteToInt(frames[1])
+	info.EID = strings.ToUpper(hex.EncodeToString(frames[2:10]))
+	return nil
+}
+
+// CreateEddystoneEID create an eddystone beacon frame with the EID broadcast at time t
+func CreateEddystoneEID(config EddystoneEIDConfig, t time.Time, txPwr int) (*Beacon, error) {
+
+	eid, err := config.ComputeEID(t)
+	if err != nil {
+		return nil, err
+	}
+
+	frames, err := eddystone.MakeEIDFrameFromBytes(eid, txPwr)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := initBeacon()
+	if err != nil {
+		return nil, err
+	}
+
+	b.props.AddServiceUUID(eddystoneSrvcUid)
+	b.props.AddServiceData(eddystoneSrvcUid, []byte(frames))
+
+	b.Type = BeaconTypeEddystone
+	b.eddystone = BeaconEddystone{
+		Frame:             eddystone.EID,
+		CalibratedTxPower: txPwr,
+		EID:               strings.ToUpper(hex.EncodeToString(eid)),
+	}
+
+	return b, nil
+}
+
+// BroadcastEddystoneEID advertise an Eddystone-EID frame, updating the EID at
+// every rotation until ctx is done
+func BroadcastEddystoneEID(ctx context.Context, m *api.AdvertisementManager, config EddystoneEIDConfig, txPwr int) (*api.Advertisement, error) {
+
+	props := func(t time.Time) (*advertising.LEAdvertisement1Properties, error) {
+		b, err := CreateEddystoneEID(config, t, txPwr)
+		if err != nil {
+			return nil, err
+		}
+		b.props.Type = advertising.AdvertisementTypeBroadcast
+		return b.props, nil
+	}
+
+	p, err := props(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	adv, err := m.Add(p)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(config.NextRotation(time.Now())))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				err := m.Remove(adv)
+				if err != nil && err != api.ErrAdvertisementNotFound {
+					log.Warnf("Eddystone-EID: %s", err)
+				}
+				return
+			case now := <-timer.C:
+				p, err := props(now)
+				if err == nil {
+					err = m.Update(adv, p)
+				}
+				if err == api.ErrAdvertisementNotFound {
+					return
+				}
+				if err != nil {
+					log.Warnf("Eddystone-EID: rotation failed: %s", err)
+				}
+			}
+		}
+	}()
+
+	return adv, nil
+}
+
+// NewEIDResolver create a resolver for Eddystone-EID beacons
+func NewEIDResolver() *EIDResolver {
+	return &EIDResolver{
+		Window:  1,
+		beacons: make(map[string]EddystoneEIDConfig),
+	}
+}
+
+// EIDResolver map received ephemeral identifiers to registered beacons
+type EIDResolver struct {
+	// Window is the number of rotation periods before and after the current
+	// one accepted to tolerate clock drift
+	Window  int
+	lock    sync.RWMutex
+	beacons map[string]EddystoneEIDConfig
+}
+
+// Register a beacon identity
+func (r *EIDResolver) Register(id string, config EddystoneEIDConfig) error {
+	if len(config.IdentityKey) != 16 {
+		return errors.New("Identity key must be 16 bytes")
+	}
+	if config.RotationExponent > 15 {
+		return errors.New("Rotation exponent must be between 0 and 15")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.beacons[id] = config
+	return nil
+}
+
+// Unregister a beacon identity
+func (r *EIDResolver) Unregister(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.beacons, id)
+}
+
+// Resolve return the ID of the registered beacon broadcasting eid at time t
+func (r *EIDResolver) Resolve(eid []byte, t time.Time) (string, bool) {
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for id, config := range r.beacons {
+		period := config.RotationPeriod()
+		for i := -r.Window; i <= r.Window; i++ {
+			candidate, err := config.ComputeEID(t.Add(time.Duration(i) * period))
+			if err != nil {
+				log.Warnf("EIDResolver: %s: %s", id, err)
+				break
+			}
+			if bytes.Equal(candidate, eid) {
+				return id, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ResolveBeacon resolve the EID of a parsed Eddystone-EID beacon
+func (r *EIDResolver) ResolveBeacon(b *Beacon, t time.Time) (string, bool) {
+	if !b.IsEddystone() || b.eddystone.EID == "" {
+		return "", false
+	}
+	eid, err := hex.DecodeString(b.eddystone.EID)
+	if err != nil {
+		return "", false
+	}
+	return r.Resolve(eid, t)
+}
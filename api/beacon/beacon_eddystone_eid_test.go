@@ -0,0 +1,192 @@
+package beacon
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	eddystone "github.com/suapapa/go_eddystone"
+)
+
+func testEIDConfig() EddystoneEIDConfig {
+	return EddystoneEIDConfig{
+		IdentityKey:      []byte("0123456789abcdef"),
+		RotationExponent: 8,
+		Epoch:            time.Unix(1500000000, 0),
+	}
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493 example 1
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "bb1d6929e95937287fa37d129b756746", hex.EncodeToString(cmac(block, []byte{})))
+
+	// RFC 4493 example 2
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	assert.Equal(t, "070a16b46b4d4144f79bdd9dd04a287c", hex.EncodeToString(cmac(block, msg)))
+}
+
+func TestEAX(t *testing.T) {
+	// EAX test vector 2, empty header
+	key, _ := hex.DecodeString("91945D3F4DCBEE0BF45EF52255F095A4")
+	nonce, _ := hex.DecodeString("BECAF043B0A23D843194BA972C66DEBD")
+	msg, _ := hex.DecodeString("F7FB")
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := omac(block, 1, []byte{0xFA, 0x3B, 0xFD, 0x48, 0x06, 0xEB, 0x53, 0xFA})
+	n := omac(block, 0, nonce)
+
+	ciphertext, _, err := eaxSeal(key, nonce, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "19dd", hex.EncodeToString(ciphertext))
+
+	c := omac(block, 2, ciphertext)
+	assert.Equal(t, "5c4c9331049d0bdab0277408f67967e5", hex.EncodeToString(eaxTag(n, h, c)))
+
+	plain, _, err := eaxOpen(key, nonce, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, msg, plain)
+}
+
+func TestCreateEddystoneEID(t *testing.T) {
+
+	config := testEIDConfig()
+	now := config.Epoch.Add(1000 * time.Second)
+
+	b, err := CreateEddystoneEID(config, now, -10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eid, err := config.ComputeEID(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, eid, 8)
+	assert.Equal(t, eddystone.Header(eddystone.EID), b.GetEddystone().Frame)
+
+	beacon := testNewBeacon(t, eddystone.Frame(b.GetFrames()))
+	assert.Equal(t, eddystone.Header(eddystone.EID), beacon.GetEddystone().Frame)
+	assert.Equal(t, -10, beacon.GetEddystone().CalibratedTxPower)
+	assert.Equal(t, b.GetEddystone().EID, beacon.GetEddystone().EID)
+}
+
+func TestEIDRotation(t *testing.T) {
+
+	config := testEIDConfig()
+
+	t0 := config.Epoch.Add(256 * time.Second)
+	t1 := config.Epoch.Add(511 * time.Second)
+	t2 := config.Epoch.Add(512 * time.Second)
+
+	assert.Equal(t, t2, config.NextRotation(t0))
+	assert.Equal(t, t2, config.NextRotation(t1))
+
+	eid0, _ := config.ComputeEID(t0)
+	eid1, _ := config.ComputeEID(t1)
+	eid2, _ := config.ComputeEID(t2)
+
+	assert.Equal(t, eid0, eid1)
+	assert.NotEqual(t, eid1, eid2)
+}
+
+func TestEIDResolver(t *testing.T) {
+
+	config := testEIDConfig()
+	now := config.Epoch.Add(10000 * time.Second)
+
+	r := NewEIDResolver()
+	err := r.Register("beacon1", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Register("invalid", EddystoneEIDConfig{IdentityKey: []byte{1}})
+	assert.Error(t, err)
+
+	eid, _ := config.ComputeEID(now)
+	id, ok := r.Resolve(eid, now)
+	assert.True(t, ok)
+	assert.Equal(t, "beacon1", id)
+
+	// the observer clock is one period ahead
+	id, ok = r.Resolve(eid, now.Add(config.RotationPeriod()))
+	assert.True(t, ok)
+	assert.Equal(t, "beacon1", id)
+
+	_, ok = r.Resolve(eid, now.Add(3*config.RotationPeriod()))
+	assert.False(t, ok)
+
+	b, err := CreateEddystoneEID(config, now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, ok = r.ResolveBeacon(b, now)
+	assert.True(t, ok)
+	assert.Equal(t, "beacon1", id)
+
+	r.Unregister("beacon1")
+	_, ok = r.Resolve(eid, now)
+	assert.False(t, ok)
+}
+
+func TestEddystoneETLM(t *testing.T) {
+
+	config := testEIDConfig()
+	now := config.Epoch.Add(1000 * time.Second)
+
+	var batt uint16 = 3000
+	var temp float32 = 21.5
+	var advCnt uint32 = 1234
+	var secCnt uint32 = 5678
+
+	b, err := CreateEddystoneETLM(config, now, 0xBEEF, batt, temp, advCnt, secCnt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := b.GetFrames()
+	assert.Len(t, frames, 18)
+
+	beacon := testNewBeacon(t, eddystone.Frame(frames))
+	info := beacon.GetEddystone()
+	assert.Equal(t, eddystone.Header(eddystone.TLM), info.Frame)
+	assert.True(t, info.TLMEncrypted)
+	assert.Equal(t, uint16(0xBEEF), info.ETLMSalt)
+
+	// same rotation period
+	err = beacon.DecryptEddystoneTLM(config, now.Add(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info = beacon.GetEddystone()
+	assert.Equal(t, batt, info.TLMBatteryVoltage)
+	assert.Equal(t, temp, info.TLMTemperature)
+	assert.Equal(t, advCnt, info.TLMAdvertisingPDU)
+	assert.Equal(t, secCnt, info.TLMLastRebootedTime)
+
+	// wrong key
+	wrong := config
+	wrong.IdentityKey = []byte("fedcba9876543210")
+	_, err = DecryptEddystoneTLM(wrong, now, frames)
+	assert.Equal(t, ErrETLMAuthentication, err)
+
+	// tampered frame
+	tampered := append([]byte{}, frames...)
+	tampered[5] ^= 0x01
+	_, err = DecryptEddystoneTLM(config, now, tampered)
+	assert.Equal(t, ErrETLMAuthentication, err)
+}
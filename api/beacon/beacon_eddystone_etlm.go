@@ -0,0 +1,256 @@
+package beacon
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	eddystone "github.com/suapapa/go_eddystone"
+)
+
+const eddystoneETLMVersion = 0x01
+
+// ErrETLMAuthentication is returned when the eTLM MIC does not match
+var ErrETLMAuthentication = errors.New("Eddystone-eTLM: message integrity check failed")
+
+// eddystone-tlm (encrypted)
+// https://github.com/google/eddystone/blob/master/eddystone-tlm/tlm-encrypted.md
+// Byte offset   Field	Description
+// 0	           Frame Type	Value = 0x20
+// 1	           Version	TLM version, value = 0x01
+// 2-13	         ETLM	12 bytes of encrypted TLM data
+// 14-15	       SALT	16 bit salt
+// 16-17	       MIC	16 bit message integrity check
+//
+// The plain TLM data (bytes 2-13 of a plain TLM frame) is encrypted with
+// AES-EAX using the EID identity key, the nonce is the 32 bit beacon time
+// counter with the lower K bits cleared followed by the salt.
+func parseEddystoneETLM(info *BeaconEddystone, frames []byte) error {
+	if len(frames) < 18 {
+		return fmt.Errorf("Eddystone-eTLM: expected 18 bytes, got %d", len(frames))
+	}
+	info.TLMVersion = int(frames[1])
+	info.TLMEncrypted = true
+	info.ETLM = append([]byte{}, frames[2:14]...)
+	info.ETLMSalt = binary.BigEndian.Uint16(frames[14:16])
+	info.ETLMMIC = binary.BigEndian.Uint16(frames[16:18])
+	return nil
+}
+
+func eddystoneETLMNonce(config EddystoneEIDConfig, t time.Time, salt uint16) []byte {
+	counter := config.TimeCounter(t)
+	counter &^= uint32(1)<<config.RotationExponent - 1
+	nonce := make([]byte, 6)
+	binary.BigEndian.PutUint32(nonce[0:4], counter)
+	binary.BigEndian.PutUint16(nonce[4:6], salt)
+	return nonce
+}
+
+// EncryptEddystoneTLM encrypt a plain TLM frame to an eTLM frame
+func EncryptEddystoneTLM(config EddystoneEIDConfig, t time.Time, salt uint16, tlm []byte) ([]byte, error) {
+
+	if len(tlm) < 14 || eddystone.Header(tlm[0]) != eddystone.TLM || tlm[1] != 0x00 {
+		return nil, errors.New("Eddystone-eTLM: expected a plain TLM frame")
+	}
+
+	ciphertext, tag, err := eaxSeal(config.IdentityKey, eddystoneETLMNonce(config, t, salt), tlm[2:14])
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make([]byte, 18)
+	frames[0] = byte(eddystone.TLM)
+	frames[1] = eddystoneETLMVersion
+	copy(frames[2:14], ciphertext)
+	binary.BigEndian.PutUint16(frames[14:16], salt)
+	copy(frames[16:18], tag[:2])
+
+	return frames, nil
+}
+
+// DecryptEddystoneTLM verify and decrypt an eTLM frame to a plain TLM frame
+func DecryptEddystoneTLM(config EddystoneEIDConfig, t time.Time, etlm []byte) ([]byte, error) {
+
+	info := BeaconEddystone{}
+	if len(etlm) < 2 || eddystone.Header(etlm[0]) != eddystone.TLM || etlm[1] != eddystoneETLMVersion {
+		return nil, errors.New("Eddystone-eTLM: expected an encrypted TLM frame")
+	}
+	err := parseEddystoneETLM(&info, etlm)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, tag, err := eaxOpen(config.IdentityKey, eddystoneETLMNonce(config, t, info.ETLMSalt), info.ETLM)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(tag[:2], etlm[16:18]) != 1 {
+		return nil, ErrETLMAuthentication
+	}
+
+	frames := make([]byte, 14)
+	frames[0] = byte(eddystone.TLM)
+	copy(frames[2:], plain)
+
+	return frames, nil
+}
+
+// CreateEddystoneETLM create an eddystone beacon frame with encrypted tlm
+func CreateEddystoneETLM(config EddystoneEIDConfig, t time.Time, salt uint16, batt uint16, temp float32, advCnt, secCnt uint32) (*Beacon, error) {
+
+	tlm, err := eddystone.MakeTLMFrame(batt, temp, advCnt, secCnt)
+	if err != nil {
+		return nil, err
+	}
+
+	frames, err := EncryptEddystoneTLM(config, t, salt, tlm)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := initBeacon()
+	if err != nil {
+		return nil, err
+	}
+
+	b.props.AddServiceUUID(eddystoneSrvcUid)
+	b.props.AddServiceData(eddystoneSrvcUid, frames)
+
+	b.Type = BeaconTypeEddystone
+	b.eddystone = BeaconEddystone{Frame: eddystone.TLM}
+	err = parseEddystoneETLM(&b.eddystone, frames)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// DecryptEddystoneTLM decrypt the eTLM frame of the beacon and fill the plain TLM fields
+func (b *Beacon) DecryptEddystoneTLM(config EddystoneEIDConfig, t time.Time) error {
+
+	info := b.eddystone
+	if !b.IsEddystone() || !info.TLMEncrypted {
+		return errors.New("Eddystone-eTLM: beacon has no encrypted TLM")
+	}
+
+	etlm := make([]byte, 18)
+	etlm[0] = byte(eddystone.TLM)
+	etlm[1] = eddystoneETLMVersion
+	copy(etlm[2:14], info.ETLM)
+	binary.BigEndian.PutUint16(etlm[14:16], info.ETLMSalt)
+	binary.BigEndian.PutUint16(etlm[16:18], info.ETLMMIC)
+
+	tlm, err := DecryptEddystoneTLM(config, t, etlm)
+	if err != nil {
+		return err
+	}
+
+	err = parseEddystoneTLM(&b.eddystone, tlm)
+	if err != nil {
+		return err
+	}
+	b.eddystone.TLMVersion = eddystoneETLMVersion
+
+	return nil
+}
+
+// eaxSeal encrypt plaintext with AES-EAX, without header
+func eaxSeal(key, nonce, plaintext []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := omac(block, 0, nonce)
+	h := omac(block, 1, nil)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCTR(block, n).XORKeyStream(ciphertext, plaintext)
+	c := omac(block, 2, ciphertext)
+	return ciphertext, eaxTag(n, h, c), nil
+}
+
+// eaxOpen decrypt ciphertext with AES-EAX and return the expected tag
+func eaxOpen(key, nonce, ciphertext []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	n := omac(block, 0, nonce)
+	h := omac(block, 1, nil)
+	c := omac(block, 2, ciphertext)
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, n).XORKeyStream(plaintext, ciphertext)
+	return plaintext, eaxTag(n, h, c), nil
+}
+
+func eaxTag(n, h, c []byte) []byte {
+	tag := make([]byte, aes.BlockSize)
+	for i := range tag {
+		tag[i] = n[i] ^ h[i] ^ c[i]
+	}
+	return tag
+}
+
+// omac compute the EAX tweaked CMAC, CMAC([t]_16 || data)
+func omac(block cipher.Block, t byte, data []byte) []byte {
+	msg := make([]byte, aes.BlockSize, aes.BlockSize+len(data))
+	msg[aes.BlockSize-1] = t
+	return cmac(block, append(msg, data...))
+}
+
+// cmac compute AES-CMAC as defined in RFC 4493
+func cmac(block cipher.Block, msg []byte) []byte {
+
+	k1 := make([]byte, aes.BlockSize)
+	block.Encrypt(k1, k1)
+	k1 = cmacDouble(k1)
+	k2 := cmacDouble(k1)
+
+	blocks := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	last := make([]byte, aes.BlockSize)
+	if blocks > 0 && len(msg)%aes.BlockSize == 0 {
+		copy(last, msg[(blocks-1)*aes.BlockSize:])
+		xorBytes(last, last, k1)
+	} else {
+		if blocks == 0 {
+			blocks = 1
+		}
+		rest := msg[(blocks-1)*aes.BlockSize:]
+		copy(last, rest)
+		last[len(rest)] = 0x80
+		xorBytes(last, last, k2)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < blocks-1; i++ {
+		xorBytes(x, x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	xorBytes(x, x, last)
+	block.Encrypt(x, x)
+
+	return x
+}
+
+func cmacDouble(b []byte) []byte {
+	d := make([]byte, len(b))
+	var carry byte
+	for i := len(b) - 1; i >= 0; i-- {
+		d[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry == 1 {
+		d[len(d)-1] ^= 0x87
+	}
+	return d
+}
+
+func xorBytes(dst, a, b []byte) {
+	for i := range dst {
+		dst[i] = a[i] ^ b[i]
+	}
+}
@@ -0,0 +1,68 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const findMyType = 0x12
+const findMyLength = 0x19
+
+// BeaconFindMy Apple Find My (offline finding) advertisement information
+type BeaconFindMy struct {
+	Status byte
+	// PublicKeyFragment bytes 6-27 of the advertised public key
+	PublicKeyFragment string
+	// KeyBits are the upper bits of the first public key byte
+	KeyBits byte
+	Hint    byte
+}
+
+// Find My offline finding frame (manufacturer 0x004C)
+// Byte(s) 	Name 						Notes
+// 0 				Type 						0x12
+// 1 				Length 					0x19
+// 2 				Status
+// 3-24 		Public key 			bytes 6-27 of the public key
+// 25 			Key bits 				bits 6-7 of byte 0 of the public key
+// 26 			Hint
+func decodeFindMy(data *AdvertisementData) (interface{}, []byte, bool) {
+	frames, ok := data.ManufacturerData[appleBit]
+	if !ok {
+		return nil, nil, false
+	}
+	info, err := ParseFindMy(frames)
+	if err != nil {
+		return nil, nil, false
+	}
+	return info, frames, true
+}
+
+// ParseFindMy parse an Apple Find My manufacturer data
+func ParseFindMy(frames []byte) (BeaconFindMy, error) {
+
+	info := BeaconFindMy{}
+
+	if len(frames) < 2 || frames[0] != findMyType {
+		return info, fmt.Errorf("FindMy: not an offline finding frame")
+	}
+	if frames[1] != findMyLength || len(frames) != int(findMyLength)+2 {
+		return info, fmt.Errorf("FindMy: invalid length %d", len(frames))
+	}
+
+	info.Status = frames[2]
+	info.PublicKeyFragment = strings.ToUpper(hex.EncodeToString(frames[3:25]))
+	info.KeyBits = frames[25]
+	info.Hint = frames[26]
+
+	return info, nil
+}
+
+// GetFindMy return Find My information
+func (b *Beacon) GetFindMy() BeaconFindMy {
+	if info, ok := b.info.(BeaconFindMy); ok {
+		return info
+	}
+	return BeaconFindMy{}
+}
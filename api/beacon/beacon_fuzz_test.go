@@ -0,0 +1,95 @@
+//go:build go1.18
+// +build go1.18
+
+package beacon
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+)
+
+func fuzzSeeds(f *testing.F, beacons ...func() (*Beacon, error)) {
+	for _, create := range beacons {
+		b, err := create()
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b.GetFrames())
+	}
+}
+
+// FuzzParseEddystone run the Eddystone parser on arbitrary service data,
+// the corpus is in testdata/fuzz/FuzzParseEddystone
+func FuzzParseEddystone(f *testing.F) {
+
+	fuzzSeeds(f,
+		func() (*Beacon, error) {
+			return CreateEddystoneUID("EDD1EBEAC04E5DEFA017", "0BDB87539B67", -20)
+		},
+		func() (*Beacon, error) { return CreateEddystoneURL("https://example.com", -20) },
+		func() (*Beacon, error) { return CreateEddystoneTLM(3000, 21.5, 10, 20) },
+		func() (*Beacon, error) {
+			return CreateEddystoneEID(testEIDConfig(), testEIDConfig().Epoch, -20)
+		},
+		func() (*Beacon, error) {
+			return CreateEddystoneETLM(testEIDConfig(), testEIDConfig().Epoch, 1, 3000, 21.5, 10, 20)
+		},
+	)
+
+	f.Fuzz(func(t *testing.T, frames []byte) {
+		b, ok := testParseServiceData(frames)
+		if !ok {
+			if b.GetFrames() != nil {
+				t.Fatalf("frames of an unparsed beacon: %X", b.GetFrames())
+			}
+			return
+		}
+		if b.IsEddystone() && len(frames) == 0 {
+			t.Fatal("empty frame parsed as Eddystone")
+		}
+		if !bytes.Equal(b.GetFrames(), frames) {
+			t.Fatalf("GetFrames: expected %X, got %X", frames, b.GetFrames())
+		}
+	})
+}
+
+// FuzzParseManufacturerData run the iBeacon and registered manufacturer data
+// decoders on arbitrary data, the corpus is in testdata/fuzz/FuzzParseManufacturerData
+func FuzzParseManufacturerData(f *testing.F) {
+
+	fuzzSeeds(f,
+		func() (*Beacon, error) {
+			return CreateIBeacon("01020304050607080910111213141516", 1, 2, 0xC5)
+		},
+		func() (*Beacon, error) {
+			return CreateAltBeacon(0x0118, "01020304050607080910111213141516", 1, 2, -59)
+		},
+	)
+
+	f.Fuzz(func(t *testing.T, frames []byte) {
+		for _, id := range []uint16{appleBit, 0x0118, ruuviManufacturerID, microsoftManufacturerID} {
+			dev := &device.Device1{
+				Properties: &device.Device1Properties{
+					ManufacturerData: map[uint16]interface{}{
+						id: frames,
+					},
+				},
+			}
+			b, _ := NewBeacon(dev)
+			if !b.Parse() {
+				if b.GetFrames() != nil {
+					t.Fatalf("frames of an unparsed beacon: %X", b.GetFrames())
+				}
+				continue
+			}
+			if b.IsIBeacon() && len(frames) < 23 {
+				t.Fatalf("short frame parsed as iBeacon: %X", frames)
+			}
+			if !bytes.Equal(b.GetFrames(), frames) {
+				t.Fatalf("GetFrames: expected %X, got %X", frames, b.GetFrames())
+			}
+		}
+	})
+}
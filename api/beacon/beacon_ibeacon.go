@@ -3,6 +3,7 @@ package beacon
 import (
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"strings"
 )
 
@@ -30,13 +31,18 @@ type BeaconIBeacon struct {
 // 25-26 		Major 					0xnnnn 		See CLBeaconRegion class in iOS Developer Library. 0x0000 = unset.
 // 27-28 		Minor 					0xnnnn 		See CLBeaconRegion class in iOS Developer Library. 0x0000 = unset.
 // 29 			Measured Power 	0xnn 			See Measured Power (page 7)
-func (b *Beacon) ParseIBeacon(frames []uint8) BeaconIBeacon {
+func (b *Beacon) ParseIBeacon(frames []uint8) (BeaconIBeacon, error) {
 
 	info := BeaconIBeacon{}
 
-	if frames[7-7] == 0x02 && frames[8-7] == 0x15 {
-		info.Type = "proximity"
+	if len(frames) < 30-7 {
+		return info, fmt.Errorf("iBeacon: expected %d bytes, got %d", 30-7, len(frames))
 	}
+	if frames[7-7] != 0x02 || frames[8-7] != 0x15 {
+		return info, fmt.Errorf("iBeacon: unsupported beacon type %X%X", frames[7-7], frames[8-7])
+	}
+
+	info.Type = "proximity"
 
 	uuid := strings.ToUpper(hex.EncodeToString(frames[9-7 : 25-7]))
 	info.ProximityUUID = strings.ToUpper(uuid)
@@ -44,11 +50,7 @@ func (b *Beacon) ParseIBeacon(frames []uint8) BeaconIBeacon {
 	info.Major = binary.BigEndian.Uint16(frames[25-7 : 27-7])
 	info.Minor = binary.BigEndian.Uint16(frames[27-7 : 29-7])
 
-	if len(frames) < 23 {
-		frames = append(frames, 0xb3)
-	}
-
 	info.MeasuredPower = uint16(frames[29-7])
 
-	return info
+	return info, nil
 }
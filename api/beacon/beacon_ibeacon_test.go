@@ -12,7 +12,7 @@ func TestParseIBeacon(t *testing.T) {
 
 	log.SetLevel(log.DebugLevel)
 
-	uuid := "010203040506070809101112131415"
+	uuid := "01020304050607080910111213141516"
 	major := uint16(999)
 	minor := uint16(111)
 	measuredPower := uint16(80)
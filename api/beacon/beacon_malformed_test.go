@@ -0,0 +1,97 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	"github.com/stretchr/testify/assert"
+)
+
+const eddystoneFullUUID = "0000feaa-0000-1000-8000-00805f9b34fb"
+
+func testParseServiceData(data interface{}) (Beacon, bool) {
+	dev := &device.Device1{
+		Properties: &device.Device1Properties{
+			UUIDs: []string{eddystoneFullUUID},
+			ServiceData: map[string]interface{}{
+				eddystoneFullUUID: data,
+			},
+		},
+	}
+	b, _ := NewBeacon(dev)
+	return b, b.Parse()
+}
+
+func testParseManufacturerData(data interface{}) (Beacon, bool) {
+	dev := &device.Device1{
+		Properties: &device.Device1Properties{
+			ManufacturerData: map[uint16]interface{}{
+				appleBit: data,
+			},
+		},
+	}
+	b, _ := NewBeacon(dev)
+	return b, b.Parse()
+}
+
+func TestParseMalformedEddystone(t *testing.T) {
+
+	frames := map[string]interface{}{
+		"empty":          []byte{},
+		"header only":    []byte{0x00},
+		"short uid":      []byte{0x00, 0xEE, 0x01, 0x02},
+		"short tlm":      []byte{0x20, 0x00, 0x0B},
+		"short etlm":     []byte{0x20, 0x01, 0x00},
+		"tlm version":    []byte{0x20, 0x07, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		"short url":      []byte{0x10, 0xEE},
+		"url prefix":     []byte{0x10, 0xEE, 0x09, 'a'},
+		"url reserved":   []byte{0x10, 0xEE, 0x00, 'a', 0x0F},
+		"url high byte":  []byte{0x10, 0xEE, 0x00, 'a', 0x80},
+		"long url":       append([]byte{0x10, 0xEE, 0x00}, make([]byte, 18)...),
+		"short eid":      []byte{0x30, 0xEE, 0x01},
+		"unknown frame":  []byte{0x40, 0x00, 0x00},
+		"not bytes":      "FEAA",
+		"variant string": dbus.MakeVariant("FEAA"),
+		"nil":            nil,
+	}
+
+	for name, data := range frames {
+		assert.NotPanics(t, func() {
+			b, ok := testParseServiceData(data)
+			assert.False(t, ok, name)
+			assert.False(t, b.IsEddystone(), name)
+		}, name)
+	}
+}
+
+func TestParseEddystoneURLExpansion(t *testing.T) {
+	b, ok := testParseServiceData([]byte{0x10, 0xEE, 0x03, 'g', 'o', 0x07})
+	assert.True(t, ok)
+	assert.Equal(t, "https://go.com", b.GetEddystone().URL)
+}
+
+func TestParseMalformedIBeacon(t *testing.T) {
+
+	valid, err := CreateIBeacon("01020304050607080910111213141516", 1, 2, 0xC5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := valid.GetFrames()
+
+	for i := 0; i < len(frames); i++ {
+		assert.NotPanics(t, func() {
+			b, _ := testParseManufacturerData(frames[:i])
+			assert.False(t, b.IsIBeacon(), "length %d", i)
+		})
+	}
+
+	b, ok := testParseManufacturerData(dbus.MakeVariant(frames))
+	assert.True(t, ok)
+	assert.True(t, b.IsIBeacon())
+	assert.Equal(t, "01020304050607080910111213141516", b.GetIBeacon().ProximityUUID)
+	assert.Equal(t, uint16(0xC5), b.GetIBeacon().MeasuredPower)
+
+	_, err = CreateIBeacon("010203", 1, 2, 0xC5)
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const microsoftManufacturerID = 0x0006
+const microsoftCDPScenario = 0x01
+
+// BeaconMicrosoftCDP Microsoft Connected Devices Platform beacon information
+type BeaconMicrosoftCDP struct {
+	ScenarioType byte
+	Version      byte
+	DeviceType   byte
+	Flags        byte
+	Salt         string
+	DeviceHash   string
+}
+
+// Microsoft CDP beacon (manufacturer 0x0006)
+// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-cdp
+// Byte(s) 	Name 										Notes
+// 0 				Scenario Type 					0x01
+// 1 				Version and Device Type 3 bits version, 5 bits device type
+// 2 				Version and Flags 			3 bits version, 5 bits flags
+// 3 				Reserved
+// 4-7 			Salt
+// 8-23 		Device Hash
+func decodeMicrosoftCDP(data *AdvertisementData) (interface{}, []byte, bool) {
+	frames, ok := data.ManufacturerData[microsoftManufacturerID]
+	if !ok {
+		return nil, nil, false
+	}
+	info, err := ParseMicrosoftCDP(frames)
+	if err != nil {
+		return nil, nil, false
+	}
+	return info, frames, true
+}
+
+// ParseMicrosoftCDP parse a Microsoft CDP manufacturer data
+func ParseMicrosoftCDP(frames []byte) (BeaconMicrosoftCDP, error) {
+
+	info := BeaconMicrosoftCDP{}
+
+	if len(frames) < 8 {
+		return info, fmt.Errorf("MicrosoftCDP: expected at least 8 bytes, got %d", len(frames))
+	}
+	if frames[0] != microsoftCDPScenario {
+		return info, fmt.Errorf("MicrosoftCDP: unsupported scenario type %d", frames[0])
+	}
+
+	info.ScenarioType = frames[0]
+	info.Version = frames[1] >> 5
+	info.DeviceType = frames[1] & 0x1F
+	info.Flags = frames[2] & 0x1F
+	info.Salt = strings.ToUpper(hex.EncodeToString(frames[4:8]))
+	info.DeviceHash = strings.ToUpper(hex.EncodeToString(frames[8:]))
+
+	return info, nil
+}
+
+// GetMicrosoftCDP return Microsoft CDP information
+func (b *Beacon) GetMicrosoftCDP() BeaconMicrosoftCDP {
+	if info, ok := b.info.(BeaconMicrosoftCDP); ok {
+		return info
+	}
+	return BeaconMicrosoftCDP{}
+}
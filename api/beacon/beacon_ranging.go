@@ -0,0 +1,382 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	eddystone "github.com/suapapa/go_eddystone"
+)
+
+// Proximity is the relative distance class of a beacon, as CoreLocation CLProximity
+type Proximity string
+
+const (
+	ProximityUnknown   Proximity = "unknown"
+	ProximityImmediate Proximity = "immediate"
+	ProximityNear      Proximity = "near"
+	ProximityFar       Proximity = "far"
+)
+
+// eddystoneTxPowerLoss is the signal loss from 0m to 1m used to convert the
+// Eddystone calibrated power to the 1m reference of iBeacon and AltBeacon
+const eddystoneTxPowerLoss = 41
+
+// RSSIFilter smooth a stream of RSSI readings
+type RSSIFilter interface {
+	// Filter add a reading and return the filtered value
+	Filter(rssi float64) float64
+	// Reset drop the filter state
+	Reset()
+}
+
+// NewKalmanFilter create a one dimension Kalman filter for a static RSSI.
+// processNoise is the expected variance between two readings of the real
+// value, measurementNoise the variance of the readings
+func NewKalmanFilter(processNoise, measurementNoise float64) *KalmanFilter {
+	return &KalmanFilter{
+		ProcessNoise:     processNoise,
+		MeasurementNoise: measurementNoise,
+	}
+}
+
+// KalmanFilter is a one dimension Kalman filter
+type KalmanFilter struct {
+	ProcessNoise     float64
+	MeasurementNoise float64
+	estimate         float64
+	covariance       float64
+	initialized      bool
+}
+
+// Filter add a reading and return the estimated value
+func (k *KalmanFilter) Filter(rssi float64) float64 {
+
+	if !k.initialized {
+		k.estimate = rssi
+		k.covariance = k.MeasurementNoise
+		k.initialized = true
+		return k.estimate
+	}
+
+	covariance := k.covariance + k.ProcessNoise
+	gain := covariance / (covariance + k.MeasurementNoise)
+	k.estimate = k.estimate + gain*(rssi-k.estimate)
+	k.covariance = (1 - gain) * covariance
+
+	return k.estimate
+}
+
+// Reset drop the filter state
+func (k *KalmanFilter) Reset() {
+	k.initialized = false
+	k.estimate = 0
+	k.covariance = 0
+}
+
+// NewMovingAverageFilter create a filter averaging the last size readings
+func NewMovingAverageFilter(size int) *MovingAverageFilter {
+	if size < 1 {
+		size = 1
+	}
+	return &MovingAverageFilter{
+		size:     size,
+		readings: make([]float64, 0, size),
+	}
+}
+
+// MovingAverageFilter average the last readings
+type MovingAverageFilter struct {
+	size     int
+	readings []float64
+	next     int
+}
+
+// Filter add a reading and return the average of the window
+func (m *MovingAverageFilter) Filter(rssi float64) float64 {
+
+	if len(m.readings) < m.size {
+		m.readings = append(m.readings, rssi)
+	} else {
+		m.readings[m.next] = rssi
+		m.next = (m.next + 1) % m.size
+	}
+
+	sum := 0.0
+	for _, r := range m.readings {
+		sum += r
+	}
+	return sum / float64(len(m.readings))
+}
+
+// Reset drop the readings
+func (m *MovingAverageFilter) Reset() {
+	m.readings = m.readings[:0]
+	m.next = 0
+}
+
+// DistanceModel estimate the distance in meters from a RSSI and the
+// measured power at 1m
+type DistanceModel interface {
+	Distance(rssi, measuredPower float64) float64
+}
+
+// LogDistanceModel is the log-distance path loss model
+// d = 10 ^ ((measuredPower - rssi) / (10 * Exponent))
+type LogDistanceModel struct {
+	// Exponent is the path loss exponent, 2 in free space, 2.7 to 4 indoor
+	Exponent float64
+}
+
+// Distance return the estimated distance in meters
+func (m LogDistanceModel) Distance(rssi, measuredPower float64) float64 {
+	exponent := m.Exponent
+	if exponent <= 0 {
+		exponent = 2
+	}
+	return math.Pow(10, (measuredPower-rssi)/(10*exponent))
+}
+
+// CurveFitModel is the empirical model used by the Android Beacon Library
+// d = A * (rssi / measuredPower) ^ B + C
+type CurveFitModel struct {
+	A float64
+	B float64
+	C float64
+}
+
+// DefaultCurveFitModel is the Nexus 4 fit of the Android Beacon Library
+var DefaultCurveFitModel = CurveFitModel{A: 0.42093, B: 6.9476, C: 0.54992}
+
+// Distance return the estimated distance in meters
+func (m CurveFitModel) Distance(rssi, measuredPower float64) float64 {
+	ratio := rssi / measuredPower
+	if ratio < 1 {
+		return math.Pow(ratio, 10)
+	}
+	return m.A*math.Pow(ratio, m.B) + m.C
+}
+
+// MeasuredPower return the expected RSSI at 1m advertised by the beacon
+func (b *Beacon) MeasuredPower() (int, bool) {
+	switch {
+	case b.IsIBeacon():
+		return int(int8(uint8(b.iBeacon.MeasuredPower))), true
+	case b.IsEddystone():
+		frame := b.eddystone.Frame
+		if frame == eddystone.UID || frame == eddystone.URL || frame == eddystone.EID {
+			return b.eddystone.CalibratedTxPower - eddystoneTxPowerLoss, true
+		}
+	case b.IsAltBeacon():
+		return b.GetAltBeacon().ReferenceRSSI, true
+	}
+	return 0, false
+}
+
+// RangingConfig configure a Ranger
+type RangingConfig struct {
+	// NewFilter create the RSSI filter of a beacon, defaults to a Kalman filter
+	NewFilter func() RSSIFilter
+	// Model estimate the distance, defaults to LogDistanceModel with exponent 2
+	Model DistanceModel
+	// ImmediateDistance is the upper bound of ProximityImmediate in meters
+	ImmediateDistance float64
+	// NearDistance is the upper bound of ProximityNear in meters
+	NearDistance float64
+	// Timeout after which a beacon without readings is ProximityUnknown
+	Timeout time.Duration
+}
+
+// DefaultRangingConfig return the default ranging configuration
+func DefaultRangingConfig() RangingConfig {
+	return RangingConfig{
+		NewFilter: func() RSSIFilter {
+			return NewKalmanFilter(0.008, 4)
+		},
+		Model:             LogDistanceModel{Exponent: 2},
+		ImmediateDistance: 0.5,
+		NearDistance:      3,
+		Timeout:           10 * time.Second,
+	}
+}
+
+// Range is the estimated position of a beacon
+type Range struct {
+	ID            string
+	RSSI          int
+	FilteredRSSI  float64
+	MeasuredPower int
+	// Distance in meters, -1 if unknown
+	Distance  float64
+	Proximity Proximity
+	LastSeen  time.Time
+}
+
+type rangedBeacon struct {
+	filter RSSIFilter
+	rng    Range
+}
+
+// NewRanger create a Ranger, missing config values use DefaultRangingConfig
+func NewRanger(config RangingConfig) *Ranger {
+	def := DefaultRangingConfig()
+	if config.NewFilter == nil {
+		config.NewFilter = def.NewFilter
+	}
+	if config.Model == nil {
+		config.Model = def.Model
+	}
+	if config.ImmediateDistance <= 0 {
+		config.ImmediateDistance = def.ImmediateDistance
+	}
+	if config.NearDistance <= 0 {
+		config.NearDistance = def.NearDistance
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = def.Timeout
+	}
+	return &Ranger{
+		config:  config,
+		beacons: make(map[string]*rangedBeacon),
+	}
+}
+
+// Ranger estimate the distance of beacons from their RSSI readings
+type Ranger struct {
+	config  RangingConfig
+	lock    sync.Mutex
+	beacons map[string]*rangedBeacon
+}
+
+// Update add a RSSI reading of beacon id and return the updated range
+func (r *Ranger) Update(id string, rssi int, measuredPower int, t time.Time) Range {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rb, ok := r.beacons[id]
+	if !ok {
+		rb = &rangedBeacon{
+			filter: r.config.NewFilter(),
+			rng:    Range{ID: id},
+		}
+		r.beacons[id] = rb
+	} else if t.Sub(rb.rng.LastSeen) > r.config.Timeout {
+		rb.filter.Reset()
+	}
+
+	filtered := rb.filter.Filter(float64(rssi))
+
+	rb.rng.RSSI = rssi
+	rb.rng.FilteredRSSI = filtered
+	rb.rng.MeasuredPower = measuredPower
+	rb.rng.LastSeen = t
+	rb.rng.Distance = -1
+	rb.rng.Proximity = ProximityUnknown
+
+	if rssi != 0 && measuredPower != 0 {
+		rb.rng.Distance = r.config.Model.Distance(filtered, float64(measuredPower))
+		rb.rng.Proximity = r.Proximity(rb.rng.Distance)
+	}
+
+	return rb.rng
+}
+
+// Proximity classify a distance in meters
+func (r *Ranger) Proximity(distance float64) Proximity {
+	switch {
+	case distance < 0 || math.IsNaN(distance) || math.IsInf(distance, 0):
+		return ProximityUnknown
+	case distance < r.config.ImmediateDistance:
+		return ProximityImmediate
+	case distance < r.config.NearDistance:
+		return ProximityNear
+	}
+	return ProximityFar
+}
+
+// Get return the last range of beacon id, a beacon not seen within the
+// timeout is ProximityUnknown
+func (r *Ranger) Get(id string, t time.Time) (Range, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	rb, ok := r.beacons[id]
+	if !ok {
+		return Range{}, false
+	}
+	return r.expire(rb.rng, t), true
+}
+
+// Ranges return the last range of all the beacons
+func (r *Ranger) Ranges(t time.Time) []Range {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	list := make([]Range, 0, len(r.beacons))
+	for _, rb := range r.beacons {
+		list = append(list, r.expire(rb.rng, t))
+	}
+	return list
+}
+
+// Remove drop the state of beacon id
+func (r *Ranger) Remove(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.beacons, id)
+}
+
+func (r *Ranger) expire(rng Range, t time.Time) Range {
+	if t.Sub(rng.LastSeen) > r.config.Timeout {
+		rng.Distance = -1
+		rng.Proximity = ProximityUnknown
+	}
+	return rng
+}
+
+// Watch range a beacon from the RSSI changes of its device until ctx is done.
+// The beacon must have been parsed to know its measured power.
+func (r *Ranger) Watch(ctx context.Context, b *Beacon) (chan Range, error) {
+
+	if b.Device == nil {
+		return nil, errors.New("Beacon has no device")
+	}
+
+	measuredPower, ok := b.MeasuredPower()
+	if !ok {
+		return nil, errors.New("Beacon does not advertise its measured power")
+	}
+
+	id := string(b.Device.Path())
+
+	propchanged, err := b.Device.WatchProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Range)
+
+	go func() {
+		defer close(ch)
+		runDeviceWatch(ctx, b.Device, propchanged, func(changed *bluez.PropertyChanged) bool {
+			if changed.Name != "RSSI" {
+				return true
+			}
+			rssi, ok := changed.Value.(int16)
+			if !ok {
+				return true
+			}
+			rng := r.Update(id, int(rssi), measuredPower, time.Now())
+			select {
+			case ch <- rng:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,120 @@
+package beacon
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKalmanFilter(t *testing.T) {
+
+	f := NewKalmanFilter(0.008, 4)
+	assert.Equal(t, -60.0, f.Filter(-60))
+
+	// a single outlier is smoothed
+	v := f.Filter(-90)
+	assert.True(t, v > -90 && v < -60)
+
+	for i := 0; i < 200; i++ {
+		v = f.Filter(-70)
+	}
+	assert.InDelta(t, -70, v, 1)
+
+	f.Reset()
+	assert.Equal(t, -50.0, f.Filter(-50))
+}
+
+func TestMovingAverageFilter(t *testing.T) {
+
+	f := NewMovingAverageFilter(3)
+	assert.Equal(t, -60.0, f.Filter(-60))
+	assert.Equal(t, -65.0, f.Filter(-70))
+	assert.Equal(t, -70.0, f.Filter(-80))
+	// -60 leaves the window
+	assert.Equal(t, -80.0, f.Filter(-90))
+
+	f.Reset()
+	assert.Equal(t, -40.0, f.Filter(-40))
+}
+
+func TestDistanceModels(t *testing.T) {
+
+	m := LogDistanceModel{Exponent: 2}
+	assert.InDelta(t, 1, m.Distance(-59, -59), 0.0001)
+	assert.InDelta(t, 10, m.Distance(-79, -59), 0.0001)
+
+	m = LogDistanceModel{Exponent: 4}
+	assert.InDelta(t, math.Sqrt(10), m.Distance(-79, -59), 0.0001)
+
+	c := DefaultCurveFitModel
+	assert.InDelta(t, 0.9709, c.Distance(-59, -59), 0.0001)
+	assert.True(t, c.Distance(-50, -59) < 1)
+	assert.True(t, c.Distance(-80, -59) > c.Distance(-70, -59))
+}
+
+func TestRanger(t *testing.T) {
+
+	r := NewRanger(RangingConfig{
+		NewFilter: func() RSSIFilter {
+			return NewMovingAverageFilter(1)
+		},
+		Timeout: time.Second,
+	})
+
+	now := time.Now()
+
+	rng := r.Update("b1", -45, -59, now)
+	assert.Equal(t, ProximityImmediate, rng.Proximity)
+
+	rng = r.Update("b1", -59, -59, now)
+	assert.InDelta(t, 1, rng.Distance, 0.0001)
+	assert.Equal(t, ProximityNear, rng.Proximity)
+
+	rng = r.Update("b1", -80, -59, now)
+	assert.Equal(t, ProximityFar, rng.Proximity)
+
+	// no measured power
+	rng = r.Update("b2", -80, 0, now)
+	assert.Equal(t, ProximityUnknown, rng.Proximity)
+	assert.Equal(t, -1.0, rng.Distance)
+
+	rng, ok := r.Get("b1", now.Add(500*time.Millisecond))
+	assert.True(t, ok)
+	assert.Equal(t, ProximityFar, rng.Proximity)
+
+	rng, ok = r.Get("b1", now.Add(2*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, ProximityUnknown, rng.Proximity)
+
+	assert.Len(t, r.Ranges(now), 2)
+	r.Remove("b2")
+	assert.Len(t, r.Ranges(now), 1)
+}
+
+func TestMeasuredPower(t *testing.T) {
+
+	b, err := CreateIBeacon("AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD", 1, 2, 0xC5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	power, ok := b.MeasuredPower()
+	assert.True(t, ok)
+	assert.Equal(t, -59, power)
+
+	b, err = CreateEddystoneURL("https://example.com", -18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	power, ok = b.MeasuredPower()
+	assert.True(t, ok)
+	assert.Equal(t, -59, power)
+
+	b, err = CreateEddystoneTLM(3000, 20, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok = b.MeasuredPower()
+	assert.False(t, ok)
+}
@@ -0,0 +1,392 @@
+package beacon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/api"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/adapter"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultRegionExitTimeout is the time without readings after which a region is exited
+const DefaultRegionExitTimeout = 30 * time.Second
+
+// Region identify a group of beacons, empty identifiers match any value
+type Region struct {
+	ID   string
+	Type BeaconType
+	// ProximityUUID of iBeacon regions
+	ProximityUUID string
+	Major         uint16
+	Minor         uint16
+	MatchMajor    bool
+	MatchMinor    bool
+	// Namespace and Instance of Eddystone-UID regions
+	Namespace string
+	Instance  string
+}
+
+// NewIBeaconRegion create a region matching iBeacons with proximityUUID, an
+// empty proximityUUID match every iBeacon
+func NewIBeaconRegion(id, proximityUUID string) Region {
+	return Region{
+		ID:            id,
+		Type:          BeaconTypeIBeacon,
+		ProximityUUID: proximityUUID,
+	}
+}
+
+// WithMajor restrict the region to a major value
+func (r Region) WithMajor(major uint16) Region {
+	r.Major = major
+	r.MatchMajor = true
+	return r
+}
+
+// WithMinor restrict the region to a minor value
+func (r Region) WithMinor(minor uint16) Region {
+	r.Minor = minor
+	r.MatchMinor = true
+	return r
+}
+
+// NewEddystoneRegion create a region matching Eddystone-UID beacons, empty
+// namespace or instance match any value
+func NewEddystoneRegion(id, namespace, instance string) Region {
+	return Region{
+		ID:        id,
+		Type:      BeaconTypeEddystone,
+		Namespace: namespace,
+		Instance:  instance,
+	}
+}
+
+func normalizeID(id string) string {
+	return strings.ToUpper(strings.Replace(id, "-", "", -1))
+}
+
+// Matches return true if the beacon belongs to the region
+func (r Region) Matches(b *Beacon) bool {
+	switch r.Type {
+	case BeaconTypeIBeacon:
+		if !b.IsIBeacon() {
+			return false
+		}
+		info := b.GetIBeacon()
+		if r.ProximityUUID != "" && normalizeID(r.ProximityUUID) != normalizeID(info.ProximityUUID) {
+			return false
+		}
+		if r.MatchMajor && r.Major != info.Major {
+			return false
+		}
+		if r.MatchMinor && r.Minor != info.Minor {
+			return false
+		}
+		return true
+	case BeaconTypeEddystone:
+		if !b.IsEddystone() {
+			return false
+		}
+		info := b.GetEddystone()
+		if info.UID == "" {
+			return false
+		}
+		if r.Namespace != "" && normalizeID(r.Namespace) != normalizeID(info.UID) {
+			return false
+		}
+		if r.Instance != "" && normalizeID(r.Instance) != normalizeID(info.InstanceUID) {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// RegionEventType is the type of a region transition
+type RegionEventType string
+
+const (
+	RegionEnter RegionEventType = "enter"
+	RegionExit  RegionEventType = "exit"
+)
+
+// RegionEvent notify a region transition
+type RegionEvent struct {
+	Type   RegionEventType
+	Region Region
+	// Beacon is the first beacon seen on enter, the last seen on exit
+	Beacon *Beacon
+	Time   time.Time
+}
+
+type regionState struct {
+	region Region
+	// last time each beacon of the region was seen
+	seen map[string]time.Time
+	last *Beacon
+}
+
+// NewRegionMonitor create a monitor, exitTimeout is the time without
+// readings after which a region is exited
+func NewRegionMonitor(exitTimeout time.Duration) *RegionMonitor {
+	if exitTimeout <= 0 {
+		exitTimeout = DefaultRegionExitTimeout
+	}
+	return &RegionMonitor{
+		exitTimeout: exitTimeout,
+		regions:     make(map[string]*regionState),
+	}
+}
+
+// RegionMonitor track beacons entering and exiting regions
+type RegionMonitor struct {
+	exitTimeout time.Duration
+	lock        sync.Mutex
+	regions     map[string]*regionState
+}
+
+// AddRegion start monitoring a region, a region with the same ID is replaced
+func (m *RegionMonitor) AddRegion(r Region) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.regions[r.ID] = &regionState{
+		region: r,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// RemoveRegion stop monitoring a region
+func (m *RegionMonitor) RemoveRegion(id string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.regions, id)
+}
+
+// Inside return true if the region has been entered and not yet exited
+func (m *RegionMonitor) Inside(id string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	state, ok := m.regions[id]
+	return ok && len(state.seen) > 0
+}
+
+// Observe record a beacon reading and return the regions entered
+func (m *RegionMonitor) Observe(b *Beacon, t time.Time) []RegionEvent {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := beaconKey(b)
+	events := []RegionEvent{}
+
+	for _, state := range m.regions {
+		if !state.region.Matches(b) {
+			continue
+		}
+		if len(state.seen) == 0 {
+			events = append(events, RegionEvent{
+				Type:   RegionEnter,
+				Region: state.region,
+				Beacon: b,
+				Time:   t,
+			})
+		}
+		state.seen[key] = t
+		state.last = b
+	}
+
+	return events
+}
+
+// Expire drop the beacons not seen within the exit timeout and return the
+// regions exited
+func (m *RegionMonitor) Expire(t time.Time) []RegionEvent {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	events := []RegionEvent{}
+
+	for _, state := range m.regions {
+		if len(state.seen) == 0 {
+			continue
+		}
+		for key, seen := range state.seen {
+			if t.Sub(seen) > m.exitTimeout {
+				delete(state.seen, key)
+			}
+		}
+		if len(state.seen) == 0 {
+			events = append(events, RegionEvent{
+				Type:   RegionExit,
+				Region: state.region,
+				Beacon: state.last,
+				Time:   t,
+			})
+			state.last = nil
+		}
+	}
+
+	return events
+}
+
+// Monitor discover beacons on the adapter and emit region events until ctx
+// is done, then stop the discovery and close the channel
+func (m *RegionMonitor) Monitor(ctx context.Context, a *adapter.Adapter1) (chan RegionEvent, error) {
+
+	filter := adapter.NewDiscoveryFilter()
+	filter.Transport = adapter.DiscoveryFilterTransportLE
+
+	discovery, cancel, err := api.Discover(a, &filter)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan RegionEvent)
+	observations := make(chan *Beacon)
+
+	go func() {
+
+		var wg sync.WaitGroup
+		watched := make(map[dbus.ObjectPath]context.CancelFunc)
+
+		defer func() {
+			cancel()
+			for _, stop := range watched {
+				stop()
+			}
+			wg.Wait()
+			close(out)
+		}()
+
+		emit := func(events []RegionEvent) bool {
+			for _, ev := range events {
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+
+		ticker := time.NewTicker(m.exitTimeout / 4)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if !emit(m.Expire(now)) {
+					return
+				}
+			case b := <-observations:
+				if !emit(m.Observe(b, time.Now())) {
+					return
+				}
+			case ev := <-discovery:
+				if ev == nil {
+					return
+				}
+				if ev.Type == adapter.DeviceRemoved {
+					if stop, ok := watched[ev.Path]; ok {
+						stop()
+						delete(watched, ev.Path)
+					}
+					continue
+				}
+				if _, ok := watched[ev.Path]; ok {
+					continue
+				}
+				stop, err := m.watchBeacon(ctx, ev.Path, observations, &wg)
+				if err != nil {
+					log.Warnf("RegionMonitor: %s: %s", ev.Path, err)
+					continue
+				}
+				watched[ev.Path] = stop
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// watchBeacon send the device to observations each time it advertise
+func (m *RegionMonitor) watchBeacon(ctx context.Context, path dbus.ObjectPath, observations chan *Beacon, wg *sync.WaitGroup) (context.CancelFunc, error) {
+
+	dev, err := device.NewDevice1(path)
+	if err != nil {
+		return nil, err
+	}
+	if dev == nil {
+		return nil, fmt.Errorf("Device %s not found", path)
+	}
+
+	b, err := NewBeacon(dev)
+	if err != nil {
+		return nil, err
+	}
+
+	propchanged, err := dev.WatchProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	watchCtx, stop := context.WithCancel(ctx)
+
+	observe := func() bool {
+		if !b.Parse() {
+			return true
+		}
+		// copy, the watch goroutine parse again on the next change
+		snapshot := b
+		select {
+		case observations <- &snapshot:
+			return true
+		case <-watchCtx.Done():
+			return false
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if !observe() {
+			unwatchDevice(dev, propchanged)
+			return
+		}
+		runDeviceWatch(watchCtx, dev, propchanged, func(changed *bluez.PropertyChanged) bool {
+			switch changed.Name {
+			case "RSSI", "ManufacturerData", "ServiceData":
+				return observe()
+			}
+			return true
+		})
+	}()
+
+	return stop, nil
+}
+
+// beaconKey identify a beacon within a region
+func beaconKey(b *Beacon) string {
+	if b.Device != nil {
+		return string(b.Device.Path())
+	}
+	switch {
+	case b.IsIBeacon():
+		info := b.GetIBeacon()
+		return fmt.Sprintf("%s/%d/%d", info.ProximityUUID, info.Major, info.Minor)
+	case b.IsEddystone():
+		info := b.GetEddystone()
+		return fmt.Sprintf("%s/%s", info.UID, info.InstanceUID)
+	}
+	return fmt.Sprintf("%p", b)
+}
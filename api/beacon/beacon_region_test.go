@@ -0,0 +1,88 @@
+package beacon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRegionUUID = "AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD"
+
+func testIBeacon(t *testing.T, major, minor uint16) *Beacon {
+	b, err := CreateIBeacon(testRegionUUID, major, minor, 0xC5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRegionMatches(t *testing.T) {
+
+	b := testIBeacon(t, 1, 2)
+
+	assert.True(t, NewIBeaconRegion("any", "").Matches(b))
+	assert.True(t, NewIBeaconRegion("uuid", "aaaabbbb-cccc-dddd-aaaa-bbbbccccdddd").Matches(b))
+	assert.True(t, NewIBeaconRegion("major", testRegionUUID).WithMajor(1).Matches(b))
+	assert.True(t, NewIBeaconRegion("minor", testRegionUUID).WithMajor(1).WithMinor(2).Matches(b))
+	assert.False(t, NewIBeaconRegion("other", "11112222333344441111222233334444").Matches(b))
+	assert.False(t, NewIBeaconRegion("major", testRegionUUID).WithMajor(3).Matches(b))
+	assert.False(t, NewIBeaconRegion("minor", testRegionUUID).WithMinor(3).Matches(b))
+	assert.False(t, NewEddystoneRegion("eddystone", "", "").Matches(b))
+
+	e, err := CreateEddystoneUID("EDD1EBEAC04E5DEFA017", "0BDB87539B67", -20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, NewEddystoneRegion("any", "", "").Matches(e))
+	assert.True(t, NewEddystoneRegion("ns", "edd1ebeac04e5defa017", "").Matches(e))
+	assert.True(t, NewEddystoneRegion("instance", "EDD1EBEAC04E5DEFA017", "0BDB87539B67").Matches(e))
+	assert.False(t, NewEddystoneRegion("other", "00000000000000000000", "").Matches(e))
+	assert.False(t, NewEddystoneRegion("instance", "", "000000000000").Matches(e))
+	assert.False(t, NewIBeaconRegion("ibeacon", "").Matches(e))
+}
+
+func TestRegionMonitor(t *testing.T) {
+
+	m := NewRegionMonitor(10 * time.Second)
+	m.AddRegion(NewIBeaconRegion("store", testRegionUUID))
+	m.AddRegion(NewIBeaconRegion("entrance", testRegionUUID).WithMajor(1))
+
+	now := time.Now()
+
+	events := m.Observe(testIBeacon(t, 2, 1), now)
+	assert.Len(t, events, 1)
+	assert.Equal(t, RegionEnter, events[0].Type)
+	assert.Equal(t, "store", events[0].Region.ID)
+	assert.True(t, m.Inside("store"))
+	assert.False(t, m.Inside("entrance"))
+
+	events = m.Observe(testIBeacon(t, 1, 1), now.Add(5*time.Second))
+	assert.Len(t, events, 1)
+	assert.Equal(t, "entrance", events[0].Region.ID)
+
+	// already inside
+	events = m.Observe(testIBeacon(t, 2, 1), now.Add(6*time.Second))
+	assert.Len(t, events, 0)
+
+	// the entrance beacon expired, the store beacon was seen at +6s
+	events = m.Expire(now.Add(16 * time.Second))
+	assert.Len(t, events, 1)
+	assert.Equal(t, RegionExit, events[0].Type)
+	assert.Equal(t, "entrance", events[0].Region.ID)
+	assert.True(t, m.Inside("store"))
+
+	events = m.Expire(now.Add(17 * time.Second))
+	assert.Len(t, events, 1)
+	assert.Equal(t, "store", events[0].Region.ID)
+	assert.False(t, m.Inside("store"))
+
+	// no further exit
+	events = m.Expire(now.Add(30 * time.Second))
+	assert.Len(t, events, 0)
+
+	m.RemoveRegion("store")
+	events = m.Observe(testIBeacon(t, 2, 1), now.Add(31*time.Second))
+	assert.Len(t, events, 0)
+}
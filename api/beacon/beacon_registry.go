@@ -0,0 +1,182 @@
+package beacon
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+)
+
+// AdvertisementData is the advertisement content inspected by the decoders
+type AdvertisementData struct {
+	ServiceUUIDs     []string
+	ServiceData      map[string][]byte
+	ManufacturerData map[uint16][]byte
+}
+
+// ManufacturerIDs return the manufacturer data IDs in ascending order, to
+// decode the entries deterministically
+func (d *AdvertisementData) ManufacturerIDs() []uint16 {
+	ids := make([]uint16, 0, len(d.ManufacturerData))
+	for id := range d.ManufacturerData {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// ServiceDataUUIDs return the service data UUIDs in ascending order
+func (d *AdvertisementData) ServiceDataUUIDs() []string {
+	uuids := make([]string, 0, len(d.ServiceData))
+	for uuid := range d.ServiceData {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+// Decoder identify and decode a beacon format
+type Decoder interface {
+	// Decode return the beacon information and the matching frames
+	// if the advertisement is in the decoder format
+	Decode(data *AdvertisementData) (info interface{}, frames []byte, ok bool)
+}
+
+// DecoderFunc adapt a function to the Decoder interface
+type DecoderFunc func(data *AdvertisementData) (interface{}, []byte, bool)
+
+// Decode call fx(data)
+func (fx DecoderFunc) Decode(data *AdvertisementData) (interface{}, []byte, bool) {
+	return fx(data)
+}
+
+// Encoder build the advertisement of a beacon format
+type Encoder interface {
+	Encode(info interface{}) (*advertising.LEAdvertisement1Properties, error)
+}
+
+// EncoderFunc adapt a function to the Encoder interface
+type EncoderFunc func(info interface{}) (*advertising.LEAdvertisement1Properties, error)
+
+// Encode call fx(info)
+func (fx EncoderFunc) Encode(info interface{}) (*advertising.LEAdvertisement1Properties, error) {
+	return fx(info)
+}
+
+type registeredDecoder struct {
+	beaconType BeaconType
+	decoder    Decoder
+}
+
+var registryLock sync.RWMutex
+var decoders = []registeredDecoder{}
+var encoders = map[BeaconType]Encoder{}
+
+// RegisterDecoder add a decoder used by Beacon.Parse. Decoders are tried in
+// registration order after the built-in Eddystone and iBeacon parsers.
+// Registering a type again replace its decoder.
+func RegisterDecoder(beaconType BeaconType, decoder Decoder) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for i, d := range decoders {
+		if d.beaconType == beaconType {
+			decoders[i].decoder = decoder
+			return
+		}
+	}
+	decoders = append(decoders, registeredDecoder{beaconType, decoder})
+}
+
+// RegisterEncoder add an encoder used by CreateBeacon
+func RegisterEncoder(beaconType BeaconType, encoder Encoder) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	encoders[beaconType] = encoder
+}
+
+// CreateBeacon create a beacon using the encoder registered for beaconType
+func CreateBeacon(beaconType BeaconType, info interface{}) (*Beacon, error) {
+
+	registryLock.RLock()
+	encoder, ok := encoders[beaconType]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("No encoder registered for beacon type %s", beaconType)
+	}
+
+	props, err := encoder.Encode(info)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := initBeacon()
+	if err != nil {
+		return nil, err
+	}
+
+	b.props = props
+	b.Type = beaconType
+	b.info = info
+
+	data := b.toAdvertisementData(props.ServiceUUIDs, props.ServiceData, props.ManufacturerData)
+	// the service data take precedence over the manufacturer data
+	if ids := data.ManufacturerIDs(); len(ids) > 0 {
+		b.frames = data.ManufacturerData[ids[0]]
+	}
+	if uuids := data.ServiceDataUUIDs(); len(uuids) > 0 {
+		b.frames = data.ServiceData[uuids[0]]
+	}
+
+	return b, nil
+}
+
+func (b *Beacon) parseRegistered(data *AdvertisementData) bool {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, d := range decoders {
+		info, frames, ok := d.decoder.Decode(data)
+		if ok {
+			b.Type = d.beaconType
+			b.info = info
+			b.frames = frames
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Beacon) toAdvertisementData(UUIDs []string, serviceData map[string]interface{}, manufacturerData map[uint16]interface{}) *AdvertisementData {
+
+	data := &AdvertisementData{
+		ServiceUUIDs:     UUIDs,
+		ServiceData:      make(map[string][]byte),
+		ManufacturerData: make(map[uint16][]byte),
+	}
+
+	for uuid, v := range serviceData {
+		if frames, ok := b.getBytesFromData(v); ok {
+			data.ServiceData[uuid] = frames
+		}
+	}
+
+	for id, v := range manufacturerData {
+		if frames, ok := b.getBytesFromData(v); ok {
+			data.ManufacturerData[id] = frames
+		}
+	}
+
+	return data
+}
+
+func init() {
+	RegisterDecoder(BeaconTypeAltBeacon, DecoderFunc(decodeAltBeacon))
+	RegisterEncoder(BeaconTypeAltBeacon, EncoderFunc(encodeAltBeacon))
+	RegisterDecoder(BeaconTypeRuuvi, DecoderFunc(decodeRuuvi))
+	RegisterEncoder(BeaconTypeRuuvi, EncoderFunc(encodeRuuvi))
+	RegisterDecoder(BeaconTypeFindMy, DecoderFunc(decodeFindMy))
+	RegisterDecoder(BeaconTypeMicrosoftCDP, DecoderFunc(decodeMicrosoftCDP))
+}
@@ -0,0 +1,156 @@
+package beacon
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+)
+
+const ruuviManufacturerID = 0x0499
+const ruuviDataFormat5 = 0x05
+
+// BeaconRuuvi RuuviTag RAWv2 (data format 5) measurements
+type BeaconRuuvi struct {
+	DataFormat byte
+	// Temperature in Celsius
+	Temperature float64
+	// Humidity in percent
+	Humidity float64
+	// Pressure in Pascal
+	Pressure uint32
+	// AccelerationX, AccelerationY, AccelerationZ in mG
+	AccelerationX int16
+	AccelerationY int16
+	AccelerationZ int16
+	// BatteryVoltage in mV
+	BatteryVoltage uint16
+	// TxPower in dBm
+	TxPower             int
+	MovementCounter     uint8
+	MeasurementSequence uint16
+	MAC                 string
+}
+
+// Ruuvi RAWv2 specifications
+// https://github.com/ruuvi/ruuvi-sensor-protocols/blob/master/dataformat_05.md
+// Byte(s) 	Name 						Notes
+// 0 				Data format 		0x05
+// 1-2 			Temperature 		int16, 0.005 degrees
+// 3-4 			Humidity 				uint16, 0.0025%
+// 5-6 			Pressure 				uint16, Pa with -50000 offset
+// 7-12 		Acceleration 		int16 X, Y, Z in mG
+// 13-14 		Power info 			11 bits battery voltage above 1.6V in mV, 5 bits TX power above -40dBm in 2dBm steps
+// 15 			Movement counter
+// 16-17 		Measurement sequence number
+// 18-23 		MAC address
+func decodeRuuvi(data *AdvertisementData) (interface{}, []byte, bool) {
+	frames, ok := data.ManufacturerData[ruuviManufacturerID]
+	if !ok {
+		return nil, nil, false
+	}
+	info, err := ParseRuuvi(frames)
+	if err != nil {
+		return nil, nil, false
+	}
+	return info, frames, true
+}
+
+// ParseRuuvi parse a RuuviTag data format 5 manufacturer data
+func ParseRuuvi(frames []byte) (BeaconRuuvi, error) {
+
+	info := BeaconRuuvi{}
+
+	if len(frames) != 24 {
+		return info, fmt.Errorf("Ruuvi: expected 24 bytes, got %d", len(frames))
+	}
+	if frames[0] != ruuviDataFormat5 {
+		return info, fmt.Errorf("Ruuvi: unsupported data format %d", frames[0])
+	}
+
+	info.DataFormat = frames[0]
+	info.Temperature = float64(int16(binary.BigEndian.Uint16(frames[1:3]))) * 0.005
+	info.Humidity = float64(binary.BigEndian.Uint16(frames[3:5])) * 0.0025
+	info.Pressure = uint32(binary.BigEndian.Uint16(frames[5:7])) + 50000
+	info.AccelerationX = int16(binary.BigEndian.Uint16(frames[7:9]))
+	info.AccelerationY = int16(binary.BigEndian.Uint16(frames[9:11]))
+	info.AccelerationZ = int16(binary.BigEndian.Uint16(frames[11:13]))
+
+	power := binary.BigEndian.Uint16(frames[13:15])
+	info.BatteryVoltage = power>>5 + 1600
+	info.TxPower = int(power&0x1F)*2 - 40
+
+	info.MovementCounter = frames[15]
+	info.MeasurementSequence = binary.BigEndian.Uint16(frames[16:18])
+
+	mac := []string{}
+	for _, b := range frames[18:24] {
+		mac = append(mac, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+	info.MAC = strings.Join(mac, ":")
+
+	return info, nil
+}
+
+func encodeRuuvi(i interface{}) (*advertising.LEAdvertisement1Properties, error) {
+
+	info, ok := i.(BeaconRuuvi)
+	if !ok {
+		return nil, fmt.Errorf("Ruuvi: expected BeaconRuuvi, got %T", i)
+	}
+
+	if info.BatteryVoltage < 1600 || info.BatteryVoltage > 1600+0x7FF {
+		return nil, fmt.Errorf("Ruuvi: battery voltage %d out of range", info.BatteryVoltage)
+	}
+	if info.TxPower < -40 || info.TxPower > 22 || info.TxPower%2 != 0 {
+		return nil, fmt.Errorf("Ruuvi: tx power %d out of range", info.TxPower)
+	}
+	if info.Pressure < 50000 || info.Pressure > 50000+0xFFFF {
+		return nil, fmt.Errorf("Ruuvi: pressure %d out of range", info.Pressure)
+	}
+
+	mac, err := hex.DecodeString(strings.Replace(info.MAC, ":", "", -1))
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("Ruuvi: invalid MAC address %s", info.MAC)
+	}
+
+	frames := make([]byte, 24)
+	frames[0] = ruuviDataFormat5
+	binary.BigEndian.PutUint16(frames[1:3], uint16(int16(math.Round(info.Temperature/0.005))))
+	binary.BigEndian.PutUint16(frames[3:5], uint16(math.Round(info.Humidity/0.0025)))
+	binary.BigEndian.PutUint16(frames[5:7], uint16(info.Pressure-50000))
+	binary.BigEndian.PutUint16(frames[7:9], uint16(info.AccelerationX))
+	binary.BigEndian.PutUint16(frames[9:11], uint16(info.AccelerationY))
+	binary.BigEndian.PutUint16(frames[11:13], uint16(info.AccelerationZ))
+	binary.BigEndian.PutUint16(frames[13:15], (info.BatteryVoltage-1600)<<5|uint16((info.TxPower+40)/2))
+	frames[15] = info.MovementCounter
+	binary.BigEndian.PutUint16(frames[16:18], info.MeasurementSequence)
+	copy(frames[18:24], mac)
+
+	props := new(advertising.LEAdvertisement1Properties)
+	props.AddManifacturerData(ruuviManufacturerID, frames)
+
+	return props, nil
+}
+
+// CreateRuuvi create a beacon in the RuuviTag data format 5
+func CreateRuuvi(info BeaconRuuvi) (*Beacon, error) {
+	info.DataFormat = ruuviDataFormat5
+	return CreateBeacon(BeaconTypeRuuvi, info)
+}
+
+// GetRuuvi return RuuviTag information
+func (b *Beacon) GetRuuvi() BeaconRuuvi {
+	if info, ok := b.info.(BeaconRuuvi); ok {
+		return info
+	}
+	return BeaconRuuvi{}
+}
+
+// IsRuuvi return if the type of beacon is RuuviTag
+func (b *Beacon) IsRuuvi() bool {
+	return b.Type == BeaconTypeRuuvi
+}
@@ -0,0 +1,40 @@
+package beacon
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRuuvi(t *testing.T) {
+
+	// valid data test vector from the Ruuvi data format 5 specifications
+	frames, err := hex.DecodeString("0512FC5394C37C0004FFFC040CAC364200CDCBB8334C884F")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ParseRuuvi(frames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.InDelta(t, 24.3, info.Temperature, 0.001)
+	assert.InDelta(t, 53.49, info.Humidity, 0.001)
+	assert.Equal(t, uint32(100044), info.Pressure)
+	assert.Equal(t, int16(4), info.AccelerationX)
+	assert.Equal(t, int16(-4), info.AccelerationY)
+	assert.Equal(t, int16(1036), info.AccelerationZ)
+	assert.Equal(t, uint16(2977), info.BatteryVoltage)
+	assert.Equal(t, 4, info.TxPower)
+	assert.Equal(t, uint8(66), info.MovementCounter)
+	assert.Equal(t, uint16(205), info.MeasurementSequence)
+	assert.Equal(t, "CB:B8:33:4C:88:4F", info.MAC)
+
+	b, err := CreateRuuvi(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, frames, b.GetFrames())
+}
@@ -0,0 +1,453 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/api"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	log "github.com/sirupsen/logrus"
+)
+
+// Eddystone frames of a simulated beacon
+const (
+	SimulatedFrameUID = "uid"
+	SimulatedFrameURL = "url"
+	SimulatedFrameTLM = "tlm"
+)
+
+const defaultSimulatedInterval = time.Second
+
+// BeaconDefinition describe a simulated beacon
+type BeaconDefinition struct {
+	ID   string     `json:"id"`
+	Type BeaconType `json:"type"`
+	// Frame is the Eddystone frame, one of uid, url, tlm
+	Frame string `json:"frame,omitempty"`
+
+	// iBeacon
+	ProximityUUID string `json:"uuid,omitempty"`
+	Major         uint16 `json:"major,omitempty"`
+	Minor         uint16 `json:"minor,omitempty"`
+	// MeasuredPower is the iBeacon RSSI at 1m, defaults to -59
+	MeasuredPower int `json:"measuredPower,omitempty"`
+
+	// Eddystone
+	Namespace string `json:"namespace,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	URL       string `json:"url,omitempty"`
+	// TxPower is the Eddystone calibrated power at 0m, defaults to -18
+	TxPower        int     `json:"txPower,omitempty"`
+	BatteryVoltage uint16  `json:"battery,omitempty"`
+	Temperature    float32 `json:"temperature,omitempty"`
+
+	// Address of the simulated device, generated if empty
+	Address string `json:"address,omitempty"`
+	// Interval between two advertisements, defaults to 1s
+	Interval Duration `json:"interval,omitempty"`
+	// Distance of the simulated beacon in meters, defaults to 1m
+	Distance float64 `json:"distance,omitempty"`
+	// PathLossExponent used to compute the RSSI, defaults to 2
+	PathLossExponent float64 `json:"pathLossExponent,omitempty"`
+	// Noise is the standard deviation of the RSSI in dB
+	Noise float64 `json:"noise,omitempty"`
+}
+
+// Duration is a time.Duration read from JSON as a string, eg. "500ms", or as
+// a number of milliseconds
+type Duration time.Duration
+
+// MarshalJSON encode the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decode a duration string or a number of milliseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case string:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(duration)
+	case float64:
+		*d = Duration(value * float64(time.Millisecond))
+	default:
+		return fmt.Errorf("Invalid duration %s", data)
+	}
+	return nil
+}
+
+// LoadBeaconDefinitions read a JSON list of beacon definitions
+func LoadBeaconDefinitions(r io.Reader) ([]BeaconDefinition, error) {
+	defs := []BeaconDefinition{}
+	err := json.NewDecoder(r).Decode(&defs)
+	if err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// AdvertisementReport is a simulated advertisement, as received on discovery
+type AdvertisementReport struct {
+	Definition *BeaconDefinition
+	// Device hold the advertised properties, as BlueZ reports them
+	Device *device.Device1
+	RSSI   int16
+	Time   time.Time
+}
+
+// Parse decode the report the same way a discovered device is
+func (r AdvertisementReport) Parse() (*Beacon, bool) {
+	b, err := NewBeacon(r.Device)
+	if err != nil {
+		return nil, false
+	}
+	if !b.Parse() {
+		return nil, false
+	}
+	return &b, true
+}
+
+// Observe feed the report to a RegionMonitor and a Ranger, either may be nil,
+// as Monitor and Ranger.Watch do for discovered devices. The simulator does
+// not drive them itself, RegionMonitor.Expire return the exited regions.
+// ok is false when the report does not parse.
+func (r AdvertisementReport) Observe(m *RegionMonitor, rg *Ranger) (events []RegionEvent, rng Range, ok bool) {
+
+	b, ok := r.Parse()
+	if !ok {
+		return nil, Range{}, false
+	}
+
+	if m != nil {
+		events = m.Observe(b, r.Time)
+	}
+	if rg != nil {
+		measuredPower, _ := b.MeasuredPower()
+		rng = rg.Update(string(r.Device.Path()), int(r.RSSI), measuredPower, r.Time)
+	}
+
+	return events, rng, true
+}
+
+type simulatedBeacon struct {
+	def    BeaconDefinition
+	path   dbus.ObjectPath
+	start  time.Time
+	next   time.Time
+	advCnt uint32
+}
+
+// SimulatorConfig configure a Simulator
+type SimulatorConfig struct {
+	// AdapterID used in the simulated device paths, defaults to hci0
+	AdapterID string
+	// Seed of the RSSI noise
+	Seed int64
+	// Start is the power on time of the beacons, defaults to now
+	Start time.Time
+}
+
+// NewSimulator create a simulator for a fleet of beacons
+func NewSimulator(defs []BeaconDefinition, config SimulatorConfig) (*Simulator, error) {
+
+	if config.AdapterID == "" {
+		config.AdapterID = "hci0"
+	}
+	if config.Start.IsZero() {
+		config.Start = time.Now()
+	}
+
+	s := &Simulator{
+		config:  config,
+		rand:    rand.New(rand.NewSource(config.Seed)),
+		beacons: make([]*simulatedBeacon, 0, len(defs)),
+	}
+
+	for i, def := range defs {
+
+		if def.ID == "" {
+			def.ID = fmt.Sprintf("beacon%d", i)
+		}
+		if def.Address == "" {
+			def.Address = fmt.Sprintf("C0:DE:%02X:%02X:%02X:%02X", byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+		}
+		if def.Interval <= 0 {
+			def.Interval = Duration(defaultSimulatedInterval)
+		}
+		if def.Distance <= 0 {
+			def.Distance = 1
+		}
+		if def.PathLossExponent <= 0 {
+			def.PathLossExponent = 2
+		}
+		if def.MeasuredPower == 0 {
+			def.MeasuredPower = -59
+		}
+		if def.TxPower == 0 {
+			def.TxPower = -18
+		}
+
+		sb := &simulatedBeacon{
+			def: def,
+			path: dbus.ObjectPath(fmt.Sprintf("%s/%s/dev_%s",
+				bluez.OrgBluezPath, config.AdapterID, strings.Replace(def.Address, ":", "_", -1))),
+			start: config.Start,
+			next:  config.Start,
+		}
+
+		// fail early on invalid definitions
+		_, err := sb.create(config.Start)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", def.ID, err)
+		}
+
+		s.beacons = append(s.beacons, sb)
+	}
+
+	return s, nil
+}
+
+// Simulator generate the advertisements of a fleet of virtual beacons
+type Simulator struct {
+	config  SimulatorConfig
+	lock    sync.Mutex
+	rand    *rand.Rand
+	beacons []*simulatedBeacon
+}
+
+// Len return the number of simulated beacons
+func (s *Simulator) Len() int {
+	return len(s.beacons)
+}
+
+// Next return the reports of the beacons advertising up to t
+func (s *Simulator) Next(t time.Time) []AdvertisementReport {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	reports := []AdvertisementReport{}
+
+	for _, sb := range s.beacons {
+		for !sb.next.After(t) {
+			report, err := s.report(sb, sb.next)
+			sb.next = sb.next.Add(time.Duration(sb.def.Interval))
+			if err != nil {
+				log.Warnf("Simulator: %s: %s", sb.def.ID, err)
+				continue
+			}
+			reports = append(reports, report)
+		}
+	}
+
+	return reports
+}
+
+// Run send the advertisements in real time until ctx is done, then close the channel
+func (s *Simulator) Run(ctx context.Context, tick time.Duration) (chan AdvertisementReport, error) {
+
+	if tick <= 0 {
+		return nil, fmt.Errorf("Invalid simulator tick %s", tick)
+	}
+
+	ch := make(chan AdvertisementReport)
+
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, report := range s.Next(now) {
+					select {
+					case ch <- report:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// Advertise rotate the simulated beacons through the advertising instances
+// of the manager, every interval each instance move to the next beacon,
+// until ctx is done
+func (s *Simulator) Advertise(ctx context.Context, m *api.AdvertisementManager, interval time.Duration) error {
+
+	if interval <= 0 {
+		return fmt.Errorf("Invalid advertising interval %s", interval)
+	}
+
+	slots, err := m.SupportedInstances()
+	if err != nil {
+		return err
+	}
+	if slots > len(s.beacons) {
+		slots = len(s.beacons)
+	}
+	if slots == 0 {
+		return api.ErrNoAdvertisingInstance
+	}
+
+	advs := make([]*api.Advertisement, 0, slots)
+	for i := 0; i < slots; i++ {
+		var adv *api.Advertisement
+		props, err := s.props(i, time.Now())
+		if err == nil {
+			adv, err = m.Add(props)
+		}
+		if err != nil {
+			for _, adv := range advs {
+				if rerr := m.Remove(adv); rerr != nil && rerr != api.ErrAdvertisementNotFound {
+					log.Warnf("Simulator: %s", rerr)
+				}
+			}
+			return err
+		}
+		advs = append(advs, adv)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		offset := 0
+		for {
+			select {
+			case <-ctx.Done():
+				for _, adv := range advs {
+					err := m.Remove(adv)
+					if err != nil && err != api.ErrAdvertisementNotFound {
+						log.Warnf("Simulator: %s", err)
+					}
+				}
+				return
+			case now := <-ticker.C:
+				offset = (offset + slots) % len(s.beacons)
+				for i, adv := range advs {
+					props, err := s.props((offset+i)%len(s.beacons), now)
+					if err == nil {
+						err = m.Update(adv, props)
+					}
+					if err != nil {
+						log.Warnf("Simulator: %s", err)
+					}
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *Simulator) props(i int, t time.Time) (*advertising.LEAdvertisement1Properties, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sb := s.beacons[i]
+	b, err := sb.create(t)
+	if err != nil {
+		return nil, err
+	}
+	sb.advCnt++
+	b.props.Type = advertising.AdvertisementTypeBroadcast
+	return b.props, nil
+}
+
+func (s *Simulator) report(sb *simulatedBeacon, t time.Time) (AdvertisementReport, error) {
+
+	b, err := sb.create(t)
+	if err != nil {
+		return AdvertisementReport{}, err
+	}
+	sb.advCnt++
+
+	def := sb.def
+	measuredPower := float64(def.MeasuredPower)
+	if def.Type == BeaconTypeEddystone {
+		measuredPower = float64(def.TxPower - eddystoneTxPowerLoss)
+	}
+	rssi := measuredPower - 10*def.PathLossExponent*math.Log10(def.Distance)
+	if def.Noise > 0 {
+		rssi += s.rand.NormFloat64() * def.Noise
+	}
+	rssi = math.Max(-127, math.Min(20, math.Round(rssi)))
+
+	props := &device.Device1Properties{
+		Address:          def.Address,
+		AddressType:      "random",
+		Name:             def.ID,
+		Alias:            def.ID,
+		RSSI:             int16(rssi),
+		ManufacturerData: map[uint16]interface{}{},
+		ServiceData:      map[string]interface{}{},
+	}
+	for _, uuid := range b.props.ServiceUUIDs {
+		props.UUIDs = append(props.UUIDs, fullUUID(uuid))
+	}
+	for uuid, data := range b.props.ServiceData {
+		props.ServiceData[fullUUID(uuid)] = data
+	}
+	for id, data := range b.props.ManufacturerData {
+		props.ManufacturerData[id] = data
+	}
+
+	return AdvertisementReport{
+		Definition: &sb.def,
+		Device:     device.NewDeviceWithProperties(sb.path, props),
+		RSSI:       props.RSSI,
+		Time:       t,
+	}, nil
+}
+
+// create encode the beacon state at time t
+func (sb *simulatedBeacon) create(t time.Time) (*Beacon, error) {
+	def := sb.def
+	switch def.Type {
+	case BeaconTypeIBeacon:
+		return CreateIBeacon(def.ProximityUUID, def.Major, def.Minor, uint16(uint8(int8(def.MeasuredPower))))
+	case BeaconTypeEddystone:
+		switch def.Frame {
+		case SimulatedFrameUID:
+			return CreateEddystoneUID(def.Namespace, def.Instance, def.TxPower)
+		case SimulatedFrameURL:
+			return CreateEddystoneURL(def.URL, def.TxPower)
+		case SimulatedFrameTLM:
+			// SEC_CNT has a 0.1s resolution
+			uptime := uint32(t.Sub(sb.start) / (100 * time.Millisecond))
+			return CreateEddystoneTLM(def.BatteryVoltage, def.Temperature, sb.advCnt, uptime)
+		}
+		return nil, fmt.Errorf("Unsupported Eddystone frame %s", def.Frame)
+	}
+	return nil, fmt.Errorf("Unsupported beacon type %s", def.Type)
+}
+
+// fullUUID expand a 16 bit UUID to the 128 bit form reported by BlueZ
+func fullUUID(uuid string) string {
+	if len(uuid) == 4 {
+		return "0000" + strings.ToLower(uuid) + "-0000-1000-8000-00805f9b34fb"
+	}
+	return strings.ToLower(uuid)
+}
@@ -0,0 +1,178 @@
+package beacon
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testFleet = `[
+	{"id": "entrance", "type": "ibeacon", "uuid": "AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD", "major": 1, "minor": 1, "distance": 0.3},
+	{"id": "shelf", "type": "ibeacon", "uuid": "AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD", "major": 2, "minor": 1, "distance": 8, "noise": 2},
+	{"id": "ns", "type": "eddystone", "frame": "uid", "namespace": "EDD1EBEAC04E5DEFA017", "instance": "0BDB87539B67", "distance": 2},
+	{"id": "url", "type": "eddystone", "frame": "url", "url": "https://example.com"},
+	{"id": "tlm", "type": "eddystone", "frame": "tlm", "battery": 3000, "temperature": 20, "interval": "500ms"}
+]`
+
+func TestSimulator(t *testing.T) {
+
+	defs, err := LoadBeaconDefinitions(strings.NewReader(testFleet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1500000000, 0)
+	s, err := NewSimulator(defs, SimulatorConfig{Seed: 1, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, s.Len())
+
+	// every beacon advertise at start
+	reports := s.Next(start)
+	assert.Len(t, reports, 5)
+
+	for _, report := range reports {
+		b, ok := report.Parse()
+		assert.True(t, ok, report.Definition.ID)
+		assert.Equal(t, report.Definition.Type, b.Type)
+	}
+
+	// the tlm beacon advertise twice per second
+	reports = s.Next(start.Add(time.Second))
+	assert.Len(t, reports, 6)
+
+	var tlm *Beacon
+	for _, report := range reports {
+		if report.Definition.ID == "tlm" {
+			tlm, _ = report.Parse()
+		}
+	}
+	if assert.NotNil(t, tlm) {
+		assert.Equal(t, uint32(10), tlm.GetEddystone().TLMLastRebootedTime)
+		assert.Equal(t, uint32(2), tlm.GetEddystone().TLMAdvertisingPDU)
+	}
+}
+
+func TestSimulatorInvalidDefinition(t *testing.T) {
+	_, err := NewSimulator([]BeaconDefinition{{Type: BeaconTypeIBeacon, ProximityUUID: "zz"}}, SimulatorConfig{})
+	assert.Error(t, err)
+	_, err = NewSimulator([]BeaconDefinition{{Type: BeaconTypeEddystone, Frame: "eid"}}, SimulatorConfig{})
+	assert.Error(t, err)
+}
+
+func TestSimulatorInvalidInterval(t *testing.T) {
+
+	s, err := NewSimulator([]BeaconDefinition{{Type: BeaconTypeIBeacon, ProximityUUID: "AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD"}}, SimulatorConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch, err := s.Run(context.Background(), 0)
+	assert.Error(t, err)
+	assert.Nil(t, ch)
+	assert.Error(t, s.Advertise(context.Background(), nil, -time.Second))
+}
+
+func TestSimulatorPipeline(t *testing.T) {
+
+	defs, err := LoadBeaconDefinitions(strings.NewReader(testFleet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1500000000, 0)
+	s, err := NewSimulator(defs, SimulatorConfig{Seed: 1, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := NewRegionMonitor(5 * time.Second)
+	monitor.AddRegion(NewIBeaconRegion("store", "AAAABBBB-CCCC-DDDD-AAAA-BBBBCCCCDDDD"))
+	monitor.AddRegion(NewEddystoneRegion("ns", "EDD1EBEAC04E5DEFA017", ""))
+
+	ranger := NewRanger(RangingConfig{})
+
+	entered := map[string]bool{}
+	ranges := map[string]Range{}
+
+	for i := 0; i < 30; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		for _, report := range s.Next(now) {
+			b, ok := report.Parse()
+			if !ok {
+				t.Fatalf("%s: not parsed", report.Definition.ID)
+			}
+			for _, ev := range monitor.Observe(b, report.Time) {
+				entered[ev.Region.ID] = ev.Type == RegionEnter
+			}
+			if power, ok := b.MeasuredPower(); ok {
+				ranges[report.Definition.ID] = ranger.Update(report.Definition.ID, int(report.RSSI), power, report.Time)
+			}
+		}
+	}
+
+	assert.True(t, entered["store"])
+	assert.True(t, entered["ns"])
+
+	assert.Equal(t, ProximityImmediate, ranges["entrance"].Proximity)
+	assert.Equal(t, ProximityFar, ranges["shelf"].Proximity)
+	assert.Equal(t, ProximityNear, ranges["ns"].Proximity)
+	assert.InDelta(t, 2, ranges["ns"].Distance, 0.2)
+}
+
+func TestDurationJSON(t *testing.T) {
+	defs, err := LoadBeaconDefinitions(strings.NewReader(`[{"interval": "1s"}, {"interval": 250}]`))
+	if assert.Nil(t, err) {
+		assert.Equal(t, Duration(time.Second), defs[0].Interval)
+		assert.Equal(t, Duration(250*time.Millisecond), defs[1].Interval)
+	}
+	_, err = LoadBeaconDefinitions(strings.NewReader(`[{"interval": "1 second"}]`))
+	assert.Error(t, err)
+
+	b, err := Duration(500 * time.Millisecond).MarshalJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, `"500ms"`, string(b))
+}
+
+func TestSimulatorObserve(t *testing.T) {
+
+	defs, err := LoadBeaconDefinitions(strings.NewReader(testFleet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1500000000, 0)
+	s, err := NewSimulator(defs, SimulatorConfig{Seed: 1, Start: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monitor := NewRegionMonitor(5 * time.Second)
+	monitor.AddRegion(NewIBeaconRegion("store", "AAAABBBBCCCCDDDDAAAABBBBCCCCDDDD"))
+	ranger := NewRanger(RangingConfig{})
+
+	entered := 0
+	for _, report := range s.Next(start.Add(2 * time.Second)) {
+		events, rng, ok := report.Observe(monitor, ranger)
+		if !assert.True(t, ok, report.Definition.ID) {
+			continue
+		}
+		entered += len(events)
+		assert.Equal(t, string(report.Device.Path()), rng.ID)
+		if report.Definition.ID == "entrance" {
+			assert.Equal(t, ProximityImmediate, rng.Proximity)
+		}
+	}
+	assert.Equal(t, 1, entered)
+	assert.True(t, monitor.Inside("store"))
+
+	// the beacons are gone once the simulation stops
+	events := monitor.Expire(start.Add(time.Minute))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, RegionExit, events[0].Type)
+	}
+}
@@ -0,0 +1,442 @@
+// package bond manage the pairing keys stored by BlueZ
+package bond
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/adapter"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultRoot is the BlueZ storage directory
+const DefaultRoot = "/var/lib/bluetooth"
+
+const infoFile = "info"
+
+// Info file sections
+const (
+	SectionGeneral               = "General"
+	SectionDeviceID              = "DeviceID"
+	SectionLinkKey               = "LinkKey"
+	SectionLongTermKey           = "LongTermKey"
+	SectionPeripheralLongTermKey = "PeripheralLongTermKey"
+	SectionSlaveLongTermKey      = "SlaveLongTermKey"
+	SectionIdentityResolvingKey  = "IdentityResolvingKey"
+	SectionLocalSignatureKey     = "LocalSignatureKey"
+	SectionRemoteSignatureKey    = "RemoteSignatureKey"
+	SectionConnectionParameters  = "ConnectionParameters"
+)
+
+var knownSections = []string{
+	SectionGeneral,
+	SectionDeviceID,
+	SectionLinkKey,
+	SectionLongTermKey,
+	SectionPeripheralLongTermKey,
+	SectionSlaveLongTermKey,
+	SectionIdentityResolvingKey,
+	SectionLocalSignatureKey,
+	SectionRemoteSignatureKey,
+	SectionConnectionParameters,
+}
+
+func isKnownSection(name string) bool {
+	for _, known := range knownSections {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
+
+var addressRegexp = regexp.MustCompile("^([0-9A-F]{2}:){5}[0-9A-F]{2}$")
+
+// ErrBondNotFound is returned when no info file exists for a device
+var ErrBondNotFound = errors.New("Bond not found")
+
+// LinkKey is a BR/EDR link key
+type LinkKey struct {
+	Key       []byte
+	Type      int
+	PINLength int
+}
+
+// LongTermKey is a LE long term key
+type LongTermKey struct {
+	Key           []byte
+	Authenticated int
+	EncSize       int
+	EDiv          uint16
+	Rand          uint64
+}
+
+// Bond is the info stored by BlueZ for a paired device
+type Bond struct {
+	// Adapter is the address of the local adapter
+	Adapter string `json:"adapter"`
+	// Address of the remote device
+	Address string `json:"address"`
+	Info    *Info  `json:"info"`
+}
+
+// Name return the device name
+func (b Bond) Name() string {
+	name, _ := b.Info.Get(SectionGeneral, "Name")
+	return name
+}
+
+// Trusted return the device trusted flag
+func (b Bond) Trusted() bool {
+	trusted, _ := b.Info.Get(SectionGeneral, "Trusted")
+	return trusted == "true"
+}
+
+// HasKeys return true if the info file holds a link key or a long term key
+func (b Bond) HasKeys() bool {
+	return b.Info.HasSection(SectionLinkKey) ||
+		b.Info.HasSection(SectionLongTermKey) ||
+		b.Info.HasSection(SectionPeripheralLongTermKey) ||
+		b.Info.HasSection(SectionSlaveLongTermKey)
+}
+
+func (b Bond) key(section string) ([]byte, bool, error) {
+	value, ok := b.Info.Get(section, "Key")
+	if !ok {
+		return nil, false, nil
+	}
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %s", section, err)
+	}
+	return key, true, nil
+}
+
+func (b Bond) int(section, name string) int {
+	value, _ := b.Info.Get(section, name)
+	i, _ := strconv.ParseInt(value, 0, 64)
+	return int(i)
+}
+
+// LinkKey return the BR/EDR link key
+func (b Bond) LinkKey() (*LinkKey, error) {
+	key, ok, err := b.key(SectionLinkKey)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &LinkKey{
+		Key:       key,
+		Type:      b.int(SectionLinkKey, "Type"),
+		PINLength: b.int(SectionLinkKey, "PINLength"),
+	}, nil
+}
+
+// LongTermKey return the LE long term key
+func (b Bond) LongTermKey() (*LongTermKey, error) {
+	key, ok, err := b.key(SectionLongTermKey)
+	if err != nil || !ok {
+		return nil, err
+	}
+	rnd, _ := b.Info.Get(SectionLongTermKey, "Rand")
+	rand, _ := strconv.ParseUint(rnd, 0, 64)
+	return &LongTermKey{
+		Key:           key,
+		Authenticated: b.int(SectionLongTermKey, "Authenticated"),
+		EncSize:       b.int(SectionLongTermKey, "EncSize"),
+		EDiv:          uint16(b.int(SectionLongTermKey, "EDiv")),
+		Rand:          rand,
+	}, nil
+}
+
+// IdentityResolvingKey return the LE identity resolving key
+func (b Bond) IdentityResolvingKey() ([]byte, error) {
+	key, _, err := b.key(SectionIdentityResolvingKey)
+	return key, err
+}
+
+// NewManager create a bond manager on a BlueZ storage directory, an empty
+// root defaults to /var/lib/bluetooth
+func NewManager(root string) *Manager {
+	if root == "" {
+		root = DefaultRoot
+	}
+	return &Manager{
+		Root: root,
+	}
+}
+
+// Manager read and write the info files of the BlueZ storage. BlueZ load
+// the info files on start, changes are applied once the daemon restarts.
+type Manager struct {
+	Root string
+}
+
+func normalizeAddress(address string) (string, error) {
+	address = strings.ToUpper(address)
+	if !addressRegexp.MatchString(address) {
+		return "", fmt.Errorf("Invalid address %s", address)
+	}
+	return address, nil
+}
+
+func (m *Manager) path(adapterAddress, address string) (string, error) {
+	adapterAddress, err := normalizeAddress(adapterAddress)
+	if err != nil {
+		return "", err
+	}
+	address, err = normalizeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(m.Root, adapterAddress, address), nil
+}
+
+// Adapters return the addresses of the adapters in the storage
+func (m *Manager) Adapters() ([]string, error) {
+	return m.listAddresses(m.Root)
+}
+
+func (m *Manager) listAddresses(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	addresses := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() && addressRegexp.MatchString(entry.Name()) {
+			addresses = append(addresses, entry.Name())
+		}
+	}
+	return addresses, nil
+}
+
+// List return the bonds of an adapter, devices known without keys are skipped
+func (m *Manager) List(adapterAddress string) ([]Bond, error) {
+
+	adapterAddress, err := normalizeAddress(adapterAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := m.listAddresses(filepath.Join(m.Root, adapterAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	bonds := []Bond{}
+	for _, address := range addresses {
+		b, err := m.Get(adapterAddress, address)
+		if err == ErrBondNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !b.HasKeys() {
+			continue
+		}
+		bonds = append(bonds, b)
+	}
+
+	return bonds, nil
+}
+
+// ListAll return the bonds of every adapter
+func (m *Manager) ListAll() ([]Bond, error) {
+	adapters, err := m.Adapters()
+	if err != nil {
+		return nil, err
+	}
+	bonds := []Bond{}
+	for _, adapterAddress := range adapters {
+		list, err := m.List(adapterAddress)
+		if err != nil {
+			return nil, err
+		}
+		bonds = append(bonds, list...)
+	}
+	return bonds, nil
+}
+
+// Get read the info file of a device
+func (m *Manager) Get(adapterAddress, address string) (Bond, error) {
+
+	dir, err := m.path(adapterAddress, address)
+	if err != nil {
+		return Bond{}, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, infoFile))
+	if os.IsNotExist(err) {
+		return Bond{}, ErrBondNotFound
+	}
+	if err != nil {
+		return Bond{}, err
+	}
+	defer f.Close()
+
+	info, err := ParseInfo(f)
+	if err != nil {
+		return Bond{}, fmt.Errorf("%s: %s", f.Name(), err)
+	}
+
+	return Bond{
+		Adapter: strings.ToUpper(adapterAddress),
+		Address: strings.ToUpper(address),
+		Info:    info,
+	}, nil
+}
+
+// check return the storage directory of a bond to save
+func (m *Manager) check(b Bond) (string, error) {
+	if b.Info == nil {
+		return "", fmt.Errorf("%s: missing info", b.Address)
+	}
+	return m.path(b.Adapter, b.Address)
+}
+
+// Save write the info file of a bond, replacing any existing one
+func (m *Manager) Save(b Bond) error {
+
+	dir, err := m.check(b)
+	if err != nil {
+		return err
+	}
+
+	// BlueZ keep the keys readable by root only
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// write then rename to never leave a truncated file
+	tmp, err := ioutil.TempFile(dir, infoFile)
+	if err != nil {
+		return err
+	}
+	_, err = b.Info.WriteTo(tmp)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, infoFile))
+}
+
+// Delete remove the storage of a device, prefer Remove while BlueZ is running
+func (m *Manager) Delete(adapterAddress, address string) error {
+	dir, err := m.path(adapterAddress, address)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// Export write the bonds as JSON
+func (m *Manager) Export(w io.Writer, bonds []Bond) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bonds)
+}
+
+// Import read bonds exported as JSON and save them. Nothing is saved when a
+// bond is invalid, on a write error the bonds saved so far are returned with
+// the error.
+func (m *Manager) Import(r io.Reader) ([]Bond, error) {
+	bonds := []Bond{}
+	err := json.NewDecoder(r).Decode(&bonds)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bonds {
+		_, err := m.check(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, b := range bonds {
+		err := m.Save(b)
+		if err != nil {
+			return bonds[:i], fmt.Errorf("%s: %s", b.Address, err)
+		}
+	}
+	return bonds, nil
+}
+
+// DevicePath return the object path of a bonded device on an adapter
+func DevicePath(adapterID, address string) dbus.ObjectPath {
+	return dbus.ObjectPath(fmt.Sprintf("%s/%s/dev_%s",
+		bluez.OrgBluezPath, adapterID, strings.Replace(strings.ToUpper(address), ":", "_", -1)))
+}
+
+// checkAdapter verify the bond belong to the adapter
+func checkAdapter(a *adapter.Adapter1, adapterAddress string) error {
+	address, err := a.GetAddress()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(address, adapterAddress) {
+		return fmt.Errorf("Bond of %s, adapter address is %s", adapterAddress, address)
+	}
+	return nil
+}
+
+// Device return the Device1 of a bond on adapterID
+func (m *Manager) Device(adapterID string, b Bond) (*device.Device1, error) {
+
+	a, err := adapter.GetAdapter(adapterID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkAdapter(a, b.Adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	return device.NewDevice1(DevicePath(adapterID, b.Address))
+}
+
+// Remove revoke a bond through Adapter1.RemoveDevice, which delete the keys
+// from BlueZ and the storage. The storage is cleaned up if BlueZ does not
+// know the device.
+func (m *Manager) Remove(adapterID string, b Bond) error {
+
+	a, err := adapter.GetAdapter(adapterID)
+	if err != nil {
+		return err
+	}
+
+	err = checkAdapter(a, b.Adapter)
+	if err != nil {
+		return err
+	}
+
+	err = a.RemoveDevice(DevicePath(adapterID, b.Address))
+	if err != nil {
+		if bluez.ErrorName(err) != "org.bluez.Error.DoesNotExist" {
+			return err
+		}
+		log.Debugf("Bond %s: device not found, removing storage", b.Address)
+	}
+
+	return m.Delete(b.Adapter, b.Address)
+}
@@ -0,0 +1,206 @@
+package bond
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type infoSection struct {
+	name   string
+	keys   []string
+	values map[string]string
+}
+
+// Info is a BlueZ info file, a key file with [Section] groups of key=value.
+// Unknown sections and keys are preserved in their original order.
+type Info struct {
+	sections []*infoSection
+}
+
+// NewInfo create an empty Info
+func NewInfo() *Info {
+	return &Info{}
+}
+
+// ParseInfo read an info file
+func ParseInfo(r io.Reader) (*Info, error) {
+
+	info := NewInfo()
+	var section *infoSection
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section %s", lineNo, line)
+			}
+			section = info.section(line[1:len(line)-1], true)
+			continue
+		}
+		if section == nil {
+			return nil, fmt.Errorf("line %d: key outside of a section", lineNo)
+		}
+		i := strings.Index(line, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("line %d: invalid entry %s", lineNo, line)
+		}
+		section.set(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (s *infoSection) set(key, value string) {
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+}
+
+func (i *Info) section(name string, create bool) *infoSection {
+	for _, s := range i.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	if !create {
+		return nil
+	}
+	s := &infoSection{
+		name:   name,
+		values: map[string]string{},
+	}
+	i.sections = append(i.sections, s)
+	return s
+}
+
+// Sections return the section names
+func (i *Info) Sections() []string {
+	names := make([]string, len(i.sections))
+	for j, s := range i.sections {
+		names[j] = s.name
+	}
+	return names
+}
+
+// HasSection return true if the section exists
+func (i *Info) HasSection(name string) bool {
+	return i.section(name, false) != nil
+}
+
+// Get return the value of a key
+func (i *Info) Get(section, key string) (string, bool) {
+	s := i.section(section, false)
+	if s == nil {
+		return "", false
+	}
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Set the value of a key, creating the section if needed
+func (i *Info) Set(section, key, value string) {
+	i.section(section, true).set(key, value)
+}
+
+// RemoveSection remove a section and its keys
+func (i *Info) RemoveSection(name string) {
+	for j, s := range i.sections {
+		if s.name == name {
+			i.sections = append(i.sections[:j], i.sections[j+1:]...)
+			return
+		}
+	}
+}
+
+// WriteTo write the info file
+func (i *Info) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for j, s := range i.sections {
+		if j > 0 {
+			c, err := io.WriteString(w, "\n")
+			n += int64(c)
+			if err != nil {
+				return n, err
+			}
+		}
+		c, err := fmt.Fprintf(w, "[%s]\n", s.name)
+		n += int64(c)
+		if err != nil {
+			return n, err
+		}
+		for _, key := range s.keys {
+			c, err := fmt.Fprintf(w, "%s=%s\n", key, s.values[key])
+			n += int64(c)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (i *Info) String() string {
+	b := new(strings.Builder)
+	i.WriteTo(b)
+	return b.String()
+}
+
+// MarshalJSON encode the info file as an object of sections
+func (i *Info) MarshalJSON() ([]byte, error) {
+	m := make(map[string]map[string]string, len(i.sections))
+	for _, s := range i.sections {
+		m[s.name] = s.values
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decode an object of sections
+func (i *Info) UnmarshalJSON(data []byte) error {
+	m := map[string]map[string]string{}
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return err
+	}
+	i.sections = nil
+	// keep the BlueZ order for the known sections
+	names := []string{}
+	for _, name := range knownSections {
+		if _, ok := m[name]; ok {
+			names = append(names, name)
+		}
+	}
+	others := []string{}
+	for name := range m {
+		if !isKnownSection(name) {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range append(names, others...) {
+		keys := make([]string, 0, len(m[name]))
+		for key := range m[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		s := i.section(name, true)
+		for _, key := range keys {
+			s.set(key, m[name][key])
+		}
+	}
+	return nil
+}
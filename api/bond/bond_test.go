@@ -0,0 +1,256 @@
+package bond
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAdapter = "00:1A:7D:DA:71:13"
+
+const testHeadsetInfo = `[General]
+Name=Headset
+Class=0x240404
+SupportedTechnologies=BR/EDR;
+Trusted=true
+Blocked=false
+
+[LinkKey]
+Key=6C2B1B4B9B7F8D0A4F3E2D1C0B0A0908
+Type=4
+PINLength=0
+
+[X-Custom]
+Foo=bar
+`
+
+const testSensorInfo = `[General]
+Name=Sensor
+AddressType=static
+Trusted=false
+
+[IdentityResolvingKey]
+Key=A1B2C3D4E5F60718293A4B5C6D7E8F90
+
+[LongTermKey]
+Key=00112233445566778899AABBCCDDEEFF
+Authenticated=1
+EncSize=16
+EDiv=4660
+Rand=1311768467463790320
+`
+
+func writeInfo(t *testing.T, root, adapterAddress, address, content string) {
+	dir := filepath.Join(root, adapterAddress, address)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, infoFile), []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createTestRoot(t *testing.T) string {
+	root, err := ioutil.TempDir("", "bond")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeInfo(t, root, testAdapter, "C0:DE:00:00:00:01", testHeadsetInfo)
+	writeInfo(t, root, testAdapter, "C0:DE:00:00:00:02", testSensorInfo)
+	// known device without keys
+	writeInfo(t, root, testAdapter, "C0:DE:00:00:00:03", "[General]\nName=Known\n")
+	err = ioutil.WriteFile(filepath.Join(root, testAdapter, "settings"), []byte("[General]\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestParseInfo(t *testing.T) {
+	info, err := ParseInfo(strings.NewReader(testHeadsetInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{SectionGeneral, SectionLinkKey, "X-Custom"}, info.Sections())
+	name, ok := info.Get(SectionGeneral, "Name")
+	assert.True(t, ok)
+	assert.Equal(t, "Headset", name)
+
+	// round trip
+	assert.Equal(t, testHeadsetInfo, info.String())
+
+	_, err = ParseInfo(strings.NewReader("Name=foo\n"))
+	assert.Error(t, err)
+	_, err = ParseInfo(strings.NewReader("[General\n"))
+	assert.Error(t, err)
+}
+
+func TestManagerList(t *testing.T) {
+	root := createTestRoot(t)
+	defer os.RemoveAll(root)
+
+	m := NewManager(root)
+
+	adapters, err := m.Adapters()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{testAdapter}, adapters)
+
+	bonds, err := m.List(strings.ToLower(testAdapter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, bonds, 2) {
+		return
+	}
+
+	headset := bonds[0]
+	assert.Equal(t, "Headset", headset.Name())
+	assert.True(t, headset.Trusted())
+	key, err := headset.LinkKey()
+	assert.Nil(t, err)
+	if assert.NotNil(t, key) {
+		assert.Equal(t, 4, key.Type)
+		assert.Len(t, key.Key, 16)
+	}
+	ltk, err := headset.LongTermKey()
+	assert.Nil(t, err)
+	assert.Nil(t, ltk)
+
+	sensor := bonds[1]
+	ltk, err = sensor.LongTermKey()
+	assert.Nil(t, err)
+	if assert.NotNil(t, ltk) {
+		assert.Equal(t, 1, ltk.Authenticated)
+		assert.Equal(t, uint16(4660), ltk.EDiv)
+		assert.Equal(t, uint64(1311768467463790320), ltk.Rand)
+	}
+	irk, err := sensor.IdentityResolvingKey()
+	assert.Nil(t, err)
+	assert.Len(t, irk, 16)
+
+	_, err = m.Get(testAdapter, "C0:DE:00:00:00:09")
+	assert.Equal(t, ErrBondNotFound, err)
+	_, err = m.Get(testAdapter, "../../etc")
+	assert.Error(t, err)
+}
+
+func TestManagerExportImport(t *testing.T) {
+	root := createTestRoot(t)
+	defer os.RemoveAll(root)
+
+	m := NewManager(root)
+	bonds, err := m.ListAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = m.Export(buf, bonds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// import on a fresh install
+	root2, err := ioutil.TempDir("", "bond")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root2)
+
+	m2 := NewManager(root2)
+	imported, err := m2.Import(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, imported, 2)
+
+	bonds2, err := m2.ListAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, bonds2, 2) {
+		for i := range bonds {
+			assert.Equal(t, bonds[i].Address, bonds2[i].Address)
+			j1, _ := bonds[i].Info.MarshalJSON()
+			j2, _ := bonds2[i].Info.MarshalJSON()
+			assert.JSONEq(t, string(j1), string(j2))
+			k1, _ := bonds[i].LinkKey()
+			k2, _ := bonds2[i].LinkKey()
+			assert.Equal(t, k1, k2)
+			l1, _ := bonds[i].LongTermKey()
+			l2, _ := bonds2[i].LongTermKey()
+			assert.Equal(t, l1, l2)
+		}
+		// known sections keep the BlueZ order
+		assert.Equal(t, []string{SectionGeneral, SectionLinkKey, "X-Custom"}, bonds2[0].Info.Sections())
+	}
+
+	stat, err := os.Stat(filepath.Join(root2, testAdapter, "C0:DE:00:00:00:01", infoFile))
+	if assert.Nil(t, err) {
+		assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+	}
+
+	err = m2.Delete(testAdapter, "C0:DE:00:00:00:01")
+	assert.Nil(t, err)
+	bonds2, _ = m2.List(testAdapter)
+	assert.Len(t, bonds2, 1)
+}
+
+func TestManagerImportPartial(t *testing.T) {
+	root := createTestRoot(t)
+	defer os.RemoveAll(root)
+
+	bonds, err := NewManager(root).ListAll()
+	if err != nil || len(bonds) != 2 {
+		t.Fatal("test bonds not found", err)
+	}
+	export := func(bonds []Bond) *bytes.Buffer {
+		buf := new(bytes.Buffer)
+		if err := NewManager(root).Export(buf, bonds); err != nil {
+			t.Fatal(err)
+		}
+		return buf
+	}
+
+	root2, err := ioutil.TempDir("", "bond")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root2)
+	m := NewManager(root2)
+
+	// an invalid bond fail the import before writing
+	invalid := append([]Bond{}, bonds...)
+	invalid[1].Address = "../../etc"
+	imported, err := m.Import(export(invalid))
+	assert.Error(t, err)
+	assert.Nil(t, imported)
+	entries, _ := ioutil.ReadDir(root2)
+	assert.Empty(t, entries)
+
+	// a file in place of the second bond directory fail its write
+	err = os.MkdirAll(filepath.Join(root2, bonds[1].Adapter), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(root2, bonds[1].Adapter, bonds[1].Address), nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err = m.Import(export(bonds))
+	assert.Error(t, err)
+	if assert.Len(t, imported, 1) {
+		assert.Equal(t, bonds[0].Address, imported[0].Address)
+	}
+}
+
+func TestDevicePath(t *testing.T) {
+	assert.Equal(t, "/org/bluez/hci0/dev_C0_DE_00_00_00_01", string(DevicePath("hci0", "c0:de:00:00:00:01")))
+}
@@ -0,0 +1,454 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/media"
+	"github.com/woongchantonylee/go-bluetooth/props"
+	log "github.com/sirupsen/logrus"
+)
+
+// BaseMediaPlayerPath is the object path of the exposed players
+const BaseMediaPlayerPath = "/%s/player/%d"
+
+// MediaPlayerInterface is the MPRIS interface BlueZ expects from a
+// registered player
+const MediaPlayerInterface = "org.mpris.MediaPlayer2.Player"
+
+// MediaPlayerHandler receive the commands sent by the connected devices.
+// The handler should report the resulting state with SetStatus.
+type MediaPlayerHandler interface {
+	Play() error
+	Pause() error
+	Stop() error
+	Next() error
+	Previous() error
+	// Seek move the position by offset, negative to rewind
+	Seek(offset time.Duration) error
+}
+
+// MediaPlayerProperties are the MPRIS player properties
+type MediaPlayerProperties struct {
+	lock sync.RWMutex `dbus:"ignore"`
+
+	PlaybackStatus string `dbus:"emit"`
+	LoopStatus     string `dbus:"emit"`
+	Shuffle        bool   `dbus:"emit"`
+	// Position is in microseconds
+	Position int64                   `dbus:"emit"`
+	Metadata map[string]dbus.Variant `dbus:"emit"`
+
+	Rate          float64
+	MinimumRate   float64
+	MaximumRate   float64
+	Volume        float64
+	CanGoNext     bool
+	CanGoPrevious bool
+	CanPlay       bool
+	CanPause      bool
+	CanSeek       bool
+	CanControl    bool
+}
+
+//Lock access to properties
+func (p *MediaPlayerProperties) Lock() {
+	p.lock.Lock()
+}
+
+//Unlock access to properties
+func (p *MediaPlayerProperties) Unlock() {
+	p.lock.Unlock()
+}
+
+// ToMap convert a MediaPlayerProperties to map
+func (p *MediaPlayerProperties) ToMap() (map[string]interface{}, error) {
+	return props.ToMap(p), nil
+}
+
+var mprisStatus = map[string]string{
+	media.StatusPlaying: "Playing",
+	media.StatusPaused:  "Paused",
+	media.StatusStopped: "Stopped",
+}
+
+var mprisLoopStatus = map[string]string{
+	media.ModeOff:         "None",
+	media.ModeSingleTrack: "Track",
+	media.ModeAllTracks:   "Playlist",
+}
+
+// trackMetadata convert a track to the MPRIS metadata
+func trackMetadata(trackID dbus.ObjectPath, track media.Track) map[string]dbus.Variant {
+	m := map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(trackID),
+		"xesam:title":   dbus.MakeVariant(track.Title),
+		"mpris:length":  dbus.MakeVariant(int64(track.Duration / time.Microsecond)),
+	}
+	if track.Artist != "" {
+		m["xesam:artist"] = dbus.MakeVariant([]string{track.Artist})
+	}
+	if track.Album != "" {
+		m["xesam:album"] = dbus.MakeVariant(track.Album)
+	}
+	if track.Genre != "" {
+		m["xesam:genre"] = dbus.MakeVariant([]string{track.Genre})
+	}
+	if track.TrackNumber > 0 {
+		m["xesam:trackNumber"] = dbus.MakeVariant(int32(track.TrackNumber))
+	}
+	if track.NumberOfTracks > 0 {
+		m["xesam:totalTracks"] = dbus.MakeVariant(int32(track.NumberOfTracks))
+	}
+	return m
+}
+
+// NewMediaPlayer create a player controlled by handler, to expose on
+// adapterID
+func NewMediaPlayer(adapterID string, handler MediaPlayerHandler) (*MediaPlayer, error) {
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	om, err := NewDBusObjectManager(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	iprops, err := NewDBusProperties(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &MediaPlayer{
+		adapterID:     adapterID,
+		handler:       handler,
+		conn:          conn,
+		objectManager: om,
+		iprops:        iprops,
+		path:          bluez.NewObjectPath(BaseMediaPlayerPath, adapterID),
+		props: &MediaPlayerProperties{
+			PlaybackStatus: mprisStatus[media.StatusStopped],
+			LoopStatus:     mprisLoopStatus[media.ModeOff],
+			Rate:           1,
+			MinimumRate:    1,
+			MaximumRate:    1,
+			Volume:         1,
+			CanGoNext:      true,
+			CanGoPrevious:  true,
+			CanPlay:        true,
+			CanPause:       true,
+			CanSeek:        true,
+			CanControl:     true,
+		},
+	}
+	p.props.Metadata = trackMetadata(p.trackID(), media.Track{})
+
+	return p, nil
+}
+
+// MediaPlayer is a local player exposed to BlueZ, which makes it
+// available to the connected devices over AVRCP
+type MediaPlayer struct {
+	adapterID     string
+	handler       MediaPlayerHandler
+	path          dbus.ObjectPath
+	conn          *dbus.Conn
+	objectManager *DBusObjectManager
+	iprops        *DBusProperties
+	props         *MediaPlayerProperties
+
+	lock       sync.Mutex
+	status     string
+	position   time.Duration
+	updated    time.Time
+	track      int
+	exposed    bool
+	registered bool
+}
+
+func (p *MediaPlayer) Path() dbus.ObjectPath {
+	return p.path
+}
+
+func (p *MediaPlayer) Interface() string {
+	return MediaPlayerInterface
+}
+
+func (p *MediaPlayer) GetProperties() bluez.Properties {
+	return p.props
+}
+
+func (p *MediaPlayer) DBusProperties() *DBusProperties {
+	return p.iprops
+}
+
+func (p *MediaPlayer) DBusObjectManager() *DBusObjectManager {
+	return p.objectManager
+}
+
+func (p *MediaPlayer) DBusConn() *dbus.Conn {
+	return p.conn
+}
+
+// trackID return the MPRIS id of the current track
+func (p *MediaPlayer) trackID() dbus.ObjectPath {
+	return dbus.ObjectPath(fmt.Sprintf("%s/track%d", p.path, p.track))
+}
+
+// set update a property, emitting the change once exposed. p.lock must
+// be held.
+func (p *MediaPlayer) set(name string, value interface{}) {
+	if !p.exposed {
+		return
+	}
+	p.iprops.Instance().SetMust(p.Interface(), name, value)
+}
+
+// SetStatus set the playback status, one of media.StatusPlaying,
+// StatusPaused or StatusStopped
+func (p *MediaPlayer) SetStatus(status string) error {
+	value, ok := mprisStatus[status]
+	if !ok {
+		return fmt.Errorf("Status %s not supported", status)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.position = p.elapsed()
+	p.updated = time.Now()
+	p.status = status
+
+	p.props.Lock()
+	p.props.PlaybackStatus = value
+	p.props.Position = int64(p.position / time.Microsecond)
+	p.props.Unlock()
+
+	p.set("Position", p.props.Position)
+	p.set("PlaybackStatus", value)
+	return nil
+}
+
+// SetTrack set the current track, resetting the position
+func (p *MediaPlayer) SetTrack(track media.Track) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.track++
+	p.position = 0
+	p.updated = time.Now()
+
+	metadata := trackMetadata(p.trackID(), track)
+	p.props.Lock()
+	p.props.Metadata = metadata
+	p.props.Position = 0
+	p.props.Unlock()
+
+	p.set("Metadata", metadata)
+	p.set("Position", int64(0))
+}
+
+// UpdatePosition set the playback position, eg. after a seek
+func (p *MediaPlayer) UpdatePosition(position time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.position = position
+	p.updated = time.Now()
+
+	value := int64(position / time.Microsecond)
+	p.props.Lock()
+	p.props.Position = value
+	p.props.Unlock()
+
+	p.set("Position", value)
+}
+
+// SetShuffle set the shuffle mode
+func (p *MediaPlayer) SetShuffle(shuffle bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.props.Lock()
+	p.props.Shuffle = shuffle
+	p.props.Unlock()
+	p.set("Shuffle", shuffle)
+}
+
+// SetRepeat set the repeat mode, media.ModeOff, ModeSingleTrack or
+// ModeAllTracks
+func (p *MediaPlayer) SetRepeat(mode string) error {
+	value, ok := mprisLoopStatus[mode]
+	if !ok {
+		return fmt.Errorf("Repeat mode %s not supported", mode)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.props.Lock()
+	p.props.LoopStatus = value
+	p.props.Unlock()
+	p.set("LoopStatus", value)
+	return nil
+}
+
+// Position return the current position, estimated while playing
+func (p *MediaPlayer) Position() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.elapsed()
+}
+
+func (p *MediaPlayer) elapsed() time.Duration {
+	if p.status == media.StatusPlaying && !p.updated.IsZero() {
+		return p.position + time.Since(p.updated)
+	}
+	return p.position
+}
+
+// Expose export the player and register it on the adapter
+func (p *MediaPlayer) Expose() error {
+
+	// the properties exposed must not change before exposed is set
+	p.lock.Lock()
+	err := ExposeDBusService(p)
+	if err == nil {
+		p.exposed = true
+	}
+	p.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	m, err := media.NewMedia1(dbus.ObjectPath(fmt.Sprintf("/org/bluez/%s", p.adapterID)))
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	properties, err := p.props.ToMap()
+	if err != nil {
+		return err
+	}
+
+	err = m.RegisterPlayer(p.path, properties)
+	if err != nil {
+		return fmt.Errorf("RegisterPlayer %s: %s", p.path, err)
+	}
+	p.registered = true
+
+	return nil
+}
+
+// Remove unregister the player, unexport it and release its object path
+func (p *MediaPlayer) Remove() error {
+
+	defer bluez.ReleaseObjectPath(p.path)
+
+	if p.registered {
+		m, err := media.NewMedia1(dbus.ObjectPath(fmt.Sprintf("/org/bluez/%s", p.adapterID)))
+		if err == nil {
+			err = m.UnregisterPlayer(p.path)
+			m.Close()
+		}
+		if err != nil {
+			// the adapter may be gone
+			log.Debugf("MediaPlayer %s: UnregisterPlayer: %s", p.path, err)
+		}
+		p.registered = false
+	}
+
+	p.lock.Lock()
+	exposed := p.exposed
+	p.exposed = false
+	p.lock.Unlock()
+
+	if !exposed {
+		return nil
+	}
+	return UnexportDBusService(p)
+}
+
+// dispatch call fx, returning the error to the caller
+func (p *MediaPlayer) dispatch(name string, fx func() error) *dbus.Error {
+	log.Debugf("MediaPlayer %s: %s", p.path, name)
+	err := fx()
+	if err != nil {
+		log.Debugf("MediaPlayer %s: %s: %s", p.path, name, err)
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// Play start or resume the playback
+func (p *MediaPlayer) Play() *dbus.Error {
+	return p.dispatch("Play", p.handler.Play)
+}
+
+// Pause the playback
+func (p *MediaPlayer) Pause() *dbus.Error {
+	return p.dispatch("Pause", p.handler.Pause)
+}
+
+// PlayPause toggle the playback
+func (p *MediaPlayer) PlayPause() *dbus.Error {
+	p.lock.Lock()
+	playing := p.status == media.StatusPlaying
+	p.lock.Unlock()
+	if playing {
+		return p.Pause()
+	}
+	return p.Play()
+}
+
+// Stop the playback
+func (p *MediaPlayer) Stop() *dbus.Error {
+	return p.dispatch("Stop", p.handler.Stop)
+}
+
+// Next skip to the next track
+func (p *MediaPlayer) Next() *dbus.Error {
+	return p.dispatch("Next", p.handler.Next)
+}
+
+// Previous skip to the previous track
+func (p *MediaPlayer) Previous() *dbus.Error {
+	return p.dispatch("Previous", p.handler.Previous)
+}
+
+// DBusMethodMapping export SeekBy as Seek, the Go name is reserved to
+// io.Seeker
+func (p *MediaPlayer) DBusMethodMapping() map[string]string {
+	return map[string]string{"SeekBy": "Seek"}
+}
+
+// SeekBy move the position by offset microseconds, exported as Seek
+func (p *MediaPlayer) SeekBy(offset int64) *dbus.Error {
+	return p.dispatch("Seek", func() error {
+		return p.handler.Seek(time.Duration(offset) * time.Microsecond)
+	})
+}
+
+// SetPosition move to position microseconds of the track, ignored if
+// trackID is not the current track
+func (p *MediaPlayer) SetPosition(trackID dbus.ObjectPath, position int64) *dbus.Error {
+	p.lock.Lock()
+	current := p.trackID()
+	offset := time.Duration(position)*time.Microsecond - p.elapsed()
+	p.lock.Unlock()
+
+	if trackID != current {
+		log.Debugf("MediaPlayer %s: SetPosition: stale track %s", p.path, trackID)
+		return nil
+	}
+	return p.dispatch("SetPosition", func() error {
+		return p.handler.Seek(offset)
+	})
+}
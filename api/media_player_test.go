@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPlayerHandler struct {
+	calls []string
+	seek  time.Duration
+	err   error
+}
+
+func (h *testPlayerHandler) Play() error {
+	h.calls = append(h.calls, "Play")
+	return h.err
+}
+
+func (h *testPlayerHandler) Pause() error {
+	h.calls = append(h.calls, "Pause")
+	return h.err
+}
+
+func (h *testPlayerHandler) Stop() error {
+	h.calls = append(h.calls, "Stop")
+	return h.err
+}
+
+func (h *testPlayerHandler) Next() error {
+	h.calls = append(h.calls, "Next")
+	return h.err
+}
+
+func (h *testPlayerHandler) Previous() error {
+	h.calls = append(h.calls, "Previous")
+	return h.err
+}
+
+func (h *testPlayerHandler) Seek(offset time.Duration) error {
+	h.calls = append(h.calls, "Seek")
+	h.seek = offset
+	return h.err
+}
+
+func newTestMediaPlayer(h MediaPlayerHandler) *MediaPlayer {
+	return &MediaPlayer{
+		handler: h,
+		path:    "/hci0/player/0",
+		props:   &MediaPlayerProperties{},
+	}
+}
+
+func TestTrackMetadata(t *testing.T) {
+
+	m := trackMetadata("/hci0/player/0/track1", media.Track{
+		Title:       "Song",
+		Artist:      "Band",
+		TrackNumber: 2,
+		Duration:    90 * time.Second,
+	})
+	assert.Equal(t, dbus.ObjectPath("/hci0/player/0/track1"), m["mpris:trackid"].Value())
+	assert.Equal(t, "Song", m["xesam:title"].Value())
+	assert.Equal(t, []string{"Band"}, m["xesam:artist"].Value())
+	assert.Equal(t, int32(2), m["xesam:trackNumber"].Value())
+	assert.Equal(t, int64(90000000), m["mpris:length"].Value())
+	_, ok := m["xesam:album"]
+	assert.False(t, ok)
+}
+
+func TestMediaPlayerState(t *testing.T) {
+
+	p := newTestMediaPlayer(&testPlayerHandler{})
+
+	p.SetTrack(media.Track{Title: "Song"})
+	assert.Equal(t, dbus.ObjectPath("/hci0/player/0/track1"), p.trackID())
+	assert.Equal(t, "Song", p.props.Metadata["xesam:title"].Value())
+
+	assert.Nil(t, p.SetStatus(media.StatusPaused))
+	assert.Equal(t, "Paused", p.props.PlaybackStatus)
+	assert.NotNil(t, p.SetStatus(media.StatusForwardSeek))
+
+	p.UpdatePosition(3 * time.Second)
+	assert.Equal(t, int64(3000000), p.props.Position)
+	assert.Equal(t, 3*time.Second, p.Position())
+
+	assert.Nil(t, p.SetRepeat(media.ModeAllTracks))
+	assert.Equal(t, "Playlist", p.props.LoopStatus)
+	assert.NotNil(t, p.SetRepeat(media.ModeGroup))
+
+	m, err := p.props.ToMap()
+	assert.Nil(t, err)
+	assert.Equal(t, "Paused", m["PlaybackStatus"])
+	_, ok := m["lock"]
+	assert.False(t, ok)
+}
+
+func TestMediaPlayerDispatch(t *testing.T) {
+
+	h := &testPlayerHandler{}
+	p := newTestMediaPlayer(h)
+	p.SetTrack(media.Track{Title: "Song"})
+
+	assert.Nil(t, p.PlayPause())
+	assert.Nil(t, p.SetStatus(media.StatusPlaying))
+	assert.Nil(t, p.PlayPause())
+	assert.Nil(t, p.Next())
+	assert.Equal(t, []string{"Play", "Pause", "Next"}, h.calls)
+
+	assert.Nil(t, p.SeekBy(-2000000))
+	assert.Equal(t, -2*time.Second, h.seek)
+
+	// SetPosition is relative to the current position
+	assert.Nil(t, p.SetStatus(media.StatusPaused))
+	p.UpdatePosition(10 * time.Second)
+	assert.Nil(t, p.SetPosition(p.trackID(), 4000000))
+	assert.Equal(t, -6*time.Second, h.seek)
+
+	// a stale track is ignored
+	h.calls = nil
+	assert.Nil(t, p.SetPosition("/hci0/player/0/track0", 0))
+	assert.Nil(t, h.calls)
+
+	h.err = errors.New("failed")
+	assert.NotNil(t, p.Stop())
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/agent"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrAgentRegistered is returned by Pair when the connection has already
+// registered an agent, BlueZ accepts a single agent per DBus sender. Pass
+// that agent as PairOptions.Agent instead.
+var ErrAgentRegistered = errors.New("An agent is already registered on the system bus connection")
+
+// PairResult is the outcome of Pair
+type PairResult int
+
+const (
+	// PairSuccess the device has been paired
+	PairSuccess PairResult = iota
+	// PairAlreadyPaired the device was already paired
+	PairAlreadyPaired
+	// PairAuthFailed the authentication failed, eg. passkey mismatch
+	PairAuthFailed
+	// PairRejected the pairing has been rejected, locally or by the device
+	PairRejected
+	// PairTimedOut the pairing did not complete in time
+	PairTimedOut
+	// PairCanceled the pairing has been cancelled
+	PairCanceled
+	// PairFailed any other failure, eg. the connection failed
+	PairFailed
+)
+
+func (r PairResult) String() string {
+	switch r {
+	case PairSuccess:
+		return "success"
+	case PairAlreadyPaired:
+		return "already paired"
+	case PairAuthFailed:
+		return "authentication failed"
+	case PairRejected:
+		return "rejected"
+	case PairTimedOut:
+		return "timed out"
+	case PairCanceled:
+		return "canceled"
+	}
+	return "failed"
+}
+
+// PairOptions configure the agent registered by Pair. Unset callbacks
+// reject the matching requests, except RequestConfirmation which is accepted
+// when no Confirm is given, as a NoInputNoOutput agent would.
+type PairOptions struct {
+	// Agent already registered by the caller, eg. by a service.App. Its
+	// handler is swapped during the pairing and the requests about other
+	// devices are passed to the previous handler. Capability and Timeout
+	// are ignored as the agent keeps its own.
+	Agent *agent.HandlerAgent
+	// Capability of the agent, derived from the callbacks if empty
+	Capability string
+	// Timeout of a single agent request, defaults to agent.DefaultAgentTimeout
+	Timeout time.Duration
+
+	PinCode        func(ctx context.Context, req agent.Request) (string, error)
+	Passkey        func(ctx context.Context, req agent.Request) (uint32, error)
+	Confirm        func(ctx context.Context, req agent.Request, passkey uint32) error
+	DisplayPinCode func(ctx context.Context, req agent.Request, pincode string) error
+	DisplayPasskey func(ctx context.Context, req agent.Request, passkey uint32, entered uint16) error
+}
+
+// capability derive the IO capability from the callbacks
+func (o PairOptions) capability() string {
+	if o.Capability != "" {
+		return o.Capability
+	}
+	input := o.PinCode != nil || o.Passkey != nil
+	display := o.DisplayPinCode != nil || o.DisplayPasskey != nil
+	switch {
+	case input && (display || o.Confirm != nil):
+		return agent.CapKeyboardDisplay
+	case input:
+		return agent.CapKeyboardOnly
+	case o.Confirm != nil:
+		return agent.CapDisplayYesNo
+	case display:
+		return agent.CapDisplayOnly
+	}
+	return agent.CapNoInputNoOutput
+}
+
+// pairHandler answer the requests about the device being paired, the
+// others are passed to fallback or rejected
+type pairHandler struct {
+	device   dbus.ObjectPath
+	options  PairOptions
+	fallback agent.Handler
+}
+
+func (h *pairHandler) check(req agent.Request) error {
+	if req.Device != h.device {
+		return agent.ErrRequestRejected
+	}
+	return nil
+}
+
+// other return the handler of the requests about other devices
+func (h *pairHandler) other(req agent.Request) (agent.Handler, bool) {
+	if req.Device == h.device || h.fallback == nil {
+		return nil, false
+	}
+	return h.fallback, true
+}
+
+func (h *pairHandler) RequestPinCode(ctx context.Context, req agent.Request) (string, error) {
+	if fallback, ok := h.other(req); ok {
+		return fallback.RequestPinCode(ctx, req)
+	}
+	if err := h.check(req); err != nil {
+		return "", err
+	}
+	if h.options.PinCode == nil {
+		return "", agent.ErrRequestRejected
+	}
+	return h.options.PinCode(ctx, req)
+}
+
+func (h *pairHandler) DisplayPinCode(ctx context.Context, req agent.Request, pincode string) error {
+	if fallback, ok := h.other(req); ok {
+		return fallback.DisplayPinCode(ctx, req, pincode)
+	}
+	if err := h.check(req); err != nil {
+		return err
+	}
+	if h.options.DisplayPinCode == nil {
+		return agent.ErrRequestRejected
+	}
+	return h.options.DisplayPinCode(ctx, req, pincode)
+}
+
+func (h *pairHandler) RequestPasskey(ctx context.Context, req agent.Request) (uint32, error) {
+	if fallback, ok := h.other(req); ok {
+		return fallback.RequestPasskey(ctx, req)
+	}
+	if err := h.check(req); err != nil {
+		return 0, err
+	}
+	if h.options.Passkey == nil {
+		return 0, agent.ErrRequestRejected
+	}
+	return h.options.Passkey(ctx, req)
+}
+
+func (h *pairHandler) DisplayPasskey(ctx context.Context, req agent.Request, passkey uint32, entered uint16) error {
+	if fallback, ok := h.other(req); ok {
+		return fallback.DisplayPasskey(ctx, req, passkey, entered)
+	}
+	if err := h.check(req); err != nil {
+		return err
+	}
+	if h.options.DisplayPasskey == nil {
+		return agent.ErrRequestRejected
+	}
+	return h.options.DisplayPasskey(ctx, req, passkey, entered)
+}
+
+func (h *pairHandler) RequestConfirmation(ctx context.Context, req agent.Request, passkey uint32) error {
+	if fallback, ok := h.other(req); ok {
+		return fallback.RequestConfirmation(ctx, req, passkey)
+	}
+	if err := h.check(req); err != nil {
+		return err
+	}
+	if h.options.Confirm == nil {
+		return nil
+	}
+	return h.options.Confirm(ctx, req, passkey)
+}
+
+func (h *pairHandler) RequestAuthorization(ctx context.Context, req agent.Request) error {
+	if fallback, ok := h.other(req); ok {
+		return fallback.RequestAuthorization(ctx, req)
+	}
+	return h.check(req)
+}
+
+func (h *pairHandler) AuthorizeService(ctx context.Context, req agent.Request, uuid string) error {
+	if fallback, ok := h.other(req); ok {
+		return fallback.AuthorizeService(ctx, req, uuid)
+	}
+	return h.check(req)
+}
+
+// pairAgentError map the error of the agent registration
+func pairAgentError(err error) error {
+	if bluez.ErrorName(err) == "org.bluez.Error.AlreadyExists" {
+		return fmt.Errorf("%w: %s", ErrAgentRegistered, err)
+	}
+	return err
+}
+
+// pairResult map the error returned by Device1.Pair
+func pairResult(err error) PairResult {
+
+	if err == nil {
+		return PairSuccess
+	}
+
+	switch bluez.ErrorName(err) {
+	case "org.bluez.Error.AlreadyExists":
+		return PairAlreadyPaired
+	case "org.bluez.Error.AuthenticationFailed":
+		return PairAuthFailed
+	case "org.bluez.Error.AuthenticationRejected":
+		return PairRejected
+	case "org.bluez.Error.AuthenticationCanceled":
+		return PairCanceled
+	case "org.bluez.Error.AuthenticationTimeout",
+		"org.freedesktop.DBus.Error.NoReply":
+		return PairTimedOut
+	}
+
+	return PairFailed
+}
+
+// Pair a device using a temporary agent, or options.Agent when set, then set
+// it as trusted. The pairing is cancelled when ctx is done. A nil error is
+// returned on PairSuccess and PairAlreadyPaired. ErrAgentRegistered is
+// returned when the connection already registered an agent and
+// options.Agent is not set.
+func Pair(ctx context.Context, dev *device.Device1, options PairOptions) (PairResult, error) {
+
+	paired, err := dev.GetPaired()
+	if err != nil {
+		return PairFailed, err
+	}
+	if paired {
+		return PairAlreadyPaired, nil
+	}
+
+	h := &pairHandler{
+		device:  dev.Path(),
+		options: options,
+	}
+
+	ag := options.Agent
+	if ag != nil {
+		h.fallback = ag.SetHandler(h)
+		defer ag.SetHandler(h.fallback)
+	} else {
+		conn, err := dbus.SystemBus()
+		if err != nil {
+			return PairFailed, err
+		}
+
+		ag = agent.NewHandlerAgent(h)
+		if options.Timeout > 0 {
+			ag.Timeout = options.Timeout
+		}
+
+		// BlueZ use the agent of the caller, no need to set it as default
+		err = agent.ExposeAgent(conn, ag, options.capability(), false)
+		if err != nil {
+			agent.UnexportAgent(conn, ag)
+			return PairFailed, pairAgentError(err)
+		}
+		defer func() {
+			err := agent.RemoveAgent(ag)
+			if err != nil {
+				log.Warnf("Pair: %s", err)
+			}
+			err = agent.UnexportAgent(conn, ag)
+			if err != nil {
+				log.Warnf("Pair: %s", err)
+			}
+		}()
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- dev.Pair()
+	}()
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		log.Debugf("Pair: cancel pairing %s", dev.Path())
+		cerr := dev.CancelPairing()
+		if cerr != nil {
+			log.Warnf("CancelPairing: %s", cerr)
+		}
+		// the agent may serve other devices
+		ag.CancelDevice(dev.Path())
+		// wait for Pair to return
+		err = <-errc
+		if err == nil {
+			break
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return PairTimedOut, ctx.Err()
+		}
+		return PairCanceled, ctx.Err()
+	}
+
+	result := pairResult(err)
+	switch result {
+	case PairSuccess:
+	case PairAlreadyPaired:
+		return result, nil
+	default:
+		return result, err
+	}
+
+	err = dev.SetTrusted(true)
+	if err != nil {
+		return PairSuccess, fmt.Errorf("SetTrusted: %s", err)
+	}
+
+	return PairSuccess, nil
+}
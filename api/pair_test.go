@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/agent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPairResult(t *testing.T) {
+	assert.Equal(t, PairSuccess, pairResult(nil))
+	assert.Equal(t, PairAlreadyPaired, pairResult(dbus.Error{Name: "org.bluez.Error.AlreadyExists"}))
+	assert.Equal(t, PairAuthFailed, pairResult(&dbus.Error{Name: "org.bluez.Error.AuthenticationFailed"}))
+	assert.Equal(t, PairRejected, pairResult(dbus.Error{Name: "org.bluez.Error.AuthenticationRejected"}))
+	assert.Equal(t, PairTimedOut, pairResult(dbus.Error{Name: "org.freedesktop.DBus.Error.NoReply"}))
+	assert.Equal(t, PairFailed, pairResult(errors.New("foo")))
+}
+
+func TestPairOptionsCapability(t *testing.T) {
+	passkey := func(ctx context.Context, req agent.Request) (uint32, error) { return 0, nil }
+	confirm := func(ctx context.Context, req agent.Request, passkey uint32) error { return nil }
+
+	assert.Equal(t, agent.CapNoInputNoOutput, PairOptions{}.capability())
+	assert.Equal(t, agent.CapKeyboardOnly, PairOptions{Passkey: passkey}.capability())
+	assert.Equal(t, agent.CapDisplayYesNo, PairOptions{Confirm: confirm}.capability())
+	assert.Equal(t, agent.CapKeyboardDisplay, PairOptions{Passkey: passkey, Confirm: confirm}.capability())
+	assert.Equal(t, agent.CapDisplayOnly, PairOptions{Capability: agent.CapDisplayOnly}.capability())
+}
+
+func TestPairHandler(t *testing.T) {
+	dev := dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55")
+	ag := agent.NewHandlerAgent(&pairHandler{
+		device: dev,
+		options: PairOptions{
+			Passkey: func(ctx context.Context, req agent.Request) (uint32, error) {
+				return 123456, nil
+			},
+		},
+	})
+
+	passkey, err := ag.RequestPasskey(dev)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(123456), passkey)
+
+	// no PinCode callback
+	_, err = ag.RequestPinCode(dev)
+	assert.NotNil(t, err)
+
+	// confirmed when no Confirm callback is set
+	assert.Nil(t, ag.RequestConfirmation(dev, 123456))
+
+	// other devices are rejected
+	assert.NotNil(t, ag.RequestConfirmation("/org/bluez/hci0/dev_66_77_88_99_AA_BB", 123456))
+}
+
+func TestPairHandlerFallback(t *testing.T) {
+	dev := dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55")
+	other := dbus.ObjectPath("/org/bluez/hci0/dev_66_77_88_99_AA_BB")
+
+	// the agent registered by the caller
+	ag := agent.NewHandlerAgent(&agent.PolicyHandler{
+		Addresses: []string{"66:77:88:99:AA:BB"},
+	})
+
+	h := &pairHandler{device: dev}
+	h.fallback = ag.SetHandler(h)
+
+	// the pairing is answered by Pair, the other devices by the caller
+	assert.Nil(t, ag.RequestConfirmation(dev, 123456))
+	assert.Nil(t, ag.RequestAuthorization(other))
+	assert.NotNil(t, ag.RequestAuthorization("/org/bluez/hci0/dev_00_00_00_00_00_00"))
+
+	// once restored, the caller handler answer every request
+	assert.Equal(t, h, ag.SetHandler(h.fallback))
+	assert.NotNil(t, ag.RequestConfirmation(dev, 123456))
+}
+
+func TestPairAgentError(t *testing.T) {
+	err := fmt.Errorf("RegisterAgent %s: %w", "/agent/handler0", dbus.Error{Name: "org.bluez.Error.AlreadyExists"})
+	assert.True(t, errors.Is(pairAgentError(err), ErrAgentRegistered))
+
+	err = fmt.Errorf("RegisterAgent %s: %w", "/agent/handler0", dbus.Error{Name: "org.bluez.Error.InvalidArguments"})
+	assert.False(t, errors.Is(pairAgentError(err), ErrAgentRegistered))
+	assert.Equal(t, err, pairAgentError(err))
+}
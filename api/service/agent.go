@@ -3,11 +3,19 @@ package service
 import "github.com/woongchantonylee/go-bluetooth/bluez/profile/agent"
 
 func (app *App) createAgent() (agent.Agent1Client, error) {
+	if app.Options.Agent != nil {
+		return app.Options.Agent, nil
+	}
 	a := agent.NewDefaultSimpleAgent()
 	return a, nil
 }
 
 // Expose app agent on DBus
 func (app *App) ExposeAgent(caps string, setAsDefaultAgent bool) error {
-	return agent.ExposeAgent(app.DBusConn(), app.agent, caps, setAsDefaultAgent)
+	err := agent.ExposeAgent(app.DBusConn(), app.agent, caps, setAsDefaultAgent)
+	if err != nil {
+		return err
+	}
+	app.agentExposed = true
+	return nil
 }
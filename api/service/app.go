@@ -16,11 +16,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// AppPath default app path
+// AppPath default app path, receives the adapter ID and an incremental index
 var AppPath = "/%s/apps/%d"
 
-var appCounter = 0
-
 // AppOptions contains App options
 type AppOptions struct {
 	AdapterID         string
@@ -28,6 +26,9 @@ type AppOptions struct {
 	AgentSetAsDefault bool
 	UUIDSuffix        string
 	UUID              string
+	// Agent used to pair, defaults to a SimpleAgent. Close unregisters it
+	// from BlueZ but the caller unexports it and releases its path.
+	Agent agent.Agent1Client
 }
 
 // NewApp initialize a new bluetooth service (app)
@@ -49,13 +50,7 @@ func NewApp(options AppOptions) (*App, error) {
 
 	app.adapterID = app.Options.AdapterID
 	app.services = make(map[dbus.ObjectPath]*Service)
-	app.path = dbus.ObjectPath(
-		fmt.Sprintf(
-			AppPath,
-			app.adapterID,
-			appCounter,
-		),
-	)
+	app.path = bluez.NewObjectPath(AppPath, app.adapterID)
 
 	app.advertisement = &advertising.LEAdvertisement1Properties{
 		Type: advertising.AdvertisementTypePeripheral,
@@ -65,9 +60,13 @@ func NewApp(options AppOptions) (*App, error) {
 		app.Options.AgentCaps = agent.CapKeyboardDisplay
 	}
 
-	appCounter++
+	err := app.init()
+	if err != nil {
+		bluez.ReleaseObjectPath(app.path)
+		return nil, err
+	}
 
-	return app, app.init()
+	return app, nil
 }
 
 // App wraps a bluetooth application exposing services
@@ -78,13 +77,17 @@ type App struct {
 	adapterID string
 	adapter   *adapter.Adapter1
 
-	agent agent.Agent1Client
+	agent        agent.Agent1Client
+	agentExposed bool
 
 	conn          *dbus.Conn
 	objectManager *api.DBusObjectManager
 	services      map[dbus.ObjectPath]*Service
 	advertisement *advertising.LEAdvertisement1Properties
 	gm            *gatt.GattManager1
+
+	authorizer           Authorizer
+	accessDeniedCallback AccessDeniedCallback
 }
 
 func (app *App) init() error {
@@ -212,14 +215,45 @@ func (app *App) Run() (err error) {
 	return err
 }
 
-// Close close the app
+// Close unregister the app and release every exported object
 func (app *App) Close() {
 
-	if app.agent != nil {
+	if app.gm != nil {
+		err1 := app.gm.UnregisterApplication(app.Path())
+		if err1 != nil {
+			log.Warnf("GattManager1.UnregisterApplication: %s", err1)
+		}
+		app.gm = nil
+	}
 
-		err := agent.RemoveAgent(app.agent)
+	for _, service := range app.GetServices() {
+		err := app.RemoveService(service)
 		if err != nil {
-			log.Warnf("RemoveAgent: %s", err)
+			log.Warnf("RemoveService %s: %s", service.Path(), err)
+		}
+	}
+
+	if app.conn != nil {
+		ifaces := []string{
+			bluez.ObjectManagerInterface,
+			bluez.Introspectable,
+		}
+		for _, iface := range ifaces {
+			err := app.conn.Export(nil, app.Path(), iface)
+			if err != nil {
+				log.Warnf("Unexport %s: %s", iface, err)
+			}
+		}
+	}
+
+	if app.agent != nil {
+
+		if app.agentExposed {
+			err := agent.RemoveAgent(app.agent)
+			if err != nil {
+				log.Warnf("RemoveAgent: %s", err)
+			}
+			app.agentExposed = false
 		}
 
 		// err =
@@ -227,12 +261,16 @@ func (app *App) Close() {
 		// if err != nil {
 		// 	log.Warnf("Agent1.Release: %s", err)
 		// }
-	}
 
-	if app.gm != nil {
-		err1 := app.gm.UnregisterApplication(app.Path())
-		if err1 != nil {
-			log.Warnf("GattManager1.UnregisterApplication: %s", err1)
+		// the caller agent path is not reserved by the app, the caller
+		// unexports it
+		if app.conn != nil && app.Options.Agent == nil {
+			err := agent.UnexportAgent(app.conn, app.agent)
+			if err != nil {
+				log.Warnf("UnexportAgent: %s", err)
+			}
 		}
 	}
+
+	bluez.ReleaseObjectPath(app.Path())
 }
@@ -5,17 +5,26 @@ import (
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/advertising"
 )
 
+// GetAdvertisement return the app advertisement properties
 func (app *App) GetAdvertisement() *advertising.LEAdvertisement1Properties {
 	return app.advertisement
 }
 
+// Advertise expose the app advertisement for timeout seconds. Duration is
+// left as set on GetAdvertisement(), as it controls the time slice shared
+// with other advertisements and not the advertisement lifetime.
 func (app *App) Advertise(timeout uint32) (func(), error) {
 
 	adv := app.GetAdvertisement()
 
 	adv.Timeout = uint16(timeout)
-	adv.Duration = uint16(timeout)
 
 	cancel, err := api.ExposeAdvertisement(app.adapterID, adv, timeout)
 	return cancel, err
 }
+
+// AdvertiseWith register the app advertisement on an AdvertisementManager,
+// allowing it to coexist with other advertisement sets
+func (app *App) AdvertiseWith(m *api.AdvertisementManager) (*api.Advertisement, error) {
+	return m.Add(app.GetAdvertisement())
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/gatt"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNotAuthorized is returned by the policies when a request is denied
+var ErrNotAuthorized = errors.New("Not authorized")
+
+// AccessOperation the type of operation requested by a remote device
+type AccessOperation string
+
+const (
+	AccessRead  AccessOperation = "read"
+	AccessWrite AccessOperation = "write"
+)
+
+// SecurityLevel the link security required by the GATT flags of an attribute
+type SecurityLevel int
+
+const (
+	// SecurityNone no encryption is required
+	SecurityNone SecurityLevel = iota
+	// SecurityEncrypted encrypt-read / encrypt-write
+	SecurityEncrypted
+	// SecurityAuthenticated encrypt-authenticated-read / encrypt-authenticated-write
+	SecurityAuthenticated
+	// SecuritySecure secure-read / secure-write (LE Secure Connections)
+	SecuritySecure
+)
+
+// AccessRequest describe a read or write received by a Char or Descr
+type AccessRequest struct {
+	Operation AccessOperation
+	// UUID of the characteristic or descriptor
+	UUID string
+	// Path of the characteristic or descriptor
+	Path dbus.ObjectPath
+	// Device is the remote device as passed by BlueZ in the device option
+	Device dbus.ObjectPath
+	// Flags of the characteristic or descriptor
+	Flags []string
+	// Options as received by ReadValue / WriteValue
+	Options map[string]interface{}
+
+	deviceProps *device.Device1Properties
+}
+
+// loadDeviceProperties retrieve the properties of a remote device
+var loadDeviceProperties = func(path dbus.ObjectPath) (*device.Device1Properties, error) {
+	dev, err := device.NewDevice1(path)
+	if err != nil {
+		return nil, err
+	}
+	return dev.Properties, nil
+}
+
+func newAccessRequest(op AccessOperation, uuid string, path dbus.ObjectPath, flags []string, options map[string]interface{}) *AccessRequest {
+	req := &AccessRequest{
+		Operation: op,
+		UUID:      uuid,
+		Path:      path,
+		Flags:     flags,
+		Options:   options,
+	}
+	req.Device = optionObjectPath(options, "device")
+	return req
+}
+
+func optionObjectPath(options map[string]interface{}, key string) dbus.ObjectPath {
+	v, ok := options[key]
+	if !ok {
+		return ""
+	}
+	if variant, ok := v.(dbus.Variant); ok {
+		v = variant.Value()
+	}
+	switch p := v.(type) {
+	case dbus.ObjectPath:
+		return p
+	case string:
+		return dbus.ObjectPath(p)
+	}
+	return ""
+}
+
+// DeviceProperties load the Device1 properties of the requesting device
+func (r *AccessRequest) DeviceProperties() (*device.Device1Properties, error) {
+	if r.deviceProps != nil {
+		return r.deviceProps, nil
+	}
+	if r.Device == "" {
+		return nil, errors.New("Request has no device option")
+	}
+	props, err := loadDeviceProperties(r.Device)
+	if err != nil {
+		return nil, err
+	}
+	r.deviceProps = props
+	return props, nil
+}
+
+// Security return the security level enforced by BlueZ for this
+// operation, based on the attribute flags
+func (r *AccessRequest) Security() SecurityLevel {
+	level := SecurityNone
+	suffix := "-" + string(r.Operation)
+	for _, flag := range r.Flags {
+		if !strings.HasSuffix(flag, suffix) {
+			continue
+		}
+		l := SecurityNone
+		switch strings.TrimSuffix(flag, suffix) {
+		case "encrypt":
+			l = SecurityEncrypted
+		case "encrypt-authenticated":
+			l = SecurityAuthenticated
+		case "secure":
+			l = SecuritySecure
+		}
+		if l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// Authorizer decide if a request from a remote device can be served.
+// Returning an error deny the request.
+type Authorizer interface {
+	Authorize(req *AccessRequest) error
+}
+
+// AuthorizerFunc adapt a function to the Authorizer interface
+type AuthorizerFunc func(req *AccessRequest) error
+
+// Authorize call fx(req)
+func (fx AuthorizerFunc) Authorize(req *AccessRequest) error {
+	return fx(req)
+}
+
+// AccessDeniedCallback is called when a request is refused by an Authorizer
+type AccessDeniedCallback func(req *AccessRequest, err error)
+
+// AllowAll accept every request
+func AllowAll() Authorizer {
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		return nil
+	})
+}
+
+// RequireAll accept a request only if every authorizer accept it
+func RequireAll(authorizers ...Authorizer) Authorizer {
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		for _, a := range authorizers {
+			err := a.Authorize(req)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RequireAny accept a request if at least one authorizer accept it
+func RequireAny(authorizers ...Authorizer) Authorizer {
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		err := ErrNotAuthorized
+		for _, a := range authorizers {
+			err = a.Authorize(req)
+			if err == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
+
+// ForOperation apply the authorizer only to the listed operations,
+// other operations are accepted
+func ForOperation(a Authorizer, ops ...AccessOperation) Authorizer {
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		for _, op := range ops {
+			if op == req.Operation {
+				return a.Authorize(req)
+			}
+		}
+		return nil
+	})
+}
+
+// RequireBonded accept requests from paired devices only
+func RequireBonded() Authorizer {
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		props, err := req.DeviceProperties()
+		if err != nil {
+			return err
+		}
+		if !props.Paired {
+			return fmt.Errorf("%w: device %s is not paired", ErrNotAuthorized, props.Address)
+		}
+		return nil
+	})
+}
+
+// RequireTrusted accept requests from trusted devices only
+func RequireTrusted() Authorizer {
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		props, err := req.DeviceProperties()
+		if err != nil {
+			return err
+		}
+		if !props.Trusted {
+			return fmt.Errorf("%w: device %s is not trusted", ErrNotAuthorized, props.Address)
+		}
+		return nil
+	})
+}
+
+// AllowAddresses accept requests from the listed addresses only
+func AllowAddresses(addresses ...string) Authorizer {
+	allowed := make(map[string]bool)
+	for _, address := range addresses {
+		allowed[strings.ToUpper(address)] = true
+	}
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		props, err := req.DeviceProperties()
+		if err != nil {
+			return err
+		}
+		if !allowed[strings.ToUpper(props.Address)] {
+			return fmt.Errorf("%w: address %s not allowed", ErrNotAuthorized, props.Address)
+		}
+		return nil
+	})
+}
+
+// RequireSecurityFlags accept requests only if the attribute flags make
+// BlueZ enforce at least the given link security. This is a configuration
+// check: the link state is not exposed over DBus, BlueZ refuses the
+// requests on an insecure link before they reach the app.
+func RequireSecurityFlags(level SecurityLevel) Authorizer {
+	return AuthorizerFunc(func(req *AccessRequest) error {
+		if req.Security() < level {
+			return fmt.Errorf("%w: %s on %s is not configured to require a secure link", ErrNotAuthorized, req.Operation, req.UUID)
+		}
+		return nil
+	})
+}
+
+// SecurityFlags return the characteristic flags that make BlueZ enforce a
+// security level on the given operation
+func SecurityFlags(op AccessOperation, level SecurityLevel) []string {
+	switch level {
+	case SecurityEncrypted:
+		if op == AccessRead {
+			return []string{gatt.FlagCharacteristicEncryptRead}
+		}
+		return []string{gatt.FlagCharacteristicEncryptWrite}
+	case SecurityAuthenticated:
+		if op == AccessRead {
+			return []string{gatt.FlagCharacteristicEncryptAuthenticatedRead}
+		}
+		return []string{gatt.FlagCharacteristicEncryptAuthenticatedWrite}
+	case SecuritySecure:
+		if op == AccessRead {
+			return []string{gatt.FlagCharacteristicSecureRead}
+		}
+		return []string{gatt.FlagCharacteristicSecureWrite}
+	}
+	return []string{}
+}
+
+// SetAuthorizer set the default authorizer for every Char and Descr of the app
+func (app *App) SetAuthorizer(a Authorizer) {
+	app.authorizer = a
+}
+
+// OnAccessDenied set the audit callback called when a request is denied
+func (app *App) OnAccessDenied(fx AccessDeniedCallback) {
+	app.accessDeniedCallback = fx
+}
+
+// authorize check a request against the attribute authorizer, falling back
+// to the app authorizer. A nil return value means the request is accepted.
+func (app *App) authorize(a Authorizer, req *AccessRequest) *dbus.Error {
+
+	if a == nil {
+		a = app.authorizer
+	}
+	if a == nil {
+		return nil
+	}
+
+	err := a.Authorize(req)
+	if err == nil {
+		return nil
+	}
+
+	log.Debugf("Denied %s on %s from %s: %s", req.Operation, req.Path, req.Device, err)
+
+	if app.accessDeniedCallback != nil {
+		app.accessDeniedCallback(req, err)
+	}
+
+	if dbusErr, ok := err.(*dbus.Error); ok {
+		return dbusErr
+	}
+
+	return &profile.ErrNotAuthorized
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/gatt"
+)
+
+func createAuthzTestChar(t *testing.T, flags []string) (*App, *Char) {
+
+	load := loadDeviceProperties
+	t.Cleanup(func() {
+		loadDeviceProperties = load
+	})
+	loadDeviceProperties = func(path dbus.ObjectPath) (*device.Device1Properties, error) {
+		return &device.Device1Properties{
+			Address: "00:11:22:33:44:55",
+			Paired:  path == "/org/bluez/hci0/dev_00_11_22_33_44_55",
+		}, nil
+	}
+
+	app := &App{}
+	c := &Char{
+		UUID:       "3344",
+		app:        app,
+		path:       "/hci0/apps/0/service1/char0",
+		Properties: NewGattCharacteristic1Properties("3344"),
+	}
+	c.Properties.Flags = flags
+	c.Properties.Value = []byte{1}
+
+	return app, c
+}
+
+func TestAuthorizeBonded(t *testing.T) {
+
+	app, c := createAuthzTestChar(t, []string{gatt.FlagCharacteristicRead})
+
+	denied := 0
+	app.OnAccessDenied(func(req *AccessRequest, err error) {
+		denied++
+	})
+	c.SetAuthorizer(RequireBonded())
+
+	_, err := c.ReadValue(map[string]interface{}{
+		"device": dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.ReadValue(map[string]interface{}{
+		"device": dbus.ObjectPath("/org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF"),
+	})
+	if err == nil {
+		t.Fatal("Expected request from unpaired device to be denied")
+	}
+	if denied != 1 {
+		t.Fatalf("Expected 1 denied request, got %d", denied)
+	}
+}
+
+func TestAuthorizeAddressAndSecurity(t *testing.T) {
+
+	app, c := createAuthzTestChar(t, []string{
+		gatt.FlagCharacteristicRead,
+		gatt.FlagCharacteristicEncryptRead,
+	})
+
+	app.SetAuthorizer(RequireAll(
+		AllowAddresses("00:11:22:33:44:55"),
+		RequireSecurityFlags(SecurityEncrypted),
+	))
+
+	options := map[string]interface{}{
+		"device": dbus.MakeVariant(dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55")),
+	}
+
+	_, err := c.ReadValue(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.SetAuthorizer(RequireSecurityFlags(SecurityAuthenticated))
+	_, err = c.ReadValue(options)
+	if err == nil {
+		t.Fatal("Expected read to be denied")
+	}
+
+	app.SetAuthorizer(AllowAddresses("AA:BB:CC:DD:EE:FF"))
+	_, err = c.ReadValue(options)
+	if err == nil {
+		t.Fatal("Expected read to be denied")
+	}
+}
+
+func TestNotAuthorizedWrapped(t *testing.T) {
+
+	_, c := createAuthzTestChar(t, []string{gatt.FlagCharacteristicRead})
+
+	req := newAccessRequest(AccessRead, c.UUID, c.Path(), c.Properties.Flags, map[string]interface{}{
+		"device": dbus.ObjectPath("/org/bluez/hci0/dev_AA_BB_CC_DD_EE_FF"),
+	})
+
+	authorizers := []Authorizer{
+		RequireBonded(),
+		AllowAddresses("AA:BB:CC:DD:EE:FF"),
+		RequireSecurityFlags(SecurityEncrypted),
+	}
+	for i, a := range authorizers {
+		err := a.Authorize(req)
+		if !errors.Is(err, ErrNotAuthorized) {
+			t.Fatalf("Authorizer %d: expected ErrNotAuthorized, got %v", i, err)
+		}
+	}
+}
+
+func TestAccessRequestSecurity(t *testing.T) {
+
+	req := newAccessRequest(AccessWrite, "", "", []string{
+		gatt.FlagCharacteristicEncryptRead,
+		gatt.FlagCharacteristicSecureRead,
+		gatt.FlagCharacteristicEncryptAuthenticatedWrite,
+	}, nil)
+
+	if req.Security() != SecurityAuthenticated {
+		t.Fatalf("Unexpected security level %d", req.Security())
+	}
+
+	flags := SecurityFlags(AccessRead, SecuritySecure)
+	if len(flags) != 1 || flags[0] != gatt.FlagCharacteristicSecureRead {
+		t.Fatalf("Unexpected flags %v", flags)
+	}
+}
@@ -1,8 +1,6 @@
 package service
 
 import (
-	"fmt"
-
 	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/api"
 	"github.com/woongchantonylee/go-bluetooth/bluez"
@@ -26,6 +24,7 @@ type Char struct {
 
 	readCallback  CharReadCallback
 	writeCallback CharWriteCallback
+	authorizer    Authorizer
 }
 
 func (s *Char) Path() dbus.ObjectPath {
@@ -83,6 +82,7 @@ func (s *Char) RemoveDescr(descr *Descr) error {
 	}
 
 	delete(s.descr, descr.Path())
+	bluez.ReleaseObjectPath(descr.Path())
 
 	return nil
 }
@@ -97,7 +97,8 @@ func (s *Char) Remove() error {
 	return api.RemoveDBusService(s)
 }
 
-// NewDescr Init new descr
+// NewDescr Init new descr, its path is reserved until RemoveDescr or a
+// failed AddDescr
 func (s *Char) NewDescr(uuid string) (*Descr, error) {
 
 	descr := new(Descr)
@@ -106,11 +107,10 @@ func (s *Char) NewDescr(uuid string) (*Descr, error) {
 	descr.app = s.App()
 	descr.char = s
 	descr.Properties = NewGattDescriptor1Properties(descr.UUID)
-	descr.path = dbus.ObjectPath(
-		fmt.Sprintf("%s/descriptor%d", s.Path(), len(s.GetDescr())),
-	)
+	descr.path = bluez.NewObjectPath("%s/descriptor%d", s.Path())
 	iprops, err := api.NewDBusProperties(s.App().DBusConn())
 	if err != nil {
+		bluez.ReleaseObjectPath(descr.path)
 		return nil, err
 	}
 	descr.iprops = iprops
@@ -122,14 +122,13 @@ func (s *Char) NewDescr(uuid string) (*Descr, error) {
 func (s *Char) AddDescr(descr *Descr) error {
 
 	err := api.ExposeDBusService(descr)
-	if err != nil {
-		return err
+	if err == nil {
+		err = s.DBusObjectManager().AddObject(descr.Path(), map[string]bluez.Properties{
+			descr.Interface(): descr.GetProperties(),
+		})
 	}
-
-	err = s.DBusObjectManager().AddObject(descr.Path(), map[string]bluez.Properties{
-		descr.Interface(): descr.GetProperties(),
-	})
 	if err != nil {
+		bluez.ReleaseObjectPath(descr.Path())
 		return err
 	}
 
@@ -152,3 +151,10 @@ func (s *Char) OnWrite(fx CharWriteCallback) *Char {
 	s.writeCallback = fx
 	return s
 }
+
+// SetAuthorizer set the authorizer checked on read and write requests,
+// overriding the one set on the App
+func (s *Char) SetAuthorizer(a Authorizer) *Char {
+	s.authorizer = a
+	return s
+}
@@ -53,6 +53,12 @@ func (s *Char) StopNotify() *dbus.Error {
 func (s *Char) ReadValue(options map[string]interface{}) ([]byte, *dbus.Error) {
 
 	log.Debug("Characteristic.ReadValue")
+
+	req := newAccessRequest(AccessRead, s.UUID, s.Path(), s.Properties.Flags, options)
+	if err := s.App().authorize(s.authorizer, req); err != nil {
+		return nil, err
+	}
+
 	if s.readCallback != nil {
 		b, err := s.readCallback(s, options)
 		if err != nil {
@@ -84,6 +90,11 @@ func (s *Char) WriteValue(value []byte, options map[string]interface{}) *dbus.Er
 
 	log.Trace("Characteristic.WriteValue")
 
+	req := newAccessRequest(AccessWrite, s.UUID, s.Path(), s.Properties.Flags, options)
+	if err := s.App().authorize(s.authorizer, req); err != nil {
+		return err
+	}
+
 	val := value
 	if s.writeCallback != nil {
 		log.Trace("Used write callback")
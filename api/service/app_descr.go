@@ -22,6 +22,7 @@ type Descr struct {
 
 	readCallback  DescrReadCallback
 	writeCallback DescrWriteCallback
+	authorizer    Authorizer
 }
 
 func (s *Descr) DBusProperties() *api.DBusProperties {
@@ -17,11 +17,23 @@ func (s *Descr) OnWrite(fx DescrWriteCallback) *Descr {
 	return s
 }
 
+// SetAuthorizer set the authorizer checked on read and write requests,
+// overriding the one set on the App
+func (s *Descr) SetAuthorizer(a Authorizer) *Descr {
+	s.authorizer = a
+	return s
+}
+
 //ReadValue read a value
 func (s *Descr) ReadValue(options map[string]interface{}) ([]byte, *dbus.Error) {
 
 	log.Trace("Descr.ReadValue")
 
+	req := newAccessRequest(AccessRead, s.UUID, s.Path(), s.Properties.Flags, options)
+	if err := s.App().authorize(s.authorizer, req); err != nil {
+		return nil, err
+	}
+
 	if s.readCallback != nil {
 		b, err := s.readCallback(s, options)
 		if err != nil {
@@ -38,6 +50,11 @@ func (s *Descr) WriteValue(value []byte, options map[string]interface{}) *dbus.E
 
 	log.Trace("Descr.WriteValue")
 
+	req := newAccessRequest(AccessWrite, s.UUID, s.Path(), s.Properties.Flags, options)
+	if err := s.App().authorize(s.authorizer, req); err != nil {
+		return err
+	}
+
 	val := value
 	if s.writeCallback != nil {
 		log.Trace("Used write callback")
@@ -1,8 +1,6 @@
 package service
 
 import (
-	"fmt"
-
 	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/api"
 	"github.com/woongchantonylee/go-bluetooth/bluez"
@@ -68,15 +66,14 @@ func (s *Service) GetChars() map[dbus.ObjectPath]*Char {
 	return s.chars
 }
 
-// NewChar Create a new characteristic
+// NewChar Create a new characteristic, its path is reserved until
+// RemoveChar or a failed AddChar
 func (s *Service) NewChar(uuid string) (*Char, error) {
 
 	char := new(Char)
 	char.UUID = s.App().GenerateUUID(uuid)
 
-	char.path = dbus.ObjectPath(
-		fmt.Sprintf("%s/char%d", s.Path(), len(s.GetChars())),
-	)
+	char.path = bluez.NewObjectPath("%s/char%d", s.Path())
 	char.app = s.App()
 	char.service = s
 	char.descr = make(map[dbus.ObjectPath]*Descr)
@@ -84,6 +81,7 @@ func (s *Service) NewChar(uuid string) (*Char, error) {
 
 	iprops, err := api.NewDBusProperties(s.App().DBusConn())
 	if err != nil {
+		bluez.ReleaseObjectPath(char.path)
 		return nil, err
 	}
 	char.iprops = iprops
@@ -96,14 +94,14 @@ func (s *Service) AddChar(char *Char) error {
 	s.chars[char.Path()] = char
 
 	err := api.ExposeDBusService(char)
-	if err != nil {
-		return err
+	if err == nil {
+		err = s.DBusObjectManager().AddObject(char.Path(), map[string]bluez.Properties{
+			char.Interface(): char.GetProperties(),
+		})
 	}
-
-	err = s.DBusObjectManager().AddObject(char.Path(), map[string]bluez.Properties{
-		char.Interface(): char.GetProperties(),
-	})
 	if err != nil {
+		delete(s.chars, char.Path())
+		bluez.ReleaseObjectPath(char.Path())
 		return err
 	}
 
@@ -127,17 +125,13 @@ func (s *Service) RemoveChar(char *Char) error {
 	}
 
 	// remove the char from the three
-	err := s.DBusObjectManager().RemoveObject(s.Path())
+	err := char.Remove()
 	if err != nil {
 		return err
 	}
 
-	// err = s.ExportTree()
-	// if err != nil {
-	// 	return err
-	// }
-
 	delete(s.chars, char.Path())
+	bluez.ReleaseObjectPath(char.Path())
 
-	return nil
+	return s.App().ExportTree()
 }
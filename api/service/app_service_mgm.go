@@ -1,9 +1,6 @@
 package service
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/api"
 	"github.com/woongchantonylee/go-bluetooth/bluez"
@@ -14,6 +11,8 @@ func (app *App) GetServices() map[dbus.ObjectPath]*Service {
 	return app.services
 }
 
+// NewService create a service, its path is reserved until RemoveService
+// or a failed AddService
 func (app *App) NewService(uuid string) (*Service, error) {
 
 	s := new(Service)
@@ -21,11 +20,12 @@ func (app *App) NewService(uuid string) (*Service, error) {
 
 	s.app = app
 	s.chars = make(map[dbus.ObjectPath]*Char)
-	s.path = dbus.ObjectPath(fmt.Sprintf("%s/service%s", app.Path(), strings.Replace(s.UUID, "-", "_", -1)[:8]))
+	s.path = bluez.NewObjectPath("%s/service%d", app.Path())
 	s.Properties = NewGattService1Properties(uuid)
 
 	iprops, err := api.NewDBusProperties(s.App().DBusConn())
 	if err != nil {
+		bluez.ReleaseObjectPath(s.path)
 		return nil, err
 	}
 	s.iprops = iprops
@@ -38,14 +38,14 @@ func (app *App) AddService(s *Service) error {
 	app.services[s.Path()] = s
 
 	err := s.Expose()
-	if err != nil {
-		return err
+	if err == nil {
+		err = app.DBusObjectManager().AddObject(s.Path(), map[string]bluez.Properties{
+			s.Interface(): s.GetProperties(),
+		})
 	}
-
-	err = app.DBusObjectManager().AddObject(s.Path(), map[string]bluez.Properties{
-		s.Interface(): s.GetProperties(),
-	})
 	if err != nil {
+		delete(app.services, s.Path())
+		bluez.ReleaseObjectPath(s.Path())
 		return err
 	}
 
@@ -73,6 +73,7 @@ func (app *App) RemoveService(service *Service) error {
 	}
 
 	delete(app.services, service.Path())
+	bluez.ReleaseObjectPath(service.Path())
 
 	return nil
 }
@@ -3,7 +3,9 @@ package service
 import (
 	"testing"
 
+	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/api"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -68,3 +70,66 @@ func TestApp(t *testing.T) {
 	a := createTestApp(t)
 	defer a.Close()
 }
+
+func TestMultipleApps(t *testing.T) {
+
+	a1 := createTestApp(t)
+	a2 := createTestApp(t)
+
+	if a1.Path() == a2.Path() {
+		t.Fatalf("Apps share the same path %s", a1.Path())
+	}
+
+	p1 := a1.Path()
+	a1.Close()
+	defer a2.Close()
+
+	a3, err := NewApp(AppOptions{
+		AdapterID: api.GetDefaultAdapterID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a3.Close()
+
+	if a3.Path() != p1 {
+		t.Fatalf("Expected released path %s to be reused, got %s", p1, a3.Path())
+	}
+}
+
+func TestServicePaths(t *testing.T) {
+
+	app := &App{
+		path:     "/hci9/apps/0",
+		services: make(map[dbus.ObjectPath]*Service),
+		Options: AppOptions{
+			UUID:       "1234",
+			UUIDSuffix: "-0000-1000-8000-00805F9B34FB",
+		},
+	}
+
+	// services sharing a UUID must not collide
+	s1, err := app.NewService("2233")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bluez.ReleaseObjectPath(s1.Path())
+	s2, err := app.NewService("2233")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bluez.ReleaseObjectPath(s2.Path())
+
+	if s1.Path() == s2.Path() {
+		t.Fatalf("Services share the same path %s", s1.Path())
+	}
+
+	c1, err := s1.NewChar("3344")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bluez.ReleaseObjectPath(c1.Path())
+	if c1.Path() != s1.Path()+"/char0" {
+		t.Fatalf("Unexpected char path %s", c1.Path())
+	}
+}
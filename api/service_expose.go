@@ -1,6 +1,8 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
 	"github.com/godbus/dbus/prop"
@@ -19,13 +21,60 @@ type ExposedDBusService interface {
 	// ExportTree() error
 }
 
+// MappedDBusService is implemented by the services exporting some methods
+// with a different DBus name, eg. when the name conflicts with a Go
+// convention. The mapping goes from the Go name to the DBus name.
+type MappedDBusService interface {
+	DBusMethodMapping() map[string]string
+}
+
+// RemoveDBusService remove the object from the object manager and
+// unexport the interfaces published by ExposeDBusService. The interfaces
+// are unexported even if the removal fails.
 func RemoveDBusService(s ExposedDBusService) error {
 
 	err := s.DBusObjectManager().RemoveObject(s.Path())
+	uerr := UnexportDBusService(s)
+
 	if err != nil {
+		if uerr != nil {
+			return fmt.Errorf("RemoveObject: %s, Unexport: %s", err, uerr)
+		}
 		return err
 	}
 
+	return uerr
+}
+
+// UnexportDBusService remove from the connection the interface,
+// properties and introspection exported by ExposeDBusService
+func UnexportDBusService(s ExposedDBusService) (err error) {
+
+	conn := s.DBusConn()
+
+	if conn == nil {
+		conn, err = dbus.SystemBus()
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Tracef("Unexport %s (%s)", s.Path(), s.Interface())
+
+	ifaces := []string{
+		s.Interface(),
+		bluez.PropertiesInterface,
+		bluez.Introspectable,
+	}
+	for _, iface := range ifaces {
+		err = conn.Export(nil, s.Path(), iface)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.DBusProperties().RemoveProperties(s.Interface())
+
 	return nil
 }
 
@@ -43,8 +92,13 @@ func ExposeDBusService(s ExposedDBusService) (err error) {
 		}
 	}
 
+	mapping := map[string]string{}
+	if m, ok := s.(MappedDBusService); ok {
+		mapping = m.DBusMethodMapping()
+	}
+
 	log.Tracef("Expose %s (%s)", s.Path(), s.Interface())
-	err = conn.Export(s, s.Path(), s.Interface())
+	err = conn.ExportWithMap(s, mapping, s.Path(), s.Interface())
 	if err != nil {
 		return err
 	}
@@ -57,6 +111,13 @@ func ExposeDBusService(s ExposedDBusService) (err error) {
 	log.Tracef("Expose Properties interface (%s)", s.Path())
 	s.DBusProperties().Expose(s.Path())
 
+	methods := introspect.Methods(s)
+	for i := range methods {
+		if name, ok := mapping[methods[i].Name]; ok {
+			methods[i].Name = name
+		}
+	}
+
 	node := &introspect.Node{
 		Interfaces: []introspect.Interface{
 			//Introspect
@@ -66,7 +127,7 @@ func ExposeDBusService(s ExposedDBusService) (err error) {
 			// Exposed service introspectable
 			{
 				Name:       s.Interface(),
-				Methods:    introspect.Methods(s),
+				Methods:    methods,
 				Properties: s.DBusProperties().Introspection(s.Interface()),
 			},
 		},
@@ -28,9 +28,10 @@ func (c *Client) isConnected() bool {
 //Disconnect from DBus
 func (c *Client) Disconnect() {
 
-	// do not disconnect SystemBus
-	// as it is a singleton from dbus package
-	if c.Config.Bus == SystemBus {
+	// do not disconnect SystemBus and SessionBus
+	// as they are singletons from dbus package
+	// shared by every client
+	if c.Config.Bus == SystemBus || c.Config.Bus == SessionBus {
 		return
 	}
 
@@ -154,6 +155,23 @@ func (c *Client) Unregister(path dbus.ObjectPath, iface string, signal chan *dbu
 	return nil
 }
 
+// DrainSignals drop the signals received while fn runs. The connection blocks
+// delivering to a full channel, fn could not unregister it otherwise.
+func DrainSignals(signals chan *dbus.Signal, fn func()) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-signals:
+			case <-done:
+				return
+			}
+		}
+	}()
+	fn()
+}
+
 // Emit
 func (c *Client) Emit(path dbus.ObjectPath, name string, values ...interface{}) error {
 	if !c.isConnected() {
@@ -0,0 +1,53 @@
+package bluez
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainSignals(t *testing.T) {
+
+	emitter, err := dbus.SessionBusPrivate()
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+	defer emitter.Close()
+	if err = emitter.Auth(nil); err == nil {
+		err = emitter.Hello()
+	}
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+
+	path := dbus.ObjectPath("/test/drain")
+	client := NewClient(&Config{Bus: SessionBus})
+	signals, err := client.Register(path, PropertiesInterface)
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+
+	// nobody reads the signals, the channel fills up
+	for i := 0; i < 10; i++ {
+		err = emitter.Emit(path, PropertiesChanged, "org.test", map[string]dbus.Variant{
+			"State": dbus.MakeVariant("idle"),
+		}, []string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error)
+	go DrainSignals(signals, func() {
+		done <- client.Unregister(path, PropertiesInterface, signals)
+	})
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Unregister blocked")
+	}
+}
@@ -21,6 +21,19 @@ type Properties interface {
 	Unlock()
 }
 
+// ErrorName return the name of a wrapped DBus error, empty if err is not one
+func ErrorName(err error) string {
+	var derr dbus.Error
+	if errors.As(err, &derr) {
+		return derr.Name
+	}
+	var perr *dbus.Error
+	if errors.As(err, &perr) {
+		return perr.Name
+	}
+	return ""
+}
+
 //BusType a type of DBus connection
 type BusType int
 
@@ -0,0 +1,19 @@
+package bluez
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorName(t *testing.T) {
+	name := "org.bluez.Error.DoesNotExist"
+	assert.Equal(t, name, ErrorName(dbus.Error{Name: name}))
+	assert.Equal(t, name, ErrorName(&dbus.Error{Name: name}))
+	assert.Equal(t, name, ErrorName(fmt.Errorf("RemoveDevice: %w", dbus.Error{Name: name})))
+	assert.Equal(t, "", ErrorName(errors.New(name)))
+	assert.Equal(t, "", ErrorName(nil))
+}
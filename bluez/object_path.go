@@ -0,0 +1,42 @@
+package bluez
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+)
+
+var objectPathsLock sync.Mutex
+var objectPaths = map[dbus.ObjectPath]bool{}
+
+// NewObjectPath reserve the first free object path generated by format.
+// format receives args followed by an incremental index, eg.
+// NewObjectPath("/%s/apps/%d", "hci0") returns /hci0/apps/0, then /hci0/apps/1 and so on.
+// Use ReleaseObjectPath to make the path available again.
+func NewObjectPath(format string, args ...interface{}) dbus.ObjectPath {
+	objectPathsLock.Lock()
+	defer objectPathsLock.Unlock()
+
+	for i := 0; ; i++ {
+		p := dbus.ObjectPath(fmt.Sprintf(format, append(args, i)...))
+		if !objectPaths[p] {
+			objectPaths[p] = true
+			return p
+		}
+	}
+}
+
+// ReleaseObjectPath mark a path reserved with NewObjectPath as free
+func ReleaseObjectPath(path dbus.ObjectPath) {
+	objectPathsLock.Lock()
+	defer objectPathsLock.Unlock()
+	delete(objectPaths, path)
+}
+
+// IsObjectPathReserved check if a path is in use
+func IsObjectPathReserved(path dbus.ObjectPath) bool {
+	objectPathsLock.Lock()
+	defer objectPathsLock.Unlock()
+	return objectPaths[path]
+}
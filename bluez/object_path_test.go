@@ -0,0 +1,34 @@
+package bluez
+
+import (
+	"testing"
+)
+
+func TestNewObjectPath(t *testing.T) {
+
+	p0 := NewObjectPath("/%s/test/%d", "hci9")
+	p1 := NewObjectPath("/%s/test/%d", "hci9")
+
+	if p0 != "/hci9/test/0" || p1 != "/hci9/test/1" {
+		t.Fatalf("Unexpected paths %s %s", p0, p1)
+	}
+
+	ReleaseObjectPath(p0)
+	if IsObjectPathReserved(p0) {
+		t.Fatalf("Path %s should be released", p0)
+	}
+
+	p2 := NewObjectPath("/%s/test/%d", "hci9")
+	if p2 != p0 {
+		t.Fatalf("Expected %s to be reused, got %s", p0, p2)
+	}
+
+	p3 := NewObjectPath("/%s/test/%d", "hci9")
+	if p3 == p1 {
+		t.Fatalf("Path %s assigned twice", p1)
+	}
+
+	ReleaseObjectPath(p1)
+	ReleaseObjectPath(p2)
+	ReleaseObjectPath(p3)
+}
@@ -54,3 +54,9 @@ func (a *LEAdvertisement1Properties) AddManifacturerData(code uint16, data []uin
 	}
 	a.ManufacturerData[code] = data
 }
+
+// DecodeData decode the Data property, see DecodeAdvertisingDataMap
+// to decode Device1.AdvertisingData
+func (a *LEAdvertisement1Properties) DecodeData() (*AdvertisingData, error) {
+	return DecodeAdvertisingDataMap(a.Data)
+}
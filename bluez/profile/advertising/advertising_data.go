@@ -0,0 +1,131 @@
+package advertising
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// AD types as assigned in the Bluetooth Generic Access Profile numbers
+const (
+	ADTypeFlags                    byte = 0x01
+	ADTypeIncomplete16BitUUIDs     byte = 0x02
+	ADTypeComplete16BitUUIDs       byte = 0x03
+	ADTypeIncomplete32BitUUIDs     byte = 0x04
+	ADTypeComplete32BitUUIDs       byte = 0x05
+	ADTypeIncomplete128BitUUIDs    byte = 0x06
+	ADTypeComplete128BitUUIDs      byte = 0x07
+	ADTypeShortName                byte = 0x08
+	ADTypeCompleteName             byte = 0x09
+	ADTypeTxPower                  byte = 0x0A
+	ADTypeSolicit16BitUUIDs        byte = 0x14
+	ADTypeSolicit128BitUUIDs       byte = 0x15
+	ADTypeServiceData16BitUUID     byte = 0x16
+	ADTypeAppearance               byte = 0x19
+	ADTypeSolicit32BitUUIDs        byte = 0x1F
+	ADTypeServiceData32BitUUID     byte = 0x20
+	ADTypeServiceData128BitUUID    byte = 0x21
+	ADTypeURI                      byte = 0x24
+	ADTypeLESupportedFeatures      byte = 0x27
+	ADTypeManufacturerSpecificData byte = 0xFF
+)
+
+// Advertising data length limits
+const (
+	// MaxLegacyAdvertisingDataLength is the size of legacy advertising data and scan response
+	MaxLegacyAdvertisingDataLength = 31
+	// MaxExtendedAdvertisingDataLength is the size of extended advertising data
+	// that can be set with a single HCI command
+	MaxExtendedAdvertisingDataLength = 251
+)
+
+// BaseUUIDSuffix is the suffix of the Bluetooth Base UUID 0000xxxx-0000-1000-8000-00805F9B34FB
+const BaseUUIDSuffix = "00001000800000805F9B34FB"
+
+// ADStructure is a single length-type-value element of advertising data
+type ADStructure struct {
+	Type byte
+	Data []byte
+}
+
+// Len return the encoded length, including the length and type bytes
+func (s ADStructure) Len() int {
+	return 2 + len(s.Data)
+}
+
+// Bytes return the encoded structure
+func (s ADStructure) Bytes() []byte {
+	b := make([]byte, 0, s.Len())
+	b = append(b, byte(len(s.Data)+1), s.Type)
+	return append(b, s.Data...)
+}
+
+// UUIDBytes return the shortest little-endian representation of an UUID.
+// 16 and 32 bits UUIDs are accepted as 4 or 8 hex characters or as 128 bits
+// UUIDs based on the Bluetooth Base UUID.
+func UUIDBytes(uuid string) ([]byte, error) {
+
+	s := strings.ToUpper(strings.Replace(uuid, "-", "", -1))
+
+	if len(s) == 32 && strings.HasSuffix(s, BaseUUIDSuffix) {
+		s = s[:8]
+		if strings.HasPrefix(s, "0000") {
+			s = s[4:]
+		}
+	}
+
+	switch len(s) {
+	case 4, 8, 32:
+	default:
+		return nil, fmt.Errorf("Invalid UUID %s", uuid)
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid UUID %s: %s", uuid, err)
+	}
+
+	reverseBytes(b)
+	return b, nil
+}
+
+// UUIDFromBytes format little-endian UUID bytes as found in advertising
+// data. 16 and 32 bits UUIDs are expanded with the Bluetooth Base UUID.
+func UUIDFromBytes(b []byte) (string, error) {
+
+	be := make([]byte, len(b))
+	copy(be, b)
+	reverseBytes(be)
+
+	var s string
+	switch len(be) {
+	case 2:
+		s = fmt.Sprintf("0000%04X%s", binary.BigEndian.Uint16(be), BaseUUIDSuffix)
+	case 4:
+		s = fmt.Sprintf("%08X%s", binary.BigEndian.Uint32(be), BaseUUIDSuffix)
+	case 16:
+		s = strings.ToUpper(hex.EncodeToString(be))
+	default:
+		return "", fmt.Errorf("Invalid UUID length %d", len(b))
+	}
+
+	return fmt.Sprintf("%s-%s-%s-%s-%s", s[0:8], s[8:12], s[12:16], s[16:20], s[20:32]), nil
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+// interfaceToBytes cast a value stored in a properties map
+func interfaceToBytes(v interface{}) ([]byte, bool) {
+	switch b := v.(type) {
+	case []byte:
+		return b, true
+	case interface{ Value() interface{} }:
+		return interfaceToBytes(b.Value())
+	}
+	return nil, false
+}
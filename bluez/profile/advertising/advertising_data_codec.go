@@ -0,0 +1,421 @@
+package advertising
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Flags bits of the ADTypeFlags structure
+const (
+	FlagLELimitedDiscoverable byte = 0x01
+	FlagLEGeneralDiscoverable byte = 0x02
+	FlagBREDRNotSupported     byte = 0x04
+	FlagLEBREDRController     byte = 0x08
+	FlagLEBREDRHost           byte = 0x10
+)
+
+// URI scheme code points used in the URI AD type
+const (
+	URISchemeNone  rune = 0x01
+	URISchemeHTTP  rune = 0x16
+	URISchemeHTTPS rune = 0x17
+)
+
+var uriSchemes = map[rune]string{
+	URISchemeHTTP:  "http:",
+	URISchemeHTTPS: "https:",
+}
+
+// MalformedDataError is returned when raw advertising data cannot be split in AD structures
+type MalformedDataError struct {
+	Offset int
+	Reason string
+}
+
+func (e *MalformedDataError) Error() string {
+	return fmt.Sprintf("Malformed advertising data at offset %d: %s", e.Offset, e.Reason)
+}
+
+// ParseADStructures split raw advertising data, as found in an HCI LE
+// advertising report or in a scan response, in AD structures.
+// A zero length terminates the data, as for padded legacy payloads.
+func ParseADStructures(b []byte) ([]ADStructure, error) {
+	list := []ADStructure{}
+	for i := 0; i < len(b); {
+		l := int(b[i])
+		if l == 0 {
+			break
+		}
+		if i+1+l > len(b) {
+			return list, &MalformedDataError{i, fmt.Sprintf("length %d exceeds the %d bytes left", l, len(b)-i-1)}
+		}
+		data := make([]byte, l-1)
+		copy(data, b[i+2:i+1+l])
+		list = append(list, ADStructure{Type: b[i+1], Data: data})
+		i += 1 + l
+	}
+	return list, nil
+}
+
+// EncodeADStructures concatenate encoded AD structures
+func EncodeADStructures(list []ADStructure) []byte {
+	return structuresBytes(list)
+}
+
+// AdvertisingData is the decoded content of advertising data or scan response
+type AdvertisingData struct {
+	// Flags value, valid if HasFlags is set
+	Flags    byte
+	HasFlags bool
+
+	// ServiceUUIDs as 128 bits UUIDs
+	ServiceUUIDs []string
+	// ServiceUUIDsIncomplete is set if the UUIDs list is marked as incomplete
+	ServiceUUIDsIncomplete bool
+
+	SolicitUUIDs []string
+
+	// LocalName the complete or short name
+	LocalName string
+	// ShortName is set if LocalName is a shortened name
+	ShortName bool
+
+	// TxPower in dBm, valid if HasTxPower is set
+	TxPower    int8
+	HasTxPower bool
+
+	// Appearance value, valid if HasAppearance is set
+	Appearance    uint16
+	HasAppearance bool
+
+	// ServiceData indexed by 128 bits UUID
+	ServiceData map[string][]byte
+
+	// ManufacturerData indexed by company identifier
+	ManufacturerData map[uint16][]byte
+
+	URI string
+
+	LESupportedFeatures []byte
+
+	// Unknown contains the structures not handled by the decoder
+	Unknown []ADStructure
+}
+
+// NewAdvertisingData create an empty AdvertisingData
+func NewAdvertisingData() *AdvertisingData {
+	return &AdvertisingData{
+		ServiceUUIDs:     []string{},
+		SolicitUUIDs:     []string{},
+		ServiceData:      make(map[string][]byte),
+		ManufacturerData: make(map[uint16][]byte),
+		Unknown:          []ADStructure{},
+	}
+}
+
+// DecodeAdvertisingData decode raw advertising data
+func DecodeAdvertisingData(b []byte) (*AdvertisingData, error) {
+	list, err := ParseADStructures(b)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeADStructures(list)
+}
+
+// DecodeAdvertisingDataMap decode a map of AD type to data, as found in
+// Device1.AdvertisingData and LEAdvertisement1.Data
+func DecodeAdvertisingDataMap(m map[byte]interface{}) (*AdvertisingData, error) {
+
+	adTypes := []int{}
+	for adType := range m {
+		adTypes = append(adTypes, int(adType))
+	}
+	sort.Ints(adTypes)
+
+	list := []ADStructure{}
+	for _, adType := range adTypes {
+		data, ok := interfaceToBytes(m[byte(adType)])
+		if !ok {
+			return nil, fmt.Errorf("AD type 0x%02X: value is not a byte array", adType)
+		}
+		list = append(list, ADStructure{byte(adType), data})
+	}
+
+	return DecodeADStructures(list)
+}
+
+func decodeUUIDList(data []byte, size int) ([]string, error) {
+	if len(data)%size != 0 {
+		return nil, fmt.Errorf("UUID list length %d is not a multiple of %d", len(data), size)
+	}
+	list := []string{}
+	for i := 0; i < len(data); i += size {
+		uuid, err := UUIDFromBytes(data[i : i+size])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, uuid)
+	}
+	return list, nil
+}
+
+func decodeServiceData(data []byte, size int) (string, []byte, error) {
+	if len(data) < size {
+		return "", nil, fmt.Errorf("service data length %d is shorter than the UUID", len(data))
+	}
+	uuid, err := UUIDFromBytes(data[:size])
+	if err != nil {
+		return "", nil, err
+	}
+	return uuid, data[size:], nil
+}
+
+// DecodeADStructures decode a list of AD structures
+func DecodeADStructures(list []ADStructure) (*AdvertisingData, error) {
+
+	d := NewAdvertisingData()
+
+	for _, s := range list {
+
+		var err error
+		var uuids []string
+
+		switch s.Type {
+		case ADTypeFlags:
+			if len(s.Data) < 1 {
+				err = fmt.Errorf("empty flags")
+				break
+			}
+			d.Flags = s.Data[0]
+			d.HasFlags = true
+		case ADTypeIncomplete16BitUUIDs, ADTypeComplete16BitUUIDs:
+			uuids, err = decodeUUIDList(s.Data, 2)
+			d.ServiceUUIDs = append(d.ServiceUUIDs, uuids...)
+			d.ServiceUUIDsIncomplete = d.ServiceUUIDsIncomplete || s.Type == ADTypeIncomplete16BitUUIDs
+		case ADTypeIncomplete32BitUUIDs, ADTypeComplete32BitUUIDs:
+			uuids, err = decodeUUIDList(s.Data, 4)
+			d.ServiceUUIDs = append(d.ServiceUUIDs, uuids...)
+			d.ServiceUUIDsIncomplete = d.ServiceUUIDsIncomplete || s.Type == ADTypeIncomplete32BitUUIDs
+		case ADTypeIncomplete128BitUUIDs, ADTypeComplete128BitUUIDs:
+			uuids, err = decodeUUIDList(s.Data, 16)
+			d.ServiceUUIDs = append(d.ServiceUUIDs, uuids...)
+			d.ServiceUUIDsIncomplete = d.ServiceUUIDsIncomplete || s.Type == ADTypeIncomplete128BitUUIDs
+		case ADTypeSolicit16BitUUIDs:
+			uuids, err = decodeUUIDList(s.Data, 2)
+			d.SolicitUUIDs = append(d.SolicitUUIDs, uuids...)
+		case ADTypeSolicit32BitUUIDs:
+			uuids, err = decodeUUIDList(s.Data, 4)
+			d.SolicitUUIDs = append(d.SolicitUUIDs, uuids...)
+		case ADTypeSolicit128BitUUIDs:
+			uuids, err = decodeUUIDList(s.Data, 16)
+			d.SolicitUUIDs = append(d.SolicitUUIDs, uuids...)
+		case ADTypeShortName, ADTypeCompleteName:
+			// prefer the complete name if both are present
+			if s.Type == ADTypeShortName && d.LocalName != "" && !d.ShortName {
+				break
+			}
+			d.LocalName = string(s.Data)
+			d.ShortName = s.Type == ADTypeShortName
+		case ADTypeTxPower:
+			if len(s.Data) != 1 {
+				err = fmt.Errorf("invalid length %d", len(s.Data))
+				break
+			}
+			d.TxPower = int8(s.Data[0])
+			d.HasTxPower = true
+		case ADTypeAppearance:
+			if len(s.Data) != 2 {
+				err = fmt.Errorf("invalid length %d", len(s.Data))
+				break
+			}
+			d.Appearance = binary.LittleEndian.Uint16(s.Data)
+			d.HasAppearance = true
+		case ADTypeServiceData16BitUUID, ADTypeServiceData32BitUUID, ADTypeServiceData128BitUUID:
+			size := map[byte]int{
+				ADTypeServiceData16BitUUID:  2,
+				ADTypeServiceData32BitUUID:  4,
+				ADTypeServiceData128BitUUID: 16,
+			}[s.Type]
+			uuid, data, err1 := decodeServiceData(s.Data, size)
+			err = err1
+			if err == nil {
+				d.ServiceData[uuid] = data
+			}
+		case ADTypeManufacturerSpecificData:
+			if len(s.Data) < 2 {
+				err = fmt.Errorf("missing company identifier")
+				break
+			}
+			d.ManufacturerData[binary.LittleEndian.Uint16(s.Data)] = s.Data[2:]
+		case ADTypeURI:
+			uri, ok := decodeURI(s.Data)
+			if !ok {
+				d.Unknown = append(d.Unknown, s)
+				break
+			}
+			d.URI = uri
+		case ADTypeLESupportedFeatures:
+			d.LESupportedFeatures = s.Data
+		default:
+			d.Unknown = append(d.Unknown, s)
+		}
+
+		if err != nil {
+			return d, fmt.Errorf("AD type 0x%02X: %s", s.Type, err)
+		}
+	}
+
+	return d, nil
+}
+
+func decodeURI(data []byte) (string, bool) {
+	scheme, size := utf8.DecodeRune(data)
+	if scheme == utf8.RuneError {
+		return "", false
+	}
+	if scheme == URISchemeNone {
+		return string(data[size:]), true
+	}
+	if prefix, ok := uriSchemes[scheme]; ok {
+		return prefix + string(data[size:]), true
+	}
+	return "", false
+}
+
+func encodeURI(uri string) []byte {
+	for scheme, prefix := range uriSchemes {
+		if len(uri) > len(prefix) && uri[:len(prefix)] == prefix {
+			b := make([]byte, utf8.RuneLen(scheme))
+			utf8.EncodeRune(b, scheme)
+			return append(b, uri[len(prefix):]...)
+		}
+	}
+	return append([]byte{byte(URISchemeNone)}, uri...)
+}
+
+// Structures encode the data as a list of AD structures
+func (d *AdvertisingData) Structures() ([]ADStructure, error) {
+
+	list := []ADStructure{}
+
+	if d.HasFlags {
+		list = append(list, ADStructure{ADTypeFlags, []byte{d.Flags}})
+	}
+
+	uuidTypes := [3]byte{ADTypeComplete16BitUUIDs, ADTypeComplete32BitUUIDs, ADTypeComplete128BitUUIDs}
+	if d.ServiceUUIDsIncomplete {
+		uuidTypes = [3]byte{ADTypeIncomplete16BitUUIDs, ADTypeIncomplete32BitUUIDs, ADTypeIncomplete128BitUUIDs}
+	}
+	uuids, err := uuidStructures("ServiceUUIDs", d.ServiceUUIDs, uuidTypes)
+	if err != nil {
+		return nil, err
+	}
+	list = append(list, uuids...)
+
+	solicit, err := uuidStructures("SolicitUUIDs", d.SolicitUUIDs, [3]byte{
+		ADTypeSolicit16BitUUIDs, ADTypeSolicit32BitUUIDs, ADTypeSolicit128BitUUIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	list = append(list, solicit...)
+
+	if d.LocalName != "" {
+		adType := ADTypeCompleteName
+		if d.ShortName {
+			adType = ADTypeShortName
+		}
+		list = append(list, ADStructure{adType, []byte(d.LocalName)})
+	}
+
+	if d.HasTxPower {
+		list = append(list, ADStructure{ADTypeTxPower, []byte{byte(d.TxPower)}})
+	}
+
+	if d.HasAppearance {
+		b := make([]byte, 2)
+		binary.LittleEndian.PutUint16(b, d.Appearance)
+		list = append(list, ADStructure{ADTypeAppearance, b})
+	}
+
+	serviceUUIDs := []string{}
+	for uuid := range d.ServiceData {
+		serviceUUIDs = append(serviceUUIDs, uuid)
+	}
+	sort.Strings(serviceUUIDs)
+	for _, uuid := range serviceUUIDs {
+		b, err := UUIDBytes(uuid)
+		if err != nil {
+			return nil, fmt.Errorf("ServiceData[%s]: %s", uuid, err)
+		}
+		adType := ADTypeServiceData128BitUUID
+		switch len(b) {
+		case 2:
+			adType = ADTypeServiceData16BitUUID
+		case 4:
+			adType = ADTypeServiceData32BitUUID
+		}
+		list = append(list, ADStructure{adType, append(b, d.ServiceData[uuid]...)})
+	}
+
+	manufacturerIDs := []int{}
+	for id := range d.ManufacturerData {
+		manufacturerIDs = append(manufacturerIDs, int(id))
+	}
+	sort.Ints(manufacturerIDs)
+	for _, id := range manufacturerIDs {
+		b := make([]byte, 2)
+		binary.LittleEndian.PutUint16(b, uint16(id))
+		list = append(list, ADStructure{ADTypeManufacturerSpecificData, append(b, d.ManufacturerData[uint16(id)]...)})
+	}
+
+	if d.URI != "" {
+		list = append(list, ADStructure{ADTypeURI, encodeURI(d.URI)})
+	}
+
+	if len(d.LESupportedFeatures) > 0 {
+		list = append(list, ADStructure{ADTypeLESupportedFeatures, d.LESupportedFeatures})
+	}
+
+	list = append(list, d.Unknown...)
+
+	for _, s := range list {
+		if len(s.Data) > 0xFE {
+			return nil, fmt.Errorf("AD type 0x%02X: data length %d exceeds 254 bytes", s.Type, len(s.Data))
+		}
+	}
+
+	return list, nil
+}
+
+// Encode the data in its raw representation
+func (d *AdvertisingData) Encode() ([]byte, error) {
+	list, err := d.Structures()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeADStructures(list), nil
+}
+
+// Payload place the encoded structures in an AdvertisingPayload, splitting
+// them between advertising data and scan response
+func (d *AdvertisingData) Payload(maxLength int) (*AdvertisingPayload, error) {
+	list, err := d.Structures()
+	if err != nil {
+		return nil, err
+	}
+	p := NewAdvertisingPayload(maxLength)
+	for _, s := range list {
+		if s.Type == ADTypeCompleteName || s.Type == ADTypeShortName {
+			err = p.SetName(d.LocalName)
+		} else {
+			err = p.Add(fmt.Sprintf("AD type 0x%02X", s.Type), s)
+		}
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
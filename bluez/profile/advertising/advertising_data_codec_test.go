@@ -0,0 +1,156 @@
+package advertising
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestDecodeAdvertisingData(t *testing.T) {
+
+	raw := []byte{
+		// flags
+		0x02, 0x01, 0x06,
+		// complete 16 bit uuids
+		0x05, 0x03, 0xAA, 0xFE, 0x0F, 0x18,
+		// service data FEAA
+		0x06, 0x16, 0xAA, 0xFE, 0x10, 0xEB, 0x03,
+		// tx power -12
+		0x02, 0x0A, 0xF4,
+		// appearance 0x0340
+		0x03, 0x19, 0x40, 0x03,
+		// short name
+		0x04, 0x08, 'g', 'o', 'b',
+		// manufacturer data
+		0x05, 0xFF, 0x4C, 0x00, 0x02, 0x15,
+		// padding
+		0x00, 0x00,
+	}
+
+	d, err := DecodeAdvertisingData(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.HasFlags || d.Flags != FlagLEGeneralDiscoverable|FlagBREDRNotSupported {
+		t.Fatalf("Unexpected flags 0x%02X", d.Flags)
+	}
+	if len(d.ServiceUUIDs) != 2 || d.ServiceUUIDs[1] != "0000180F-0000-1000-8000-00805F9B34FB" {
+		t.Fatalf("Unexpected UUIDs %v", d.ServiceUUIDs)
+	}
+	if !bytes.Equal(d.ServiceData["0000FEAA-0000-1000-8000-00805F9B34FB"], []byte{0x10, 0xEB, 0x03}) {
+		t.Fatalf("Unexpected service data %v", d.ServiceData)
+	}
+	if !d.HasTxPower || d.TxPower != -12 {
+		t.Fatalf("Unexpected tx power %d", d.TxPower)
+	}
+	if !d.HasAppearance || d.Appearance != 0x0340 {
+		t.Fatalf("Unexpected appearance 0x%04X", d.Appearance)
+	}
+	if d.LocalName != "gob" || !d.ShortName {
+		t.Fatalf("Unexpected name %s", d.LocalName)
+	}
+	if !bytes.Equal(d.ManufacturerData[0x004C], []byte{0x02, 0x15}) {
+		t.Fatalf("Unexpected manufacturer data %v", d.ManufacturerData)
+	}
+
+	encoded, err := d.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d2, err := DecodeAdvertisingData(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded2, err := d2.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, encoded2) {
+		t.Fatalf("Round trip mismatch %X %X", encoded, encoded2)
+	}
+}
+
+func TestDecodeURIAndFeatures(t *testing.T) {
+
+	d := NewAdvertisingData()
+	d.URI = "https://example.com"
+	d.LESupportedFeatures = []byte{0x01, 0x02}
+	d.Unknown = append(d.Unknown, ADStructure{0x2A, []byte{0x01}})
+
+	raw, err := d.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(raw, []byte{0x0F, ADTypeURI, 0x17, '/', '/'}) {
+		t.Fatalf("Unexpected URI encoding %X", raw)
+	}
+
+	d2, err := DecodeAdvertisingData(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d2.URI != d.URI {
+		t.Fatalf("Unexpected URI %s", d2.URI)
+	}
+	if !bytes.Equal(d2.LESupportedFeatures, d.LESupportedFeatures) {
+		t.Fatalf("Unexpected features %X", d2.LESupportedFeatures)
+	}
+	if len(d2.Unknown) != 1 || d2.Unknown[0].Type != 0x2A {
+		t.Fatalf("Unexpected unknown structures %v", d2.Unknown)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+
+	_, err := DecodeAdvertisingData([]byte{0x02, 0x01, 0x06, 0x05, 0xFF, 0x4C})
+	if _, ok := err.(*MalformedDataError); !ok {
+		t.Fatalf("Expected MalformedDataError, got %v", err)
+	}
+
+	_, err = DecodeAdvertisingData([]byte{0x04, 0x03, 0xAA, 0xFE, 0x0F})
+	if err == nil {
+		t.Fatal("Expected error on odd UUID list")
+	}
+
+	_, err = DecodeAdvertisingData([]byte{0x02, 0xFF, 0x4C})
+	if err == nil {
+		t.Fatal("Expected error on short manufacturer data")
+	}
+}
+
+func TestDecodeAdvertisingDataMap(t *testing.T) {
+
+	d, err := DecodeAdvertisingDataMap(map[byte]interface{}{
+		ADTypeFlags:        dbus.MakeVariant([]byte{0x06}),
+		ADTypeCompleteName: []byte("gobluetooth"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Flags != 0x06 || d.LocalName != "gobluetooth" {
+		t.Fatalf("Unexpected data %+v", d)
+	}
+}
+
+func TestAdvertisingDataPayload(t *testing.T) {
+
+	d := NewAdvertisingData()
+	d.HasFlags = true
+	d.Flags = FlagLEGeneralDiscoverable
+	d.ManufacturerData[0xFFFF] = make([]byte, 24)
+	d.LocalName = "gobluetooth"
+
+	p, err := d.Payload(MaxLegacyAdvertisingDataLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.AdvertisingDataLength() != 31 || p.ScanResponseLength() != 13 {
+		t.Fatalf("Unexpected split %d %d", p.AdvertisingDataLength(), p.ScanResponseLength())
+	}
+}
@@ -0,0 +1,380 @@
+package advertising
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// AdvertisingDataError is returned when an AD structure does not fit in the payload
+type AdvertisingDataError struct {
+	// Field describe the property that did not fit, eg. ServiceData[FEAA]
+	Field string
+	// Length is the encoded length of the field
+	Length int
+	// Available is the space left when the field was added
+	Available int
+	// MaxLength is the size of the advertising data
+	MaxLength int
+}
+
+func (e *AdvertisingDataError) Error() string {
+	return fmt.Sprintf(
+		"%s requires %d bytes but only %d of %d are available",
+		e.Field, e.Length, e.Available, e.MaxLength,
+	)
+}
+
+// NewAdvertisingPayload create an empty payload. maxLength is the size of
+// both advertising data and scan response, see MaxLegacyAdvertisingDataLength
+// and MaxExtendedAdvertisingDataLength
+func NewAdvertisingPayload(maxLength int) *AdvertisingPayload {
+	if maxLength <= 0 {
+		maxLength = MaxLegacyAdvertisingDataLength
+	}
+	return &AdvertisingPayload{
+		MaxLength:       maxLength,
+		AdvertisingData: []ADStructure{},
+		ScanResponse:    []ADStructure{},
+	}
+}
+
+// AdvertisingPayload collects the AD structures of the advertising data and
+// scan response. It models the split BlueZ applies to the registered
+// properties, it is not sent to BlueZ.
+type AdvertisingPayload struct {
+	MaxLength       int
+	AdvertisingData []ADStructure
+	ScanResponse    []ADStructure
+	// Name is the advertised local name, shortened if NameTruncated is set
+	Name string
+	// NameTruncated is set when the local name has been shortened to fit
+	NameTruncated bool
+}
+
+func structuresLength(list []ADStructure) int {
+	l := 0
+	for _, s := range list {
+		l += s.Len()
+	}
+	return l
+}
+
+func structuresBytes(list []ADStructure) []byte {
+	b := []byte{}
+	for _, s := range list {
+		b = append(b, s.Bytes()...)
+	}
+	return b
+}
+
+// AdvertisingDataLength return the encoded length of the advertising data
+func (p *AdvertisingPayload) AdvertisingDataLength() int {
+	return structuresLength(p.AdvertisingData)
+}
+
+// ScanResponseLength return the encoded length of the scan response
+func (p *AdvertisingPayload) ScanResponseLength() int {
+	return structuresLength(p.ScanResponse)
+}
+
+// AdvertisingDataBytes return the encoded advertising data
+func (p *AdvertisingPayload) AdvertisingDataBytes() []byte {
+	return structuresBytes(p.AdvertisingData)
+}
+
+// ScanResponseBytes return the encoded scan response
+func (p *AdvertisingPayload) ScanResponseBytes() []byte {
+	return structuresBytes(p.ScanResponse)
+}
+
+// AddToAdvertisingData add a structure to the advertising data only
+func (p *AdvertisingPayload) AddToAdvertisingData(field string, s ADStructure) error {
+	available := p.MaxLength - p.AdvertisingDataLength()
+	if s.Len() > available {
+		return &AdvertisingDataError{field, s.Len(), available, p.MaxLength}
+	}
+	p.AdvertisingData = append(p.AdvertisingData, s)
+	return nil
+}
+
+// AddToScanResponse add a structure to the scan response only
+func (p *AdvertisingPayload) AddToScanResponse(field string, s ADStructure) error {
+	available := p.MaxLength - p.ScanResponseLength()
+	if s.Len() > available {
+		return &AdvertisingDataError{field, s.Len(), available, p.MaxLength}
+	}
+	p.ScanResponse = append(p.ScanResponse, s)
+	return nil
+}
+
+// Add a structure to the advertising data, moving it to the scan response
+// if there is no space left
+func (p *AdvertisingPayload) Add(field string, s ADStructure) error {
+	err := p.AddToAdvertisingData(field, s)
+	if err == nil {
+		return nil
+	}
+	if p.AddToScanResponse(field, s) == nil {
+		return nil
+	}
+	return err
+}
+
+// SetName add the local name to the scan response, or to the advertising
+// data if it does not fit. When the complete name does not fit anywhere it
+// is truncated and advertised as short name.
+func (p *AdvertisingPayload) SetName(name string) error {
+
+	if name == "" {
+		return nil
+	}
+
+	complete := ADStructure{ADTypeCompleteName, []byte(name)}
+	p.Name = name
+	if p.AddToScanResponse("LocalName", complete) == nil {
+		return nil
+	}
+	if p.AddToAdvertisingData("LocalName", complete) == nil {
+		return nil
+	}
+
+	// truncate in the part with most space left
+	scanAvailable := p.MaxLength - p.ScanResponseLength() - 2
+	advAvailable := p.MaxLength - p.AdvertisingDataLength() - 2
+	available := scanAvailable
+	if advAvailable > available {
+		available = advAvailable
+	}
+	if available < 1 {
+		return &AdvertisingDataError{"LocalName", complete.Len(), available + 2, p.MaxLength}
+	}
+
+	short := ADStructure{ADTypeShortName, truncateName(name, available)}
+	p.Name = string(short.Data)
+	p.NameTruncated = true
+	if available == scanAvailable {
+		return p.AddToScanResponse("LocalName", short)
+	}
+	return p.AddToAdvertisingData("LocalName", short)
+}
+
+// truncateName cut a name to max bytes without splitting UTF-8 sequences
+func truncateName(name string, max int) []byte {
+	b := []byte(name)
+	if len(b) <= max {
+		return b
+	}
+	i := max
+	// move back to the beginning of a rune
+	for i > 0 && b[i]&0xC0 == 0x80 {
+		i--
+	}
+	return b[:i]
+}
+
+// uuidStructures group UUIDs by size in a list of AD structures
+func uuidStructures(field string, uuids []string, types [3]byte) ([]ADStructure, error) {
+	lists := [3][]byte{}
+	for _, uuid := range uuids {
+		b, err := UUIDBytes(uuid)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", field, err)
+		}
+		switch len(b) {
+		case 2:
+			lists[0] = append(lists[0], b...)
+		case 4:
+			lists[1] = append(lists[1], b...)
+		case 16:
+			lists[2] = append(lists[2], b...)
+		}
+	}
+	res := []ADStructure{}
+	for i, list := range lists {
+		if len(list) > 0 {
+			res = append(res, ADStructure{types[i], list})
+		}
+	}
+	return res, nil
+}
+
+// NewAdvertisingPayloadFromProperties build the payload BlueZ would generate
+// for an advertisement. All the fields are placed in the advertising data,
+// except the local name which BlueZ sends in the scan response. Flags
+// are accounted for peripheral and discoverable advertisements, as the
+// kernel adds them to the advertising data.
+func NewAdvertisingPayloadFromProperties(a *LEAdvertisement1Properties, maxLength int) (*AdvertisingPayload, error) {
+
+	p := NewAdvertisingPayload(maxLength)
+
+	if a.Type == AdvertisementTypePeripheral || a.Discoverable {
+		err := p.AddToAdvertisingData("Flags", ADStructure{ADTypeFlags, []byte{0x06}})
+		if err != nil {
+			return p, err
+		}
+	}
+
+	uuids, err := uuidStructures("ServiceUUIDs", a.ServiceUUIDs, [3]byte{
+		ADTypeComplete16BitUUIDs, ADTypeComplete32BitUUIDs, ADTypeComplete128BitUUIDs,
+	})
+	if err != nil {
+		return p, err
+	}
+	for _, s := range uuids {
+		err = p.AddToAdvertisingData("ServiceUUIDs", s)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	solicit, err := uuidStructures("SolicitUUIDs", a.SolicitUUIDs, [3]byte{
+		ADTypeSolicit16BitUUIDs, ADTypeSolicit32BitUUIDs, ADTypeSolicit128BitUUIDs,
+	})
+	if err != nil {
+		return p, err
+	}
+	for _, s := range solicit {
+		err = p.AddToAdvertisingData("SolicitUUIDs", s)
+		if err != nil {
+			return p, err
+		}
+	}
+
+	manufacturerIDs := []int{}
+	for id := range a.ManufacturerData {
+		manufacturerIDs = append(manufacturerIDs, int(id))
+	}
+	sort.Ints(manufacturerIDs)
+	for _, id := range manufacturerIDs {
+		field := fmt.Sprintf("ManufacturerData[0x%04X]", id)
+		data, ok := interfaceToBytes(a.ManufacturerData[uint16(id)])
+		if !ok {
+			return p, fmt.Errorf("%s: value is not a byte array", field)
+		}
+		b := make([]byte, 2, 2+len(data))
+		binary.LittleEndian.PutUint16(b, uint16(id))
+		err = p.AddToAdvertisingData(field, ADStructure{ADTypeManufacturerSpecificData, append(b, data...)})
+		if err != nil {
+			return p, err
+		}
+	}
+
+	serviceUUIDs := []string{}
+	for uuid := range a.ServiceData {
+		serviceUUIDs = append(serviceUUIDs, uuid)
+	}
+	sort.Strings(serviceUUIDs)
+	for _, uuid := range serviceUUIDs {
+		field := fmt.Sprintf("ServiceData[%s]", uuid)
+		data, ok := interfaceToBytes(a.ServiceData[uuid])
+		if !ok {
+			return p, fmt.Errorf("%s: value is not a byte array", field)
+		}
+		b, err := UUIDBytes(uuid)
+		if err != nil {
+			return p, fmt.Errorf("%s: %s", field, err)
+		}
+		adType := ADTypeServiceData128BitUUID
+		switch len(b) {
+		case 2:
+			adType = ADTypeServiceData16BitUUID
+		case 4:
+			adType = ADTypeServiceData32BitUUID
+		}
+		err = p.AddToAdvertisingData(field, ADStructure{adType, append(b, data...)})
+		if err != nil {
+			return p, err
+		}
+	}
+
+	if a.Appearance != 0 {
+		b := make([]byte, 2)
+		binary.LittleEndian.PutUint16(b, a.Appearance)
+		err = p.AddToAdvertisingData("Appearance", ADStructure{ADTypeAppearance, b})
+		if err != nil {
+			return p, err
+		}
+	}
+
+	dataTypes := []int{}
+	for adType := range a.Data {
+		dataTypes = append(dataTypes, int(adType))
+	}
+	sort.Ints(dataTypes)
+	for _, adType := range dataTypes {
+		field := fmt.Sprintf("Data[0x%02X]", adType)
+		data, ok := interfaceToBytes(a.Data[byte(adType)])
+		if !ok {
+			return p, fmt.Errorf("%s: value is not a byte array", field)
+		}
+		err = p.AddToAdvertisingData(field, ADStructure{byte(adType), data})
+		if err != nil {
+			return p, err
+		}
+	}
+
+	includeName := false
+	for _, include := range a.Includes {
+		switch include {
+		case SupportedIncludesTxPower:
+			err = p.AddToAdvertisingData("Includes[tx-power]", ADStructure{ADTypeTxPower, []byte{0}})
+		case SupportedIncludesAppearance:
+			err = p.AddToAdvertisingData("Includes[appearance]", ADStructure{ADTypeAppearance, []byte{0, 0}})
+		case SupportedIncludesLocalName:
+			includeName = true
+		}
+		if err != nil {
+			return p, err
+		}
+	}
+
+	if a.LocalName == "" && includeName {
+		// BlueZ advertise the adapter alias shortened to the space left,
+		// at least one character must fit
+		minimal := ADStructure{ADTypeShortName, []byte{0}}
+		if p.AddToScanResponse("Includes[local-name]", minimal) != nil {
+			err = p.AddToAdvertisingData("Includes[local-name]", minimal)
+			if err != nil {
+				return p, err
+			}
+		}
+	}
+
+	err = p.SetName(a.LocalName)
+	if err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+// MaxDataLength return the advertising data size available to the
+// advertisement, extended advertising is used when a SecondaryChannel is set
+func (a *LEAdvertisement1Properties) MaxDataLength() int {
+	if a.SecondaryChannel != "" {
+		return MaxExtendedAdvertisingDataLength
+	}
+	return MaxLegacyAdvertisingDataLength
+}
+
+// Validate check that the advertisement fits in the advertising data and
+// scan response. The returned error describe the first field not fitting.
+func (a *LEAdvertisement1Properties) Validate() error {
+	_, err := NewAdvertisingPayloadFromProperties(a, a.MaxDataLength())
+	return err
+}
+
+// Fit validate the advertisement as Validate does and shorten LocalName
+// in place when only a truncated name fits, so the registered name is the
+// one accounted for
+func (a *LEAdvertisement1Properties) Fit() error {
+	p, err := NewAdvertisingPayloadFromProperties(a, a.MaxDataLength())
+	if err != nil {
+		return err
+	}
+	if p.NameTruncated {
+		a.LocalName = p.Name
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package advertising
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUUIDBytes(t *testing.T) {
+
+	cases := map[string][]byte{
+		"FEAA":                                 {0xAA, 0xFE},
+		"0000feaa-0000-1000-8000-00805f9b34fb": {0xAA, 0xFE},
+		"1234ABCD":                             {0xCD, 0xAB, 0x34, 0x12},
+		"12345678-0000-1000-8000-00805F9B34FB": {0x78, 0x56, 0x34, 0x12},
+		"00112233-4455-6677-8899-AABBCCDDEEFF": {
+			0xFF, 0xEE, 0xDD, 0xCC, 0xBB, 0xAA, 0x99, 0x88,
+			0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00,
+		},
+	}
+
+	for uuid, expected := range cases {
+		b, err := UUIDBytes(uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expected) {
+			t.Fatalf("%s: expected %X got %X", uuid, expected, b)
+		}
+	}
+
+	uuid, err := UUIDFromBytes([]byte{0xAA, 0xFE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uuid != "0000FEAA-0000-1000-8000-00805F9B34FB" {
+		t.Fatalf("Unexpected UUID %s", uuid)
+	}
+
+	_, err = UUIDBytes("FEA")
+	if err == nil {
+		t.Fatal("Expected invalid UUID error")
+	}
+}
+
+func TestPayloadFromProperties(t *testing.T) {
+
+	props := &LEAdvertisement1Properties{
+		Type:      AdvertisementTypePeripheral,
+		LocalName: "a very long device name exceeding the scan response",
+	}
+	props.AddServiceUUID("FEAA")
+	props.AddServiceData("FEAA", []byte{0x10, 0x00, 0x01})
+	props.AddManifacturerData(0x004C, []byte{0x02, 0x15})
+
+	p, err := NewAdvertisingPayloadFromProperties(props, MaxLegacyAdvertisingDataLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flags 3 + uuid 4 + service data 7 + manufacturer data 6
+	if p.AdvertisingDataLength() != 20 {
+		t.Fatalf("Unexpected advertising data length %d", p.AdvertisingDataLength())
+	}
+
+	if !p.NameTruncated {
+		t.Fatal("Expected name to be truncated")
+	}
+	if p.ScanResponseLength() != MaxLegacyAdvertisingDataLength {
+		t.Fatalf("Unexpected scan response length %d", p.ScanResponseLength())
+	}
+	if p.ScanResponse[0].Type != ADTypeShortName {
+		t.Fatalf("Expected short name, got type 0x%02X", p.ScanResponse[0].Type)
+	}
+
+	expected := []byte{0x02, ADTypeFlags, 0x06, 0x03, ADTypeComplete16BitUUIDs, 0xAA, 0xFE}
+	if !bytes.HasPrefix(p.AdvertisingDataBytes(), expected) {
+		t.Fatalf("Unexpected advertising data %X", p.AdvertisingDataBytes())
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	props := &LEAdvertisement1Properties{
+		Type: AdvertisementTypeBroadcast,
+	}
+	props.AddManifacturerData(0xFFFF, make([]byte, 28))
+
+	err := props.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	adErr, ok := err.(*AdvertisingDataError)
+	if !ok {
+		t.Fatalf("Unexpected error type %T", err)
+	}
+	if adErr.Length != 32 || adErr.Available != 31 {
+		t.Fatalf("Unexpected error %s", adErr)
+	}
+
+	props.ManufacturerData[0xFFFF] = make([]byte, 25)
+	err = props.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	props.AddServiceData("FEAA", []byte{0x00})
+	err = props.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	props.SecondaryChannel = SecondaryChannel1M
+	err = props.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPayloadSplit(t *testing.T) {
+
+	p := NewAdvertisingPayload(MaxLegacyAdvertisingDataLength)
+
+	err := p.Add("first", ADStructure{ADTypeManufacturerSpecificData, make([]byte, 20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Add("second", ADStructure{ADTypeManufacturerSpecificData, make([]byte, 20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.AdvertisingData) != 1 || len(p.ScanResponse) != 1 {
+		t.Fatal("Expected the second structure in the scan response")
+	}
+
+	err = p.Add("third", ADStructure{ADTypeManufacturerSpecificData, make([]byte, 20)})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+}
+
+func TestFitLocalName(t *testing.T) {
+
+	props := &LEAdvertisement1Properties{
+		Type:      AdvertisementTypeBroadcast,
+		LocalName: "A very long local name for a small advertisement",
+	}
+
+	err := props.Fit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the scan response hold 29 bytes of name
+	if props.LocalName != "A very long local name for a " {
+		t.Fatalf("Unexpected name %q", props.LocalName)
+	}
+
+	// a fitting name is kept
+	err = props.Fit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props.LocalName != "A very long local name for a " {
+		t.Fatalf("Unexpected name %q", props.LocalName)
+	}
+}
+
+func TestIncludesLocalName(t *testing.T) {
+
+	props := &LEAdvertisement1Properties{
+		Type:     AdvertisementTypeBroadcast,
+		Includes: []string{SupportedIncludesLocalName},
+	}
+
+	p, err := NewAdvertisingPayloadFromProperties(props, MaxLegacyAdvertisingDataLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ScanResponseLength() != 3 {
+		t.Fatalf("Expected the name to be accounted, got %d bytes", p.ScanResponseLength())
+	}
+}
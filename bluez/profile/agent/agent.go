@@ -2,12 +2,12 @@ package agent
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
 	"github.com/woongchantonylee/go-bluetooth/bluez"
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/adapter"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -34,35 +34,32 @@ type Agent1Client interface {
 	Interface() string
 }
 
-// SetTrusted lookup for a device by object path and set it to trusted
+// SetTrusted set the device at the object path to trusted
 func SetTrusted(adapterID string, devicePath dbus.ObjectPath) error {
 
 	log.Tracef("Trust device %s on %s", devicePath, adapterID)
 
-	a, err := adapter.GetAdapter(adapterID)
+	devAdapterID, err := adapter.ParseAdapterID(devicePath)
 	if err != nil {
 		return err
 	}
+	if devAdapterID != adapterID {
+		return fmt.Errorf("Cannot trust device %s, not found on %s", devicePath, adapterID)
+	}
 
-	devices, err := a.GetDevices()
+	dev, err := device.NewDevice1(devicePath)
 	if err != nil {
 		return err
 	}
+	defer dev.Close()
 
-	path := string(devicePath)
-	for _, dev := range devices {
-		if strings.Contains(string(dev.Path()), path) {
-			log.Tracef("SetTrusted: Trust device at %s", path)
-			err := dev.SetTrusted(true)
-			if err != nil {
-				return fmt.Errorf("SetTrusted error: %s", err)
-			}
-			log.Tracef("SetTrusted: OK")
-			return nil
-		}
+	err = dev.SetTrusted(true)
+	if err != nil {
+		return fmt.Errorf("SetTrusted error: %s", err)
 	}
+	log.Tracef("SetTrusted: OK")
 
-	return fmt.Errorf("Cannot trust device %s, not found", path)
+	return nil
 }
 
 // RemoveAgent remove an Agent1 implementation from AgentManager1
@@ -100,7 +97,7 @@ func ExposeAgent(conn *dbus.Conn, ag Agent1Client, caps string, setAsDefaultAgen
 	// Register the exported interface as application agent via AgenManager API
 	err = am.RegisterAgent(ag.Path(), caps)
 	if err != nil {
-		return fmt.Errorf("RegisterAgent %s: %s", ag.Path(), err)
+		return fmt.Errorf("RegisterAgent %s: %w", ag.Path(), err)
 	}
 
 	if setAsDefaultAgent {
@@ -114,6 +111,21 @@ func ExposeAgent(conn *dbus.Conn, ag Agent1Client, caps string, setAsDefaultAgen
 	return nil
 }
 
+// UnexportAgent remove an Agent1 implementation from DBus and release its object path
+func UnexportAgent(conn *dbus.Conn, ag Agent1Client) error {
+
+	log.Tracef("Unexport Agent1 at %s", ag.Path())
+
+	defer bluez.ReleaseObjectPath(ag.Path())
+
+	err := conn.Export(nil, ag.Path(), ag.Interface())
+	if err != nil {
+		return err
+	}
+
+	return conn.Export(nil, ag.Path(), bluez.Introspectable)
+}
+
 //ExportAgent exports the xml of a go agent to dbus
 func exportAgent(conn *dbus.Conn, ag Agent1Client) error {
 
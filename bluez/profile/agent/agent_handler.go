@@ -0,0 +1,288 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/adapter"
+	log "github.com/sirupsen/logrus"
+)
+
+const HandlerAgentBasePath = "/agent/handler%d"
+
+// DefaultAgentTimeout is the time given to a Handler to answer a request
+const DefaultAgentTimeout = 30 * time.Second
+
+// ErrRequestRejected is returned by a Handler to reject a request
+var ErrRequestRejected = errors.New("Request rejected")
+
+// ErrCanceled map to org.bluez.Error.Canceled
+var ErrCanceled = dbus.Error{
+	Name: "org.bluez.Error.Canceled",
+	Body: []interface{}{"Canceled"},
+}
+
+// Request describe the device an agent request is about
+type Request struct {
+	Method    string
+	Device    dbus.ObjectPath
+	AdapterID string
+	Address   string
+}
+
+// NewRequest create a Request parsing the adapter and address from the device path
+func NewRequest(method string, device dbus.ObjectPath) Request {
+	req := Request{
+		Method: method,
+		Device: device,
+	}
+	req.AdapterID, _ = adapter.ParseAdapterID(device)
+	spath := string(device)
+	if i := strings.LastIndex(spath, "/dev_"); i > -1 {
+		req.Address = strings.Replace(spath[i+len("/dev_"):], "_", ":", -1)
+	}
+	return req
+}
+
+// Handler take the decisions of a HandlerAgent, eg. prompting the user or
+// applying a policy. The context is done when the request times out or is
+// cancelled by BlueZ. Returning an error rejects the request.
+type Handler interface {
+	RequestPinCode(ctx context.Context, req Request) (string, error)
+	DisplayPinCode(ctx context.Context, req Request, pincode string) error
+	RequestPasskey(ctx context.Context, req Request) (uint32, error)
+	DisplayPasskey(ctx context.Context, req Request, passkey uint32, entered uint16) error
+	RequestConfirmation(ctx context.Context, req Request, passkey uint32) error
+	RequestAuthorization(ctx context.Context, req Request) error
+	AuthorizeService(ctx context.Context, req Request, uuid string) error
+}
+
+// NewHandlerAgent create an agent delegating the requests to handler
+func NewHandlerAgent(handler Handler) *HandlerAgent {
+	return &HandlerAgent{
+		path:    bluez.NewObjectPath(HandlerAgentBasePath),
+		handler: handler,
+		Timeout: DefaultAgentTimeout,
+		pending: make(map[uint64]pendingRequest),
+	}
+}
+
+// HandlerAgent implement Agent1Client delegating every request to a Handler
+type HandlerAgent struct {
+	path    dbus.ObjectPath
+	handler Handler
+	// Timeout of a request, the request is cancelled once elapsed
+	Timeout time.Duration
+	// Trust the device once paired
+	Trust bool
+
+	lock    sync.Mutex
+	pending map[uint64]pendingRequest
+	nextID  uint64
+}
+
+type pendingRequest struct {
+	device dbus.ObjectPath
+	cancel context.CancelFunc
+}
+
+func (self *HandlerAgent) Path() dbus.ObjectPath {
+	return self.path
+}
+
+func (self *HandlerAgent) Interface() string {
+	return Agent1Interface
+}
+
+// SetHandler replace the handler of the agent and return the previous one,
+// the pending requests keep the handler they started with
+func (self *HandlerAgent) SetHandler(handler Handler) Handler {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	prev := self.handler
+	self.handler = handler
+	return prev
+}
+
+func (self *HandlerAgent) getHandler() Handler {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.handler
+}
+
+// call run fx with a context done on timeout or Cancel
+func (self *HandlerAgent) call(req Request, fx func(ctx context.Context) error) *dbus.Error {
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if self.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), self.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	self.lock.Lock()
+	id := self.nextID
+	self.nextID++
+	self.pending[id] = pendingRequest{req.Device, cancel}
+	self.lock.Unlock()
+
+	defer func() {
+		self.lock.Lock()
+		delete(self.pending, id)
+		self.lock.Unlock()
+		cancel()
+	}()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fx(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err == nil && ctx.Err() != nil {
+		// answered too late
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		log.Debugf("HandlerAgent: %s %s: %s", req.Method, req.Device, err)
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return &ErrCanceled
+		}
+		return &profile.ErrRejected
+	}
+
+	return nil
+}
+
+func (self *HandlerAgent) trust(req Request) error {
+	if !self.Trust {
+		return nil
+	}
+	if req.AdapterID == "" {
+		return fmt.Errorf("Failed to parse adapter from %s", req.Device)
+	}
+	return SetTrusted(req.AdapterID, req.Device)
+}
+
+// Release cancel the pending requests
+func (self *HandlerAgent) Release() *dbus.Error {
+	self.cancelPending()
+	return nil
+}
+
+// Cancel the pending requests
+func (self *HandlerAgent) Cancel() *dbus.Error {
+	log.Debugf("HandlerAgent: Cancel")
+	self.cancelPending()
+	return nil
+}
+
+// CancelDevice cancel the pending requests of device only
+func (self *HandlerAgent) CancelDevice(device dbus.ObjectPath) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, pending := range self.pending {
+		if pending.device == device {
+			pending.cancel()
+		}
+	}
+}
+
+func (self *HandlerAgent) cancelPending() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, pending := range self.pending {
+		pending.cancel()
+	}
+}
+
+func (self *HandlerAgent) RequestPinCode(device dbus.ObjectPath) (string, *dbus.Error) {
+	req := NewRequest("RequestPinCode", device)
+	var pincode string
+	err := self.call(req, func(ctx context.Context) error {
+		var err error
+		pincode, err = self.getHandler().RequestPinCode(ctx, req)
+		if err != nil {
+			return err
+		}
+		return self.trust(req)
+	})
+	if err != nil {
+		return "", err
+	}
+	return pincode, nil
+}
+
+func (self *HandlerAgent) DisplayPinCode(device dbus.ObjectPath, pincode string) *dbus.Error {
+	req := NewRequest("DisplayPinCode", device)
+	return self.call(req, func(ctx context.Context) error {
+		return self.getHandler().DisplayPinCode(ctx, req, pincode)
+	})
+}
+
+func (self *HandlerAgent) RequestPasskey(device dbus.ObjectPath) (uint32, *dbus.Error) {
+	req := NewRequest("RequestPasskey", device)
+	var passkey uint32
+	err := self.call(req, func(ctx context.Context) error {
+		var err error
+		passkey, err = self.getHandler().RequestPasskey(ctx, req)
+		if err != nil {
+			return err
+		}
+		if passkey > 999999 {
+			return fmt.Errorf("Passkey %d out of range", passkey)
+		}
+		return self.trust(req)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return passkey, nil
+}
+
+func (self *HandlerAgent) DisplayPasskey(device dbus.ObjectPath, passkey uint32, entered uint16) *dbus.Error {
+	req := NewRequest("DisplayPasskey", device)
+	return self.call(req, func(ctx context.Context) error {
+		return self.getHandler().DisplayPasskey(ctx, req, passkey, entered)
+	})
+}
+
+func (self *HandlerAgent) RequestConfirmation(device dbus.ObjectPath, passkey uint32) *dbus.Error {
+	req := NewRequest("RequestConfirmation", device)
+	return self.call(req, func(ctx context.Context) error {
+		err := self.getHandler().RequestConfirmation(ctx, req, passkey)
+		if err != nil {
+			return err
+		}
+		return self.trust(req)
+	})
+}
+
+func (self *HandlerAgent) RequestAuthorization(device dbus.ObjectPath) *dbus.Error {
+	req := NewRequest("RequestAuthorization", device)
+	return self.call(req, func(ctx context.Context) error {
+		return self.getHandler().RequestAuthorization(ctx, req)
+	})
+}
+
+func (self *HandlerAgent) AuthorizeService(device dbus.ObjectPath, uuid string) *dbus.Error {
+	req := NewRequest("AuthorizeService", device)
+	return self.call(req, func(ctx context.Context) error {
+		return self.getHandler().AuthorizeService(ctx, req, uuid)
+	})
+}
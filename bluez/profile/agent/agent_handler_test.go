@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDevice = dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55")
+
+// blockingHandler wait for the request to be done
+type blockingHandler struct {
+	PolicyHandler
+}
+
+func (h *blockingHandler) RequestConfirmation(ctx context.Context, req Request, passkey uint32) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestNewRequest(t *testing.T) {
+	req := NewRequest("RequestPinCode", testDevice)
+	assert.Equal(t, "hci0", req.AdapterID)
+	assert.Equal(t, "00:11:22:33:44:55", req.Address)
+	assert.Equal(t, testDevice, req.Device)
+}
+
+func TestHandlerAgentTimeout(t *testing.T) {
+	a := NewHandlerAgent(&blockingHandler{})
+	a.Timeout = 10 * time.Millisecond
+	err := a.RequestConfirmation(testDevice, 123456)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrCanceled.Name, err.Name)
+	}
+}
+
+func TestHandlerAgentCancel(t *testing.T) {
+	a := NewHandlerAgent(&blockingHandler{})
+	a.Timeout = 0
+
+	errc := make(chan *dbus.Error)
+	go func() {
+		errc <- a.RequestConfirmation(testDevice, 123456)
+	}()
+
+	// wait for the request to be pending
+	for {
+		a.lock.Lock()
+		n := len(a.pending)
+		a.lock.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	a.Cancel()
+
+	select {
+	case err := <-errc:
+		if assert.NotNil(t, err) {
+			assert.Equal(t, ErrCanceled.Name, err.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request not cancelled")
+	}
+}
+
+func TestHandlerAgentCancelDevice(t *testing.T) {
+	a := NewHandlerAgent(&blockingHandler{})
+	a.Timeout = 0
+
+	other := dbus.ObjectPath("/org/bluez/hci0/dev_66_77_88_99_AA_BB")
+	errc := make(chan *dbus.Error)
+	othererrc := make(chan *dbus.Error)
+	go func() {
+		errc <- a.RequestConfirmation(testDevice, 123456)
+	}()
+	go func() {
+		othererrc <- a.RequestConfirmation(other, 654321)
+	}()
+
+	// wait for both requests to be pending
+	for {
+		a.lock.Lock()
+		n := len(a.pending)
+		a.lock.Unlock()
+		if n > 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	a.CancelDevice(testDevice)
+
+	select {
+	case err := <-errc:
+		if assert.NotNil(t, err) {
+			assert.Equal(t, ErrCanceled.Name, err.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request not cancelled")
+	}
+
+	// the other device request is still pending
+	select {
+	case <-othererrc:
+		t.Fatal("other request cancelled")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	a.Cancel()
+	<-othererrc
+}
+
+func TestHandlerAgentPolicy(t *testing.T) {
+	a := NewHandlerAgent(&PolicyHandler{
+		Addresses: []string{"00:11:22:33:44:55"},
+		Services:  []string{"110b"},
+		PinCode:   "0000",
+	})
+
+	pincode, err := a.RequestPinCode(testDevice)
+	assert.Nil(t, err)
+	assert.Equal(t, "0000", pincode)
+
+	assert.Nil(t, a.AuthorizeService(testDevice, "0000110b-0000-1000-8000-00805f9b34fb"))
+
+	err = a.AuthorizeService(testDevice, "0000110a-0000-1000-8000-00805f9b34fb")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, profile.ErrRejected.Name, err.Name)
+	}
+
+	err = a.RequestAuthorization("/org/bluez/hci0/dev_66_77_88_99_AA_BB")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, profile.ErrRejected.Name, err.Name)
+	}
+}
+
+func TestHandlerAgentPasskeyRange(t *testing.T) {
+	a := NewHandlerAgent(&PolicyHandler{Passkey: 1000000})
+	_, err := a.RequestPasskey(testDevice)
+	assert.NotNil(t, err)
+}
+
+func TestTerminalHandler(t *testing.T) {
+	out := new(strings.Builder)
+	h := NewTerminalHandler(strings.NewReader("1234\nyes\nno\n"), out)
+	a := NewHandlerAgent(h)
+
+	pincode, err := a.RequestPinCode(testDevice)
+	assert.Nil(t, err)
+	assert.Equal(t, "1234", pincode)
+
+	assert.Nil(t, a.RequestConfirmation(testDevice, 42))
+	assert.NotNil(t, a.RequestAuthorization(testDevice))
+
+	assert.Contains(t, out.String(), "Confirm passkey 000042 for 00:11:22:33:44:55")
+}
+
+func TestHTTPHandler(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := HTTPRequest{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		res := HTTPResponse{
+			Accept:  body.Address == "00:11:22:33:44:55",
+			Passkey: 123456,
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	a := NewHandlerAgent(NewHTTPHandler(server.URL))
+
+	passkey, err := a.RequestPasskey(testDevice)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(123456), passkey)
+
+	assert.NotNil(t, a.RequestAuthorization("/org/bluez/hci0/dev_66_77_88_99_AA_BB"))
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"strings"
+)
+
+// PolicyHandler is a Handler answering from allow-lists. Allowed requests
+// needing an input are forwarded to Next when set, eg. a prompt, otherwise
+// answered with PinCode and Passkey and confirmed.
+type PolicyHandler struct {
+	// Addresses allowed to pair, empty allows any device
+	Addresses []string
+	// Services allowed by AuthorizeService, empty allows any service
+	Services []string
+	PinCode  string
+	Passkey  uint32
+	Next     Handler
+}
+
+func (p *PolicyHandler) allowAddress(req Request) bool {
+	if len(p.Addresses) == 0 {
+		return true
+	}
+	for _, address := range p.Addresses {
+		if strings.EqualFold(address, req.Address) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PolicyHandler) allowService(uuid string) bool {
+	if len(p.Services) == 0 {
+		return true
+	}
+	for _, service := range p.Services {
+		if strings.EqualFold(service, uuid) {
+			return true
+		}
+		// 16 bit UUID, eg. 110b
+		if len(service) == 4 && len(uuid) == 36 && strings.EqualFold(service, uuid[4:8]) &&
+			strings.EqualFold(uuid[8:], "-0000-1000-8000-00805f9b34fb") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PolicyHandler) RequestPinCode(ctx context.Context, req Request) (string, error) {
+	if !p.allowAddress(req) {
+		return "", ErrRequestRejected
+	}
+	if p.Next != nil {
+		return p.Next.RequestPinCode(ctx, req)
+	}
+	return p.PinCode, nil
+}
+
+func (p *PolicyHandler) DisplayPinCode(ctx context.Context, req Request, pincode string) error {
+	if !p.allowAddress(req) {
+		return ErrRequestRejected
+	}
+	if p.Next != nil {
+		return p.Next.DisplayPinCode(ctx, req, pincode)
+	}
+	return nil
+}
+
+func (p *PolicyHandler) RequestPasskey(ctx context.Context, req Request) (uint32, error) {
+	if !p.allowAddress(req) {
+		return 0, ErrRequestRejected
+	}
+	if p.Next != nil {
+		return p.Next.RequestPasskey(ctx, req)
+	}
+	return p.Passkey, nil
+}
+
+func (p *PolicyHandler) DisplayPasskey(ctx context.Context, req Request, passkey uint32, entered uint16) error {
+	if !p.allowAddress(req) {
+		return ErrRequestRejected
+	}
+	if p.Next != nil {
+		return p.Next.DisplayPasskey(ctx, req, passkey, entered)
+	}
+	return nil
+}
+
+func (p *PolicyHandler) RequestConfirmation(ctx context.Context, req Request, passkey uint32) error {
+	if !p.allowAddress(req) {
+		return ErrRequestRejected
+	}
+	if p.Next != nil {
+		return p.Next.RequestConfirmation(ctx, req, passkey)
+	}
+	return nil
+}
+
+func (p *PolicyHandler) RequestAuthorization(ctx context.Context, req Request) error {
+	if !p.allowAddress(req) {
+		return ErrRequestRejected
+	}
+	if p.Next != nil {
+		return p.Next.RequestAuthorization(ctx, req)
+	}
+	return nil
+}
+
+func (p *PolicyHandler) AuthorizeService(ctx context.Context, req Request, uuid string) error {
+	if !p.allowAddress(req) || !p.allowService(uuid) {
+		return ErrRequestRejected
+	}
+	if p.Next != nil {
+		return p.Next.AuthorizeService(ctx, req, uuid)
+	}
+	return nil
+}
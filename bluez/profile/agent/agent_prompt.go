@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NewTerminalHandler create a Handler prompting on out and reading the answers from in
+func NewTerminalHandler(in io.Reader, out io.Writer) *TerminalHandler {
+	return &TerminalHandler{
+		in:  in,
+		out: out,
+	}
+}
+
+// TerminalHandler is a Handler prompting the user on a terminal
+type TerminalHandler struct {
+	in    io.Reader
+	out   io.Writer
+	once  sync.Once
+	lines chan string
+	lock  sync.Mutex
+}
+
+// readLines read the input in background, a pending read can not be interrupted
+func (t *TerminalHandler) readLines() {
+	t.lines = make(chan string)
+	go func() {
+		defer close(t.lines)
+		scanner := bufio.NewScanner(t.in)
+		for scanner.Scan() {
+			t.lines <- strings.TrimSpace(scanner.Text())
+		}
+	}()
+}
+
+func (t *TerminalHandler) prompt(ctx context.Context, format string, args ...interface{}) (string, error) {
+
+	t.once.Do(t.readLines)
+
+	// one prompt at a time
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	fmt.Fprintf(t.out, format, args...)
+
+	select {
+	case line, ok := <-t.lines:
+		if !ok {
+			return "", io.EOF
+		}
+		return line, nil
+	case <-ctx.Done():
+		fmt.Fprintln(t.out)
+		return "", ctx.Err()
+	}
+}
+
+func (t *TerminalHandler) confirm(ctx context.Context, format string, args ...interface{}) error {
+	answer, err := t.prompt(ctx, format+" (yes/no): ", args...)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return nil
+	}
+	return ErrRequestRejected
+}
+
+func (t *TerminalHandler) RequestPinCode(ctx context.Context, req Request) (string, error) {
+	return t.prompt(ctx, "Enter PIN code for %s: ", req.Address)
+}
+
+func (t *TerminalHandler) DisplayPinCode(ctx context.Context, req Request, pincode string) error {
+	fmt.Fprintf(t.out, "PIN code for %s: %s\n", req.Address, pincode)
+	return nil
+}
+
+func (t *TerminalHandler) RequestPasskey(ctx context.Context, req Request) (uint32, error) {
+	answer, err := t.prompt(ctx, "Enter passkey for %s: ", req.Address)
+	if err != nil {
+		return 0, err
+	}
+	passkey, err := strconv.ParseUint(answer, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(passkey), nil
+}
+
+func (t *TerminalHandler) DisplayPasskey(ctx context.Context, req Request, passkey uint32, entered uint16) error {
+	fmt.Fprintf(t.out, "Passkey for %s: %06d (%d typed)\n", req.Address, passkey, entered)
+	return nil
+}
+
+func (t *TerminalHandler) RequestConfirmation(ctx context.Context, req Request, passkey uint32) error {
+	return t.confirm(ctx, "Confirm passkey %06d for %s", passkey, req.Address)
+}
+
+func (t *TerminalHandler) RequestAuthorization(ctx context.Context, req Request) error {
+	return t.confirm(ctx, "Authorize pairing with %s", req.Address)
+}
+
+func (t *TerminalHandler) AuthorizeService(ctx context.Context, req Request, uuid string) error {
+	return t.confirm(ctx, "Authorize service %s for %s", uuid, req.Address)
+}
+
+// HTTPRequest is the JSON body posted by HTTPHandler
+type HTTPRequest struct {
+	Method    string `json:"method"`
+	Device    string `json:"device"`
+	AdapterID string `json:"adapter"`
+	Address   string `json:"address"`
+	PinCode   string `json:"pincode,omitempty"`
+	Passkey   uint32 `json:"passkey,omitempty"`
+	Entered   uint16 `json:"entered,omitempty"`
+	UUID      string `json:"uuid,omitempty"`
+}
+
+// HTTPResponse is the JSON body expected from the HTTPHandler endpoint
+type HTTPResponse struct {
+	Accept  bool   `json:"accept"`
+	PinCode string `json:"pincode,omitempty"`
+	Passkey uint32 `json:"passkey,omitempty"`
+}
+
+// NewHTTPHandler create a Handler posting the requests to url
+func NewHTTPHandler(url string) *HTTPHandler {
+	return &HTTPHandler{
+		URL:    url,
+		Client: http.DefaultClient,
+	}
+}
+
+// HTTPHandler is a Handler delegating the decisions to an HTTP endpoint
+type HTTPHandler struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h *HTTPHandler) post(ctx context.Context, body HTTPRequest) (HTTPResponse, error) {
+
+	res := HTTPResponse{}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return res, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(payload))
+	if err != nil {
+		return res, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpRes, err := h.Client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+		return res, err
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusOK {
+		return res, fmt.Errorf("%s: %s", h.URL, httpRes.Status)
+	}
+
+	err = json.NewDecoder(httpRes.Body).Decode(&res)
+	if err != nil {
+		return res, err
+	}
+	if !res.Accept {
+		return res, ErrRequestRejected
+	}
+
+	return res, nil
+}
+
+func newHTTPRequest(req Request) HTTPRequest {
+	return HTTPRequest{
+		Method:    req.Method,
+		Device:    string(req.Device),
+		AdapterID: req.AdapterID,
+		Address:   req.Address,
+	}
+}
+
+func (h *HTTPHandler) RequestPinCode(ctx context.Context, req Request) (string, error) {
+	res, err := h.post(ctx, newHTTPRequest(req))
+	return res.PinCode, err
+}
+
+func (h *HTTPHandler) DisplayPinCode(ctx context.Context, req Request, pincode string) error {
+	body := newHTTPRequest(req)
+	body.PinCode = pincode
+	_, err := h.post(ctx, body)
+	return err
+}
+
+func (h *HTTPHandler) RequestPasskey(ctx context.Context, req Request) (uint32, error) {
+	res, err := h.post(ctx, newHTTPRequest(req))
+	return res.Passkey, err
+}
+
+func (h *HTTPHandler) DisplayPasskey(ctx context.Context, req Request, passkey uint32, entered uint16) error {
+	body := newHTTPRequest(req)
+	body.Passkey = passkey
+	body.Entered = entered
+	_, err := h.post(ctx, body)
+	return err
+}
+
+func (h *HTTPHandler) RequestConfirmation(ctx context.Context, req Request, passkey uint32) error {
+	body := newHTTPRequest(req)
+	body.Passkey = passkey
+	_, err := h.post(ctx, body)
+	return err
+}
+
+func (h *HTTPHandler) RequestAuthorization(ctx context.Context, req Request) error {
+	_, err := h.post(ctx, newHTTPRequest(req))
+	return err
+}
+
+func (h *HTTPHandler) AuthorizeService(ctx context.Context, req Request, uuid string) error {
+	body := newHTTPRequest(req)
+	body.UUID = uuid
+	_, err := h.post(ctx, body)
+	return err
+}
@@ -4,20 +4,18 @@ import (
 	"fmt"
 
 	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/adapter"
 	log "github.com/sirupsen/logrus"
 )
 
-var agentInstances = 0
-
 const AgentBasePath = "/agent/simple%d"
 const SimpleAgentPinCode = "0000"
 const SimpleAgentPassKey uint32 = 1024
 
+// NextAgentPath reserve the first free agent path, use UnexportAgent to release it
 func NextAgentPath() dbus.ObjectPath {
-	p := dbus.ObjectPath(fmt.Sprintf(AgentBasePath, agentInstances))
-	agentInstances += 1
-	return p
+	return bluez.NewObjectPath(AgentBasePath)
 }
 
 // NewDefaultSimpleAgent return a SimpleAgent instance with default pincode and passcode
@@ -15,6 +15,22 @@ func NewDevice(adapterID string, address string) (*Device1, error) {
 	return NewDevice1(dbus.ObjectPath(path))
 }
 
+// NewDeviceWithProperties create a Device1 with the given properties without
+// loading them from the bus, eg. for devices simulated offline
+func NewDeviceWithProperties(objectPath dbus.ObjectPath, props *Device1Properties) *Device1 {
+	a := new(Device1)
+	a.client = bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez",
+			Iface: Device1Interface,
+			Path:  objectPath,
+			Bus:   bluez.SystemBus,
+		},
+	)
+	a.Properties = props
+	return a
+}
+
 // GetCharacteristicsList return device characteristics object path list
 func (d *Device1) GetCharacteristicsList() ([]dbus.ObjectPath, error) {
 
@@ -0,0 +1,156 @@
+package media
+
+import (
+	"fmt"
+)
+
+// AAC object types
+const (
+	AACObjectTypeMPEG2LC       byte = 0x80
+	AACObjectTypeMPEG4LC       byte = 0x40
+	AACObjectTypeMPEG4LTP      byte = 0x20
+	AACObjectTypeMPEG4Scalable byte = 0x10
+)
+
+// AAC sampling frequencies
+const (
+	AACFrequency8000  uint16 = 0x0800
+	AACFrequency11025 uint16 = 0x0400
+	AACFrequency12000 uint16 = 0x0200
+	AACFrequency16000 uint16 = 0x0100
+	AACFrequency22050 uint16 = 0x0080
+	AACFrequency24000 uint16 = 0x0040
+	AACFrequency32000 uint16 = 0x0020
+	AACFrequency44100 uint16 = 0x0010
+	AACFrequency48000 uint16 = 0x0008
+	AACFrequency64000 uint16 = 0x0004
+	AACFrequency88200 uint16 = 0x0002
+	AACFrequency96000 uint16 = 0x0001
+)
+
+// AAC channels
+const (
+	AACChannels1 byte = 0x02
+	AACChannels2 byte = 0x01
+)
+
+var aacFrequencies = []struct {
+	bit  uint16
+	rate int
+}{
+	{AACFrequency48000, 48000},
+	{AACFrequency44100, 44100},
+	{AACFrequency96000, 96000},
+	{AACFrequency88200, 88200},
+	{AACFrequency64000, 64000},
+	{AACFrequency32000, 32000},
+	{AACFrequency24000, 24000},
+	{AACFrequency22050, 22050},
+	{AACFrequency16000, 16000},
+	{AACFrequency12000, 12000},
+	{AACFrequency11025, 11025},
+	{AACFrequency8000, 8000},
+}
+
+// AACCapabilities are the MPEG-2,4 AAC codec capabilities, or a
+// configuration when a single bit is set in each mask
+type AACCapabilities struct {
+	ObjectTypes byte
+	Frequencies uint16
+	Channels    byte
+	VBR         bool
+	// Bitrate is the maximum bitrate in bits per second, 0 if unknown
+	Bitrate uint32
+}
+
+// DefaultAACCapabilities support AAC-LC at 44.1 or 48 kHz
+func DefaultAACCapabilities() AACCapabilities {
+	return AACCapabilities{
+		ObjectTypes: AACObjectTypeMPEG2LC | AACObjectTypeMPEG4LC,
+		Frequencies: AACFrequency44100 | AACFrequency48000,
+		Channels:    AACChannels1 | AACChannels2,
+		VBR:         true,
+		Bitrate:     320000,
+	}
+}
+
+// ParseAACCapabilities decode the 6 bytes AAC capabilities
+func ParseAACCapabilities(b []byte) (AACCapabilities, error) {
+	if len(b) != 6 {
+		return AACCapabilities{}, fmt.Errorf("AAC capabilities: invalid length %d", len(b))
+	}
+	return AACCapabilities{
+		ObjectTypes: b[0],
+		Frequencies: uint16(b[1])<<4 | uint16(b[2]>>4),
+		Channels:    (b[2] >> 2) & 0x03,
+		VBR:         b[3]&0x80 != 0,
+		Bitrate:     uint32(b[3]&0x7f)<<16 | uint32(b[4])<<8 | uint32(b[5]),
+	}, nil
+}
+
+// Bytes encode the capabilities
+func (c AACCapabilities) Bytes() []byte {
+	b := []byte{
+		c.ObjectTypes,
+		byte(c.Frequencies >> 4),
+		byte(c.Frequencies&0x0f)<<4 | (c.Channels&0x03)<<2,
+		byte(c.Bitrate>>16) & 0x7f,
+		byte(c.Bitrate >> 8),
+		byte(c.Bitrate),
+	}
+	if c.VBR {
+		b[3] |= 0x80
+	}
+	return b
+}
+
+// SampleRate return the configured sampling frequency
+func (c AACCapabilities) SampleRate() int {
+	for _, f := range aacFrequencies {
+		if c.Frequencies&f.bit != 0 {
+			return f.rate
+		}
+	}
+	return 0
+}
+
+// ChannelCount return the configured number of channels
+func (c AACCapabilities) ChannelCount() int {
+	switch {
+	case c.Channels&AACChannels2 != 0:
+		return 2
+	case c.Channels&AACChannels1 != 0:
+		return 1
+	}
+	return 0
+}
+
+// SelectAAC choose the best configuration supported by local and remote
+func SelectAAC(local, remote AACCapabilities) (AACCapabilities, error) {
+
+	config := AACCapabilities{
+		ObjectTypes: first(local.ObjectTypes&remote.ObjectTypes,
+			AACObjectTypeMPEG2LC, AACObjectTypeMPEG4LC, AACObjectTypeMPEG4LTP, AACObjectTypeMPEG4Scalable),
+		Channels: first(local.Channels&remote.Channels, AACChannels2, AACChannels1),
+		VBR:      local.VBR && remote.VBR,
+	}
+
+	frequencies := local.Frequencies & remote.Frequencies
+	for _, f := range aacFrequencies {
+		if frequencies&f.bit != 0 {
+			config.Frequencies = f.bit
+			break
+		}
+	}
+
+	if config.ObjectTypes == 0 || config.Frequencies == 0 || config.Channels == 0 {
+		return AACCapabilities{}, ErrNoConfiguration
+	}
+
+	config.Bitrate = local.Bitrate
+	if config.Bitrate == 0 || (remote.Bitrate != 0 && remote.Bitrate < config.Bitrate) {
+		config.Bitrate = remote.Bitrate
+	}
+
+	return config, nil
+}
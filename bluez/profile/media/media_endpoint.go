@@ -0,0 +1,285 @@
+package media
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	log "github.com/sirupsen/logrus"
+)
+
+const EndpointBasePath = "/media/endpoint%d"
+
+// Endpoint event types
+const (
+	// TransportConfigured is sent when BlueZ created a MediaTransport1
+	TransportConfigured = "configured"
+	// TransportCleared is sent when the transport configuration is cleared
+	TransportCleared = "cleared"
+	// EndpointReleased is sent when BlueZ unregistered the endpoint
+	EndpointReleased = "released"
+)
+
+// EndpointEvent report the transports configured on an Endpoint
+type EndpointEvent struct {
+	Type      string
+	Transport dbus.ObjectPath
+	// Device, Configuration and Properties are set for TransportConfigured
+	Device        dbus.ObjectPath
+	Configuration []byte
+	Properties    map[string]dbus.Variant
+}
+
+// SelectFunc choose a configuration from the remote capabilities
+type SelectFunc func(capabilities []byte) ([]byte, error)
+
+// NewEndpoint create an endpoint for codec, selecting the configurations
+// with selectFn
+func NewEndpoint(uuid string, codec byte, capabilities []byte, selectFn SelectFunc) *Endpoint {
+	return &Endpoint{
+		UUID:         uuid,
+		Codec:        codec,
+		Capabilities: capabilities,
+		selectFn:     selectFn,
+		path:         bluez.NewObjectPath(EndpointBasePath),
+		events:       make(chan EndpointEvent, 16),
+		transports:   map[dbus.ObjectPath]EndpointEvent{},
+	}
+}
+
+// NewSBCEndpoint create an SBC endpoint, eg. for A2DPSinkUUID to receive
+// audio from a phone or A2DPSourceUUID to stream to a speaker
+func NewSBCEndpoint(uuid string, capabilities SBCCapabilities) *Endpoint {
+	return NewEndpoint(uuid, CodecSBC, capabilities.Bytes(), func(b []byte) ([]byte, error) {
+		remote, err := ParseSBCCapabilities(b)
+		if err != nil {
+			return nil, err
+		}
+		config, err := SelectSBC(capabilities, remote)
+		if err != nil {
+			return nil, err
+		}
+		return config.Bytes(), nil
+	})
+}
+
+// NewAACEndpoint create an MPEG-2,4 AAC endpoint
+func NewAACEndpoint(uuid string, capabilities AACCapabilities) *Endpoint {
+	return NewEndpoint(uuid, CodecMPEG24, capabilities.Bytes(), func(b []byte) ([]byte, error) {
+		remote, err := ParseAACCapabilities(b)
+		if err != nil {
+			return nil, err
+		}
+		config, err := SelectAAC(capabilities, remote)
+		if err != nil {
+			return nil, err
+		}
+		return config.Bytes(), nil
+	})
+}
+
+// Endpoint is a local MediaEndpoint1 implementation
+type Endpoint struct {
+	UUID         string
+	Codec        byte
+	Capabilities []byte
+
+	selectFn  SelectFunc
+	path      dbus.ObjectPath
+	adapterID string
+	conn      *dbus.Conn
+
+	lock       sync.Mutex
+	events     chan EndpointEvent
+	closed     bool
+	transports map[dbus.ObjectPath]EndpointEvent
+}
+
+// Path return the endpoint object path
+func (e *Endpoint) Path() dbus.ObjectPath {
+	return e.path
+}
+
+// Events return the transport events, the channel is closed by Unregister
+func (e *Endpoint) Events() <-chan EndpointEvent {
+	return e.events
+}
+
+// Transports return the configured transports
+func (e *Endpoint) Transports() []EndpointEvent {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	list := []EndpointEvent{}
+	for _, ev := range e.transports {
+		list = append(list, ev)
+	}
+	return list
+}
+
+// endpointObject is the MediaEndpoint1 object exported on DBus
+type endpointObject struct {
+	endpoint *Endpoint
+}
+
+func (o *endpointObject) SetConfiguration(transport dbus.ObjectPath, properties map[string]dbus.Variant) *dbus.Error {
+	log.Debugf("Endpoint %s: SetConfiguration %s", o.endpoint.path, transport)
+	o.endpoint.setConfiguration(transport, properties)
+	return nil
+}
+
+func (o *endpointObject) SelectConfiguration(capabilities []byte) ([]byte, *dbus.Error) {
+	config, err := o.endpoint.selectFn(capabilities)
+	if err != nil {
+		log.Debugf("Endpoint %s: SelectConfiguration %x: %s", o.endpoint.path, capabilities, err)
+		return nil, &dbus.Error{
+			Name: "org.bluez.Error.InvalidArguments",
+			Body: []interface{}{err.Error()},
+		}
+	}
+	log.Debugf("Endpoint %s: SelectConfiguration %x: %x", o.endpoint.path, capabilities, config)
+	return config, nil
+}
+
+func (o *endpointObject) ClearConfiguration(transport dbus.ObjectPath) *dbus.Error {
+	log.Debugf("Endpoint %s: ClearConfiguration %s", o.endpoint.path, transport)
+	o.endpoint.clearConfiguration(transport)
+	return nil
+}
+
+func (o *endpointObject) Release() *dbus.Error {
+	log.Debugf("Endpoint %s: Release", o.endpoint.path)
+	o.endpoint.send(EndpointEvent{Type: EndpointReleased})
+	return nil
+}
+
+func (e *Endpoint) setConfiguration(transport dbus.ObjectPath, properties map[string]dbus.Variant) {
+	ev := EndpointEvent{
+		Type:       TransportConfigured,
+		Transport:  transport,
+		Properties: properties,
+	}
+	ev.Device, _ = properties["Device"].Value().(dbus.ObjectPath)
+	ev.Configuration, _ = properties["Configuration"].Value().([]byte)
+
+	e.lock.Lock()
+	e.transports[transport] = ev
+	e.lock.Unlock()
+
+	e.send(ev)
+}
+
+func (e *Endpoint) clearConfiguration(transport dbus.ObjectPath) {
+	e.lock.Lock()
+	ev, ok := e.transports[transport]
+	delete(e.transports, transport)
+	e.lock.Unlock()
+
+	if !ok {
+		ev = EndpointEvent{Transport: transport}
+	}
+	ev.Type = TransportCleared
+	e.send(ev)
+}
+
+func (e *Endpoint) send(ev EndpointEvent) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.events <- ev:
+	default:
+		log.Warnf("Endpoint %s: events channel full, dropped %s %s", e.path, ev.Type, ev.Transport)
+	}
+}
+
+// Register export the endpoint and register it on the adapter
+func (e *Endpoint) Register(adapterID string) error {
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+	e.conn = conn
+
+	obj := &endpointObject{endpoint: e}
+	err = conn.Export(obj, e.path, MediaEndpoint1Interface)
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    MediaEndpoint1Interface,
+				Methods: introspect.Methods(obj),
+			},
+		},
+	}
+	err = conn.Export(introspect.NewIntrospectable(node), e.path, bluez.Introspectable)
+	if err != nil {
+		e.unexport()
+		return err
+	}
+
+	media, err := NewMedia1(dbus.ObjectPath(fmt.Sprintf("/org/bluez/%s", adapterID)))
+	if err != nil {
+		e.unexport()
+		return err
+	}
+	defer media.Close()
+
+	err = media.RegisterEndpoint(e.path, map[string]interface{}{
+		"UUID":         e.UUID,
+		"Codec":        e.Codec,
+		"Capabilities": e.Capabilities,
+	})
+	if err != nil {
+		e.unexport()
+		return fmt.Errorf("RegisterEndpoint %s: %s", e.path, err)
+	}
+
+	e.adapterID = adapterID
+	return nil
+}
+
+func (e *Endpoint) unexport() {
+	e.conn.Export(nil, e.path, MediaEndpoint1Interface)
+	e.conn.Export(nil, e.path, bluez.Introspectable)
+}
+
+// Unregister the endpoint and close the events channel
+func (e *Endpoint) Unregister() error {
+
+	e.lock.Lock()
+	if e.closed {
+		e.lock.Unlock()
+		return nil
+	}
+	e.closed = true
+	close(e.events)
+	e.lock.Unlock()
+
+	defer bluez.ReleaseObjectPath(e.path)
+
+	if e.conn == nil {
+		return nil
+	}
+	defer e.unexport()
+
+	if e.adapterID == "" {
+		return nil
+	}
+
+	media, err := NewMedia1(dbus.ObjectPath(fmt.Sprintf("/org/bluez/%s", e.adapterID)))
+	if err != nil {
+		return err
+	}
+	defer media.Close()
+
+	return media.UnregisterEndpoint(e.path)
+}
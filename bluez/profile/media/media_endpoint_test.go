@@ -0,0 +1,120 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSBCCapabilities(t *testing.T) {
+
+	caps := DefaultSBCCapabilities()
+	b := caps.Bytes()
+	assert.Equal(t, []byte{0xff, 0xff, 2, 64}, b)
+
+	parsed, err := ParseSBCCapabilities(b)
+	assert.Nil(t, err)
+	assert.Equal(t, caps, parsed)
+
+	_, err = ParseSBCCapabilities([]byte{0xff})
+	assert.NotNil(t, err)
+}
+
+func TestSelectSBC(t *testing.T) {
+
+	// a speaker supporting 44.1 kHz stereo only
+	remote, err := ParseSBCCapabilities([]byte{0x23, 0x15, 2, 53})
+	assert.Nil(t, err)
+
+	config, err := SelectSBC(DefaultSBCCapabilities(), remote)
+	assert.Nil(t, err)
+	assert.Equal(t, 44100, config.SampleRate())
+	assert.Equal(t, SBCChannelModeJointStereo, config.ChannelModes)
+	assert.Equal(t, 2, config.Channels())
+	assert.Equal(t, 16, config.Blocks())
+	assert.Equal(t, 8, config.SubbandCount())
+	assert.Equal(t, SBCAllocationLoudness, config.AllocationMethods)
+	assert.Equal(t, byte(2), config.MinBitpool)
+	assert.Equal(t, byte(53), config.MaxBitpool)
+	assert.Equal(t, []byte{0x21, 0x15, 2, 53}, config.Bytes())
+
+	// mono only
+	remote.ChannelModes = SBCChannelModeMono
+	config, err = SelectSBC(DefaultSBCCapabilities(), remote)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, config.Channels())
+	assert.Equal(t, byte(31), config.MaxBitpool)
+
+	remote.Frequencies = 0
+	_, err = SelectSBC(DefaultSBCCapabilities(), remote)
+	assert.Equal(t, ErrNoConfiguration, err)
+}
+
+func TestAACCapabilities(t *testing.T) {
+
+	caps := DefaultAACCapabilities()
+	b := caps.Bytes()
+	assert.Equal(t, []byte{0xc0, 0x01, 0x8c, 0x84, 0xe2, 0x00}, b)
+
+	parsed, err := ParseAACCapabilities(b)
+	assert.Nil(t, err)
+	assert.Equal(t, caps, parsed)
+
+	remote := AACCapabilities{
+		ObjectTypes: AACObjectTypeMPEG4LC,
+		Frequencies: AACFrequency44100 | AACFrequency32000,
+		Channels:    AACChannels2,
+		Bitrate:     256000,
+	}
+	config, err := SelectAAC(caps, remote)
+	assert.Nil(t, err)
+	assert.Equal(t, AACObjectTypeMPEG4LC, config.ObjectTypes)
+	assert.Equal(t, 44100, config.SampleRate())
+	assert.Equal(t, 2, config.ChannelCount())
+	assert.False(t, config.VBR)
+	assert.Equal(t, uint32(256000), config.Bitrate)
+
+	remote.ObjectTypes = AACObjectTypeMPEG4Scalable
+	_, err = SelectAAC(caps, remote)
+	assert.Equal(t, ErrNoConfiguration, err)
+}
+
+func TestEndpointEvents(t *testing.T) {
+
+	e := NewSBCEndpoint(A2DPSinkUUID, DefaultSBCCapabilities())
+	defer bluez.ReleaseObjectPath(e.Path())
+	obj := &endpointObject{endpoint: e}
+
+	config, derr := obj.SelectConfiguration([]byte{0x23, 0x15, 2, 53})
+	assert.Nil(t, derr)
+	assert.Equal(t, []byte{0x21, 0x15, 2, 53}, config)
+
+	_, derr = obj.SelectConfiguration([]byte{0x00})
+	assert.NotNil(t, derr)
+
+	transport := dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55/fd0")
+	device := dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55")
+	obj.SetConfiguration(transport, map[string]dbus.Variant{
+		"Device":        dbus.MakeVariant(device),
+		"Configuration": dbus.MakeVariant(config),
+	})
+
+	ev := <-e.Events()
+	assert.Equal(t, TransportConfigured, ev.Type)
+	assert.Equal(t, transport, ev.Transport)
+	assert.Equal(t, device, ev.Device)
+	assert.Equal(t, config, ev.Configuration)
+	assert.Len(t, e.Transports(), 1)
+
+	obj.ClearConfiguration(transport)
+	ev = <-e.Events()
+	assert.Equal(t, TransportCleared, ev.Type)
+	assert.Equal(t, device, ev.Device)
+	assert.Len(t, e.Transports(), 0)
+
+	assert.Nil(t, e.Unregister())
+	_, ok := <-e.Events()
+	assert.False(t, ok)
+}
@@ -0,0 +1,551 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/props"
+	log "github.com/sirupsen/logrus"
+)
+
+// Player status values
+const (
+	StatusPlaying     = "playing"
+	StatusStopped     = "stopped"
+	StatusPaused      = "paused"
+	StatusForwardSeek = "forward-seek"
+	StatusReverseSeek = "reverse-seek"
+	StatusError       = "error"
+)
+
+// Shuffle and Repeat values, Shuffle does not support singletrack
+const (
+	ModeOff         = "off"
+	ModeSingleTrack = "singletrack"
+	ModeAllTracks   = "alltracks"
+	ModeGroup       = "group"
+)
+
+// ErrNoPlayer is returned when the device has no media player
+var ErrNoPlayer = errors.New("No media player")
+
+// Track is the metadata of the current track or of a MediaItem1
+type Track struct {
+	Title          string
+	Artist         string
+	Album          string
+	Genre          string
+	NumberOfTracks uint32
+	TrackNumber    uint32
+	Duration       time.Duration
+}
+
+// ParseTrack decode a Track or Metadata property
+func ParseTrack(props map[string]dbus.Variant) Track {
+	t := Track{}
+	t.Title, _ = props["Title"].Value().(string)
+	t.Artist, _ = props["Artist"].Value().(string)
+	t.Album, _ = props["Album"].Value().(string)
+	t.Genre, _ = props["Genre"].Value().(string)
+	t.NumberOfTracks, _ = props["NumberOfTracks"].Value().(uint32)
+	t.TrackNumber, _ = props["TrackNumber"].Value().(uint32)
+	if ms, ok := props["Duration"].Value().(uint32); ok {
+		t.Duration = time.Duration(ms) * time.Millisecond
+	}
+	return t
+}
+
+// variantMap return the map held by a variant
+func variantMap(v dbus.Variant) map[string]dbus.Variant {
+	switch m := v.Value().(type) {
+	case map[string]dbus.Variant:
+		return m
+	case map[string]interface{}:
+		return props.ToVariantMap(m)
+	}
+	return map[string]dbus.Variant{}
+}
+
+// PlayerState is the state of a remote media player
+type PlayerState struct {
+	Player     dbus.ObjectPath
+	Device     dbus.ObjectPath
+	Name       string
+	Status     string
+	Position   time.Duration
+	Track      Track
+	Shuffle    string
+	Repeat     string
+	Browsable  bool
+	Searchable bool
+	// Updated is the time Position was last reported
+	Updated time.Time
+}
+
+// Elapsed estimate the current position, as the players do not report the
+// position while playing
+func (s PlayerState) Elapsed() time.Duration {
+	pos := s.Position
+	if s.Status == StatusPlaying && !s.Updated.IsZero() {
+		pos += time.Since(s.Updated)
+	}
+	if s.Track.Duration > 0 && pos > s.Track.Duration {
+		pos = s.Track.Duration
+	}
+	return pos
+}
+
+// apply the MediaPlayer1 properties, return true if the state changed
+func (s *PlayerState) apply(props map[string]dbus.Variant) bool {
+	changed := false
+	if v, ok := props["Name"].Value().(string); ok {
+		s.Name = v
+		changed = true
+	}
+	if v, ok := props["Device"].Value().(dbus.ObjectPath); ok {
+		s.Device = v
+		changed = true
+	}
+	if v, ok := props["Status"].Value().(string); ok {
+		// keep the estimated position when the playback stops or resumes
+		s.Position = s.Elapsed()
+		s.Updated = time.Now()
+		s.Status = v
+		changed = true
+	}
+	if v, ok := props["Position"].Value().(uint32); ok {
+		s.Position = time.Duration(v) * time.Millisecond
+		s.Updated = time.Now()
+		changed = true
+	}
+	if v, ok := props["Track"]; ok {
+		s.Track = ParseTrack(variantMap(v))
+		changed = true
+	}
+	if v, ok := props["Shuffle"].Value().(string); ok {
+		s.Shuffle = v
+		changed = true
+	}
+	if v, ok := props["Repeat"].Value().(string); ok {
+		s.Repeat = v
+		changed = true
+	}
+	if v, ok := props["Browsable"].Value().(bool); ok {
+		s.Browsable = v
+		changed = true
+	}
+	if v, ok := props["Searchable"].Value().(bool); ok {
+		s.Searchable = v
+		changed = true
+	}
+	return changed
+}
+
+// FindPlayer return the active player of device, falling back to the
+// first player exposed below the device path
+func FindPlayer(device dbus.ObjectPath) (dbus.ObjectPath, error) {
+
+	control, err := NewMediaControl1(device)
+	if err == nil {
+		player, err := control.GetPlayer()
+		control.Close()
+		if err == nil && player != "" && player != "/" {
+			return player, nil
+		}
+	}
+
+	om, err := bluez.GetObjectManager()
+	if err != nil {
+		return "", err
+	}
+	objects, err := om.GetManagedObjects()
+	if err != nil {
+		return "", err
+	}
+
+	prefix := string(device) + "/"
+	var found dbus.ObjectPath
+	for path, ifaces := range objects {
+		if !strings.HasPrefix(string(path), prefix) {
+			continue
+		}
+		if _, ok := ifaces[MediaPlayer1Interface]; !ok {
+			continue
+		}
+		// paths are not ordered, pick the lowest for a stable result
+		if found == "" || path < found {
+			found = path
+		}
+	}
+	if found == "" {
+		return "", ErrNoPlayer
+	}
+	return found, nil
+}
+
+// NewRemote create a remote control for the active player of device
+func NewRemote(device dbus.ObjectPath) (*Remote, error) {
+	path, err := FindPlayer(device)
+	if err != nil {
+		return nil, err
+	}
+	player, err := NewMediaPlayer1(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Remote{
+		Device: device,
+		player: player,
+	}, nil
+}
+
+// Remote control a media player exposed by a device over AVRCP
+type Remote struct {
+	Device dbus.ObjectPath
+
+	lock   sync.Mutex
+	player *MediaPlayer1
+}
+
+// Player return the controlled MediaPlayer1
+func (r *Remote) Player() *MediaPlayer1 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.player
+}
+
+// setPlayer switch to another player of the device
+func (r *Remote) setPlayer(path dbus.ObjectPath) error {
+	player, err := NewMediaPlayer1(path)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	old := r.player
+	r.player = player
+	r.lock.Unlock()
+	old.Close()
+	return nil
+}
+
+// Close the player connection
+func (r *Remote) Close() {
+	r.Player().Close()
+}
+
+func (r *Remote) Play() error {
+	return r.Player().Play()
+}
+
+func (r *Remote) Pause() error {
+	return r.Player().Pause()
+}
+
+func (r *Remote) Stop() error {
+	return r.Player().Stop()
+}
+
+func (r *Remote) Next() error {
+	return r.Player().Next()
+}
+
+func (r *Remote) Previous() error {
+	return r.Player().Previous()
+}
+
+// FastForward seek forward until Play or Pause is called
+func (r *Remote) FastForward() error {
+	return r.Player().FastForward()
+}
+
+// Rewind seek backward until Play or Pause is called
+func (r *Remote) Rewind() error {
+	return r.Player().Rewind()
+}
+
+// SetShuffle set the shuffle mode, ModeOff, ModeAllTracks or ModeGroup
+func (r *Remote) SetShuffle(mode string) error {
+	return r.Player().SetShuffle(mode)
+}
+
+// SetRepeat set the repeat mode
+func (r *Remote) SetRepeat(mode string) error {
+	return r.Player().SetRepeat(mode)
+}
+
+// State read the current player state
+func (r *Remote) State() (PlayerState, error) {
+	conn, err := bluez.GetConnection(bluez.SystemBus)
+	if err != nil {
+		return PlayerState{}, err
+	}
+
+	// GetAll is called directly, Track holds variants the generated
+	// properties do not decode
+	player := r.Player()
+	props := map[string]dbus.Variant{}
+	err = conn.Object(bluez.OrgBluezInterface, player.Path()).
+		Call("org.freedesktop.DBus.Properties.GetAll", 0, MediaPlayer1Interface).Store(&props)
+	if err != nil {
+		return PlayerState{}, fmt.Errorf("Properties.GetAll %s: %s", player.Path(), err)
+	}
+	state := PlayerState{Player: player.Path(), Device: r.Device}
+	state.apply(props)
+	return state, nil
+}
+
+// Watch stream the player state on every change, following the active
+// player of the device. The channel is closed when ctx is done.
+func (r *Remote) Watch(ctx context.Context) (chan PlayerState, error) {
+
+	conn, err := bluez.GetConnection(bluez.SystemBus)
+	if err != nil {
+		return nil, err
+	}
+
+	match := fmt.Sprintf("type='signal',interface='%s',member='PropertiesChanged',path_namespace='%s'",
+		bluez.PropertiesInterface, r.Device)
+	err = conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Store()
+	if err != nil {
+		return nil, err
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	state, err := r.State()
+	if err != nil {
+		bluez.DrainSignals(signals, func() {
+			conn.RemoveSignal(signals)
+			conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match)
+		})
+		return nil, err
+	}
+
+	ch := make(chan PlayerState, 16)
+	ch <- state
+
+	go func() {
+
+		defer close(ch)
+		defer bluez.DrainSignals(signals, func() {
+			conn.RemoveSignal(signals)
+			err := conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match).Store()
+			if err != nil {
+				log.Warnf("Remote %s: %s", r.Device, err)
+			}
+		})
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-signals:
+				if sig == nil {
+					return
+				}
+				if sig.Name != bluez.PropertiesChanged || len(sig.Body) < 2 {
+					continue
+				}
+				iface, _ := sig.Body[0].(string)
+				changes, ok := sig.Body[1].(map[string]dbus.Variant)
+				if !ok {
+					continue
+				}
+
+				switch {
+				case iface == MediaControl1Interface && sig.Path == r.Device:
+					path, ok := changes["Player"].Value().(dbus.ObjectPath)
+					if !ok || path == state.Player {
+						continue
+					}
+					err := r.setPlayer(path)
+					if err != nil {
+						log.Warnf("Remote %s: player %s: %s", r.Device, path, err)
+						continue
+					}
+					next, err := r.State()
+					if err != nil {
+						log.Warnf("Remote %s: %s", r.Device, err)
+						continue
+					}
+					state = next
+				case iface == MediaPlayer1Interface && sig.Path == state.Player:
+					if !state.apply(changes) {
+						continue
+					}
+				default:
+					continue
+				}
+
+				select {
+				case ch <- state:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// ItemFilter limit the items listed by Browse
+type ItemFilter struct {
+	Start uint32
+	// End is the last item, 0 to list every item
+	End uint32
+	// Attributes are the metadata to return, eg. Title, nil for all
+	Attributes []string
+}
+
+// ToMap convert the filter to the ListItems argument
+func (f ItemFilter) ToMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	if f.Start > 0 {
+		m["Start"] = f.Start
+	}
+	if f.End > 0 {
+		m["End"] = f.End
+	}
+	if f.Attributes != nil {
+		m["Attributes"] = f.Attributes
+	}
+	return m
+}
+
+// Item is a MediaItem1 listed in a folder
+type Item struct {
+	Path       dbus.ObjectPath
+	Name       string
+	Type       string
+	FolderType string
+	Playable   bool
+	Track      Track
+}
+
+// IsFolder return true if the item is a folder to browse
+func (i Item) IsFolder() bool {
+	return i.Type == "folder"
+}
+
+func newItem(path dbus.ObjectPath, props map[string]dbus.Variant) Item {
+	item := Item{Path: path}
+	item.Name, _ = props["Name"].Value().(string)
+	item.Type, _ = props["Type"].Value().(string)
+	item.FolderType, _ = props["FolderType"].Value().(string)
+	item.Playable, _ = props["Playable"].Value().(bool)
+	if v, ok := props["Metadata"]; ok {
+		item.Track = ParseTrack(variantMap(v))
+	}
+	return item
+}
+
+// folder return the player folder, MediaFolder1 is implemented by the
+// player object
+func (r *Remote) folder() (*MediaFolder1, error) {
+	return NewMediaFolder1Controller(r.Player().Path())
+}
+
+// Browse change to folder, if not empty, and list its items by uid. The
+// player must be Browsable.
+func (r *Remote) Browse(folder dbus.ObjectPath, filter ItemFilter) ([]Item, error) {
+
+	f, err := r.folder()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if folder != "" {
+		err = f.ChangeFolder(folder)
+		if err != nil {
+			return nil, fmt.Errorf("ChangeFolder %s: %s", folder, err)
+		}
+	}
+
+	// the generated ListItems signature does not match a{oa{sv}}
+	var list map[dbus.ObjectPath]map[string]dbus.Variant
+	err = f.Client().Call("ListItems", 0, filter.ToMap()).Store(&list)
+	if err != nil {
+		return nil, fmt.Errorf("ListItems: %s", err)
+	}
+
+	// the dict loses the order of the player
+	items := []Item{}
+	for path, props := range list {
+		items = append(items, newItem(path, props))
+	}
+	sortItems(items)
+	return items, nil
+}
+
+// sortItems sort the items by the uid ending their path, which players usually
+// assign in listing order, then by path
+func sortItems(items []Item) {
+	sort.Slice(items, func(i, j int) bool {
+		ui, iok := itemUID(items[i].Path)
+		uj, jok := itemUID(items[j].Path)
+		if iok && jok && ui != uj {
+			return ui < uj
+		}
+		if iok != jok {
+			return iok
+		}
+		return items[i].Path < items[j].Path
+	})
+}
+
+// itemUID return the uid ending an item path, eg. .../NowPlaying/item12
+func itemUID(path dbus.ObjectPath) (uint64, bool) {
+	name := string(path)
+	if i := strings.LastIndex(name, "/"); i > -1 {
+		name = name[i+1:]
+	}
+	if !strings.HasPrefix(name, "item") {
+		return 0, false
+	}
+	uid, err := strconv.ParseUint(name[len("item"):], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uid, true
+}
+
+// Search the player for value, returning the folder holding the results.
+// The player must be Searchable.
+func (r *Remote) Search(value string) (dbus.ObjectPath, error) {
+	f, err := r.folder()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return f.Search(value, map[string]interface{}{})
+}
+
+// PlayItem play the item at path
+func (r *Remote) PlayItem(path dbus.ObjectPath) error {
+	item, err := NewMediaItem1Controller(path)
+	if err != nil {
+		return err
+	}
+	defer item.Close()
+	return item.Play()
+}
+
+// AddToNowPlaying queue the item at path
+func (r *Remote) AddToNowPlaying(path dbus.ObjectPath) error {
+	item, err := NewMediaItem1Controller(path)
+	if err != nil {
+		return err
+	}
+	defer item.Close()
+	return item.AddtoNowPlaying()
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrack(t *testing.T) {
+
+	track := ParseTrack(map[string]dbus.Variant{
+		"Title":          dbus.MakeVariant("Song"),
+		"Artist":         dbus.MakeVariant("Band"),
+		"Album":          dbus.MakeVariant("Record"),
+		"Genre":          dbus.MakeVariant("Rock"),
+		"NumberOfTracks": dbus.MakeVariant(uint32(12)),
+		"TrackNumber":    dbus.MakeVariant(uint32(3)),
+		"Duration":       dbus.MakeVariant(uint32(215000)),
+	})
+	assert.Equal(t, Track{
+		Title:          "Song",
+		Artist:         "Band",
+		Album:          "Record",
+		Genre:          "Rock",
+		NumberOfTracks: 12,
+		TrackNumber:    3,
+		Duration:       215 * time.Second,
+	}, track)
+
+	// the generated properties hold plain values
+	m := variantMap(dbus.MakeVariant(map[string]interface{}{"Title": "Other"}))
+	assert.Equal(t, "Other", ParseTrack(m).Title)
+	assert.Equal(t, Track{}, ParseTrack(map[string]dbus.Variant{}))
+}
+
+func TestPlayerStateApply(t *testing.T) {
+
+	state := PlayerState{}
+	changed := state.apply(map[string]dbus.Variant{
+		"Status":   dbus.MakeVariant(StatusPaused),
+		"Position": dbus.MakeVariant(uint32(10000)),
+		"Shuffle":  dbus.MakeVariant(ModeOff),
+		"Repeat":   dbus.MakeVariant(ModeAllTracks),
+		"Track": dbus.MakeVariant(map[string]dbus.Variant{
+			"Title":    dbus.MakeVariant("Song"),
+			"Duration": dbus.MakeVariant(uint32(60000)),
+		}),
+	})
+	assert.True(t, changed)
+	assert.Equal(t, StatusPaused, state.Status)
+	assert.Equal(t, ModeAllTracks, state.Repeat)
+	assert.Equal(t, "Song", state.Track.Title)
+	assert.Equal(t, 10*time.Second, state.Elapsed())
+
+	assert.False(t, state.apply(map[string]dbus.Variant{"Unknown": dbus.MakeVariant(1)}))
+
+	// the position is estimated while playing
+	state.apply(map[string]dbus.Variant{"Status": dbus.MakeVariant(StatusPlaying)})
+	state.Updated = state.Updated.Add(-5 * time.Second)
+	elapsed := state.Elapsed()
+	assert.True(t, elapsed >= 15*time.Second && elapsed < 16*time.Second)
+
+	// and bounded by the duration
+	state.Updated = state.Updated.Add(-time.Hour)
+	assert.Equal(t, time.Minute, state.Elapsed())
+}
+
+func TestItemFilter(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{}, ItemFilter{}.ToMap())
+	assert.Equal(t, map[string]interface{}{
+		"Start":      uint32(1),
+		"End":        uint32(10),
+		"Attributes": []string{"Title"},
+	}, ItemFilter{Start: 1, End: 10, Attributes: []string{"Title"}}.ToMap())
+}
+
+func TestNewItem(t *testing.T) {
+	item := newItem("/org/bluez/hci0/dev_00_11_22_33_44_55/player0/Filesystem/item1", map[string]dbus.Variant{
+		"Name":     dbus.MakeVariant("Song"),
+		"Type":     dbus.MakeVariant("audio"),
+		"Playable": dbus.MakeVariant(true),
+		"Metadata": dbus.MakeVariant(map[string]dbus.Variant{
+			"Artist": dbus.MakeVariant("Band"),
+		}),
+	})
+	assert.Equal(t, "Song", item.Name)
+	assert.True(t, item.Playable)
+	assert.False(t, item.IsFolder())
+	assert.Equal(t, "Band", item.Track.Artist)
+}
+
+func TestSortItems(t *testing.T) {
+	prefix := "/org/bluez/hci0/dev_00_11_22_33_44_55/player0/NowPlaying/"
+	items := []Item{}
+	for _, name := range []string{"item10", "folder", "item2", "item1"} {
+		items = append(items, Item{Path: dbus.ObjectPath(prefix + name)})
+	}
+	sortItems(items)
+	paths := []dbus.ObjectPath{}
+	for _, item := range items {
+		paths = append(paths, item.Path)
+	}
+	assert.Equal(t, []dbus.ObjectPath{
+		dbus.ObjectPath(prefix + "item1"),
+		dbus.ObjectPath(prefix + "item2"),
+		dbus.ObjectPath(prefix + "item10"),
+		dbus.ObjectPath(prefix + "folder"),
+	}, paths)
+}
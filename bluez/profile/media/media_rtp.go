@@ -0,0 +1,248 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// RTPHeaderSize is the size of an RTP header without CSRC
+const RTPHeaderSize = 12
+
+// RTPPayloadTypeA2DP is the dynamic payload type used by A2DP
+const RTPPayloadTypeA2DP byte = 96
+
+// ErrShortPacket is returned when a packet is smaller than its headers
+var ErrShortPacket = errors.New("Short packet")
+
+// RTPHeader is the RTP header of the A2DP media packets
+type RTPHeader struct {
+	Version        byte
+	Padding        bool
+	Extension      bool
+	Marker         bool
+	PayloadType    byte
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+	CSRC           []uint32
+}
+
+// ParseRTPHeader decode the header of packet, returning its length
+func ParseRTPHeader(packet []byte) (RTPHeader, int, error) {
+
+	if len(packet) < RTPHeaderSize {
+		return RTPHeader{}, 0, ErrShortPacket
+	}
+
+	h := RTPHeader{
+		Version:        packet[0] >> 6,
+		Padding:        packet[0]&0x20 != 0,
+		Extension:      packet[0]&0x10 != 0,
+		Marker:         packet[1]&0x80 != 0,
+		PayloadType:    packet[1] & 0x7f,
+		SequenceNumber: binary.BigEndian.Uint16(packet[2:]),
+		Timestamp:      binary.BigEndian.Uint32(packet[4:]),
+		SSRC:           binary.BigEndian.Uint32(packet[8:]),
+	}
+	if h.Version != 2 {
+		return RTPHeader{}, 0, fmt.Errorf("RTP version %d not supported", h.Version)
+	}
+
+	n := RTPHeaderSize
+	count := int(packet[0] & 0x0f)
+	if len(packet) < n+4*count {
+		return RTPHeader{}, 0, ErrShortPacket
+	}
+	for i := 0; i < count; i++ {
+		h.CSRC = append(h.CSRC, binary.BigEndian.Uint32(packet[n:]))
+		n += 4
+	}
+
+	// the extension content is skipped
+	if h.Extension {
+		if len(packet) < n+4 {
+			return RTPHeader{}, 0, ErrShortPacket
+		}
+		n += 4 + 4*int(binary.BigEndian.Uint16(packet[n+2:]))
+		if len(packet) < n {
+			return RTPHeader{}, 0, ErrShortPacket
+		}
+	}
+
+	return h, n, nil
+}
+
+// Bytes encode the header, without extension
+func (h RTPHeader) Bytes() []byte {
+	b := make([]byte, RTPHeaderSize+4*len(h.CSRC))
+	version := h.Version
+	if version == 0 {
+		version = 2
+	}
+	b[0] = version<<6 | byte(len(h.CSRC)&0x0f)
+	if h.Padding {
+		b[0] |= 0x20
+	}
+	b[1] = h.PayloadType & 0x7f
+	if h.Marker {
+		b[1] |= 0x80
+	}
+	binary.BigEndian.PutUint16(b[2:], h.SequenceNumber)
+	binary.BigEndian.PutUint32(b[4:], h.Timestamp)
+	binary.BigEndian.PutUint32(b[8:], h.SSRC)
+	for i, csrc := range h.CSRC {
+		binary.BigEndian.PutUint32(b[RTPHeaderSize+4*i:], csrc)
+	}
+	return b
+}
+
+// SBCPayloadHeader is the header preceding the SBC frames of a packet
+type SBCPayloadHeader struct {
+	Fragmented bool
+	Start      bool
+	Last       bool
+	// Frames is the number of frames, or the fragments left when fragmented
+	Frames byte
+}
+
+// ParseSBCPayloadHeader decode the SBC payload header
+func ParseSBCPayloadHeader(b byte) SBCPayloadHeader {
+	return SBCPayloadHeader{
+		Fragmented: b&0x80 != 0,
+		Start:      b&0x40 != 0,
+		Last:       b&0x20 != 0,
+		Frames:     b & 0x0f,
+	}
+}
+
+// Byte encode the SBC payload header
+func (h SBCPayloadHeader) Byte() byte {
+	b := h.Frames & 0x0f
+	if h.Fragmented {
+		b |= 0x80
+	}
+	if h.Start {
+		b |= 0x40
+	}
+	if h.Last {
+		b |= 0x20
+	}
+	return b
+}
+
+// SBCSyncWord start every SBC frame
+const SBCSyncWord byte = 0x9c
+
+const sbcFrameHeaderSize = 4
+
+// SBCFrameHeader is the header of an SBC frame, the masks use the
+// SBCCapabilities values
+type SBCFrameHeader struct {
+	Frequency        byte
+	BlockLength      byte
+	ChannelMode      byte
+	AllocationMethod byte
+	Subbands         byte
+	Bitpool          byte
+	CRC              byte
+}
+
+var (
+	sbcFrequencies  = []byte{SBCFrequency16000, SBCFrequency32000, SBCFrequency44100, SBCFrequency48000}
+	sbcBlockLengths = []byte{SBCBlockLength4, SBCBlockLength8, SBCBlockLength12, SBCBlockLength16}
+	sbcChannelModes = []byte{SBCChannelModeMono, SBCChannelModeDualChannel, SBCChannelModeStereo, SBCChannelModeJointStereo}
+)
+
+// ParseSBCFrameHeader decode the header of the SBC frame starting b
+func ParseSBCFrameHeader(b []byte) (SBCFrameHeader, error) {
+	if len(b) < sbcFrameHeaderSize {
+		return SBCFrameHeader{}, ErrShortPacket
+	}
+	if b[0] != SBCSyncWord {
+		return SBCFrameHeader{}, fmt.Errorf("Invalid SBC sync word %#x", b[0])
+	}
+	h := SBCFrameHeader{
+		Frequency:        sbcFrequencies[b[1]>>6],
+		BlockLength:      sbcBlockLengths[(b[1]>>4)&0x03],
+		ChannelMode:      sbcChannelModes[(b[1]>>2)&0x03],
+		AllocationMethod: SBCAllocationLoudness,
+		Subbands:         SBCSubbands4,
+		Bitpool:          b[2],
+		CRC:              b[3],
+	}
+	if b[1]&0x02 != 0 {
+		h.AllocationMethod = SBCAllocationSNR
+	}
+	if b[1]&0x01 != 0 {
+		h.Subbands = SBCSubbands8
+	}
+	return h, nil
+}
+
+// Config return the header parameters as a configuration
+func (h SBCFrameHeader) Config() SBCCapabilities {
+	return SBCCapabilities{
+		Frequencies:       h.Frequency,
+		ChannelModes:      h.ChannelMode,
+		BlockLengths:      h.BlockLength,
+		Subbands:          h.Subbands,
+		AllocationMethods: h.AllocationMethod,
+		MinBitpool:        h.Bitpool,
+		MaxBitpool:        h.Bitpool,
+	}
+}
+
+// FrameLength return the size of the frames encoded with bitpool
+func (c SBCCapabilities) FrameLength(bitpool byte) int {
+
+	subbands := c.SubbandCount()
+	blocks := c.Blocks()
+	channels := c.Channels()
+	bp := int(bitpool)
+
+	length := 4 + (4*subbands*channels)/8
+	switch c.ChannelModes {
+	case SBCChannelModeMono, SBCChannelModeDualChannel:
+		length += (blocks*channels*bp + 7) / 8
+	case SBCChannelModeStereo:
+		length += (blocks*bp + 7) / 8
+	default:
+		length += (subbands + blocks*bp + 7) / 8
+	}
+	return length
+}
+
+// Samples return the number of samples per channel in a frame
+func (c SBCCapabilities) Samples() int {
+	return c.Blocks() * c.SubbandCount()
+}
+
+// SplitSBCFrames split the payload of a non fragmented packet in frames
+func SplitSBCFrames(payload []byte) ([][]byte, error) {
+
+	if len(payload) < 1 {
+		return nil, ErrShortPacket
+	}
+	ph := ParseSBCPayloadHeader(payload[0])
+	if ph.Fragmented {
+		return nil, errors.New("Fragmented SBC frames are not supported")
+	}
+
+	frames := [][]byte{}
+	data := payload[1:]
+	for i := 0; i < int(ph.Frames); i++ {
+		h, err := ParseSBCFrameHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		length := h.Config().FrameLength(h.Bitpool)
+		if len(data) < length {
+			return nil, ErrShortPacket
+		}
+		frames = append(frames, data[:length])
+		data = data[length:]
+	}
+
+	return frames, nil
+}
@@ -0,0 +1,238 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A2DP profile UUIDs
+const (
+	A2DPSourceUUID = "0000110a-0000-1000-8000-00805f9b34fb"
+	A2DPSinkUUID   = "0000110b-0000-1000-8000-00805f9b34fb"
+)
+
+// A2DP codec IDs
+const (
+	CodecSBC    byte = 0x00
+	CodecMPEG24 byte = 0x02
+)
+
+// SBC sampling frequencies
+const (
+	SBCFrequency16000 byte = 0x08
+	SBCFrequency32000 byte = 0x04
+	SBCFrequency44100 byte = 0x02
+	SBCFrequency48000 byte = 0x01
+)
+
+// SBC channel modes
+const (
+	SBCChannelModeMono        byte = 0x08
+	SBCChannelModeDualChannel byte = 0x04
+	SBCChannelModeStereo      byte = 0x02
+	SBCChannelModeJointStereo byte = 0x01
+)
+
+// SBC block lengths
+const (
+	SBCBlockLength4  byte = 0x08
+	SBCBlockLength8  byte = 0x04
+	SBCBlockLength12 byte = 0x02
+	SBCBlockLength16 byte = 0x01
+)
+
+// SBC subbands
+const (
+	SBCSubbands4 byte = 0x02
+	SBCSubbands8 byte = 0x01
+)
+
+// SBC allocation methods
+const (
+	SBCAllocationSNR      byte = 0x02
+	SBCAllocationLoudness byte = 0x01
+)
+
+// SBC bitpool range
+const (
+	SBCMinBitpool byte = 2
+	SBCMaxBitpool byte = 250
+)
+
+// ErrNoConfiguration is returned when the capabilities have nothing in common
+var ErrNoConfiguration = errors.New("No matching configuration")
+
+// SBCCapabilities are the SBC codec capabilities, or a configuration when a
+// single bit is set in each mask. The masks use the BlueZ a2dp-codecs values.
+type SBCCapabilities struct {
+	Frequencies       byte
+	ChannelModes      byte
+	BlockLengths      byte
+	Subbands          byte
+	AllocationMethods byte
+	MinBitpool        byte
+	MaxBitpool        byte
+}
+
+// DefaultSBCCapabilities support every SBC configuration
+func DefaultSBCCapabilities() SBCCapabilities {
+	return SBCCapabilities{
+		Frequencies:       SBCFrequency16000 | SBCFrequency32000 | SBCFrequency44100 | SBCFrequency48000,
+		ChannelModes:      SBCChannelModeMono | SBCChannelModeDualChannel | SBCChannelModeStereo | SBCChannelModeJointStereo,
+		BlockLengths:      SBCBlockLength4 | SBCBlockLength8 | SBCBlockLength12 | SBCBlockLength16,
+		Subbands:          SBCSubbands4 | SBCSubbands8,
+		AllocationMethods: SBCAllocationSNR | SBCAllocationLoudness,
+		MinBitpool:        SBCMinBitpool,
+		MaxBitpool:        64,
+	}
+}
+
+// ParseSBCCapabilities decode the 4 bytes SBC capabilities
+func ParseSBCCapabilities(b []byte) (SBCCapabilities, error) {
+	if len(b) != 4 {
+		return SBCCapabilities{}, fmt.Errorf("SBC capabilities: invalid length %d", len(b))
+	}
+	return SBCCapabilities{
+		Frequencies:       b[0] >> 4,
+		ChannelModes:      b[0] & 0x0f,
+		BlockLengths:      b[1] >> 4,
+		Subbands:          (b[1] >> 2) & 0x03,
+		AllocationMethods: b[1] & 0x03,
+		MinBitpool:        b[2],
+		MaxBitpool:        b[3],
+	}, nil
+}
+
+// Bytes encode the capabilities
+func (c SBCCapabilities) Bytes() []byte {
+	return []byte{
+		c.Frequencies<<4 | c.ChannelModes&0x0f,
+		c.BlockLengths<<4 | (c.Subbands&0x03)<<2 | c.AllocationMethods&0x03,
+		c.MinBitpool,
+		c.MaxBitpool,
+	}
+}
+
+// SampleRate return the configured sampling frequency
+func (c SBCCapabilities) SampleRate() int {
+	switch {
+	case c.Frequencies&SBCFrequency48000 != 0:
+		return 48000
+	case c.Frequencies&SBCFrequency44100 != 0:
+		return 44100
+	case c.Frequencies&SBCFrequency32000 != 0:
+		return 32000
+	case c.Frequencies&SBCFrequency16000 != 0:
+		return 16000
+	}
+	return 0
+}
+
+// Channels return the configured number of channels
+func (c SBCCapabilities) Channels() int {
+	if c.ChannelModes&SBCChannelModeMono != 0 {
+		return 1
+	}
+	if c.ChannelModes != 0 {
+		return 2
+	}
+	return 0
+}
+
+// Blocks return the configured block length
+func (c SBCCapabilities) Blocks() int {
+	switch {
+	case c.BlockLengths&SBCBlockLength16 != 0:
+		return 16
+	case c.BlockLengths&SBCBlockLength12 != 0:
+		return 12
+	case c.BlockLengths&SBCBlockLength8 != 0:
+		return 8
+	case c.BlockLengths&SBCBlockLength4 != 0:
+		return 4
+	}
+	return 0
+}
+
+// SubbandCount return the configured number of subbands
+func (c SBCCapabilities) SubbandCount() int {
+	switch {
+	case c.Subbands&SBCSubbands8 != 0:
+		return 8
+	case c.Subbands&SBCSubbands4 != 0:
+		return 4
+	}
+	return 0
+}
+
+// first return the first bit of mask set in preference order
+func first(mask byte, preference ...byte) byte {
+	for _, bit := range preference {
+		if mask&bit != 0 {
+			return bit
+		}
+	}
+	return 0
+}
+
+// sbcMaxBitpool return the bitpool recommended by A2DP for high quality
+func sbcMaxBitpool(frequency, mode byte) byte {
+	mono := mode == SBCChannelModeMono || mode == SBCChannelModeDualChannel
+	switch frequency {
+	case SBCFrequency16000, SBCFrequency32000, SBCFrequency44100:
+		if mono {
+			return 31
+		}
+		return 53
+	default:
+		if mono {
+			return 29
+		}
+		return 51
+	}
+}
+
+// SelectSBC choose the best configuration supported by local and remote
+func SelectSBC(local, remote SBCCapabilities) (SBCCapabilities, error) {
+
+	config := SBCCapabilities{
+		Frequencies: first(local.Frequencies&remote.Frequencies,
+			SBCFrequency48000, SBCFrequency44100, SBCFrequency32000, SBCFrequency16000),
+		ChannelModes: first(local.ChannelModes&remote.ChannelModes,
+			SBCChannelModeJointStereo, SBCChannelModeStereo, SBCChannelModeDualChannel, SBCChannelModeMono),
+		BlockLengths: first(local.BlockLengths&remote.BlockLengths,
+			SBCBlockLength16, SBCBlockLength12, SBCBlockLength8, SBCBlockLength4),
+		Subbands: first(local.Subbands&remote.Subbands,
+			SBCSubbands8, SBCSubbands4),
+		AllocationMethods: first(local.AllocationMethods&remote.AllocationMethods,
+			SBCAllocationLoudness, SBCAllocationSNR),
+	}
+
+	if config.Frequencies == 0 || config.ChannelModes == 0 || config.BlockLengths == 0 ||
+		config.Subbands == 0 || config.AllocationMethods == 0 {
+		return SBCCapabilities{}, ErrNoConfiguration
+	}
+
+	config.MinBitpool = maxByte(SBCMinBitpool, maxByte(local.MinBitpool, remote.MinBitpool))
+	config.MaxBitpool = minByte(sbcMaxBitpool(config.Frequencies, config.ChannelModes),
+		minByte(local.MaxBitpool, remote.MaxBitpool))
+	if config.MinBitpool > config.MaxBitpool {
+		return SBCCapabilities{}, ErrNoConfiguration
+	}
+
+	return config, nil
+}
+
+func minByte(a, b byte) byte {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxByte(a, b byte) byte {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,362 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	log "github.com/sirupsen/logrus"
+)
+
+// Transport states
+const (
+	TransportStateIdle    = "idle"
+	TransportStatePending = "pending"
+	TransportStateActive  = "active"
+)
+
+// ErrPacketTooLarge is returned when writing a packet larger than the MTU
+var ErrPacketTooLarge = errors.New("Packet larger than the write MTU")
+
+// StreamState is the transport state reported on changes
+type StreamState struct {
+	State  string
+	Volume uint16
+	Delay  uint16
+}
+
+// AcquireStream acquire the transport at path, blocking until BlueZ
+// established the stream
+func AcquireStream(path dbus.ObjectPath) (*Stream, error) {
+	return acquireStream(path, false)
+}
+
+// TryAcquireStream acquire the transport at path, failing when it is not
+// pending or active, eg. when the remote device has not started playing
+func TryAcquireStream(path dbus.ObjectPath) (*Stream, error) {
+	return acquireStream(path, true)
+}
+
+func acquireStream(path dbus.ObjectPath, try bool) (*Stream, error) {
+
+	transport, err := NewMediaTransport1(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// watch the changes before acquiring, not to miss the state change
+	signals, err := transport.Client().Register(path, bluez.PropertiesInterface)
+	if err != nil {
+		transport.Close()
+		return nil, err
+	}
+
+	var fd dbus.UnixFD
+	var readMTU, writeMTU uint16
+	if try {
+		fd, readMTU, writeMTU, err = transport.TryAcquire()
+	} else {
+		fd, readMTU, writeMTU, err = transport.Acquire()
+	}
+	if err != nil {
+		bluez.DrainSignals(signals, func() {
+			transport.Client().Unregister(path, bluez.PropertiesInterface, signals)
+		})
+		transport.Close()
+		return nil, err
+	}
+
+	s, err := NewStream(int(fd), int(readMTU), int(writeMTU))
+	if err != nil {
+		syscall.Close(int(fd))
+		bluez.DrainSignals(signals, func() {
+			transport.Release()
+			transport.Client().Unregister(path, bluez.PropertiesInterface, signals)
+		})
+		transport.Close()
+		return nil, err
+	}
+
+	s.transport = transport
+	s.signals = signals
+
+	// the state is read after Acquire changed it
+	props, err := transport.GetProperties()
+	if err == nil {
+		props.Lock()
+		s.state = StreamState{
+			State:  props.State,
+			Volume: props.Volume,
+			Delay:  props.Delay,
+		}
+		props.Unlock()
+	}
+
+	go s.watch()
+
+	return s, nil
+}
+
+// NewStream wrap an acquired transport fd, the stream take ownership of fd
+func NewStream(fd int, readMTU, writeMTU int) (*Stream, error) {
+
+	// a non blocking fd is handled by the runtime poller, enabling deadlines
+	err := syscall.SetNonblock(fd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stream{
+		ReadMTU:  readMTU,
+		WriteMTU: writeMTU,
+		file:     os.NewFile(uintptr(fd), fmt.Sprintf("transport%d", fd)),
+		changes:  make(chan StreamState, 16),
+		done:     make(chan struct{}),
+		ssrc:     1,
+	}, nil
+}
+
+// Stream is an acquired MediaTransport1. Read and Write transfer a single
+// RTP packet each.
+type Stream struct {
+	ReadMTU  int
+	WriteMTU int
+
+	file      *os.File
+	transport *MediaTransport1
+	signals   chan *dbus.Signal
+
+	lock    sync.Mutex
+	state   StreamState
+	changes chan StreamState
+	done    chan struct{}
+	once    sync.Once
+
+	// RTP state of the written packets
+	sequence  uint16
+	timestamp uint32
+	ssrc      uint32
+}
+
+// Transport return the transport object path, empty for a NewStream
+func (s *Stream) Transport() dbus.ObjectPath {
+	if s.transport == nil {
+		return ""
+	}
+	return s.transport.Path()
+}
+
+// State return the last known transport state
+func (s *Stream) State() StreamState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.state
+}
+
+// Changes return the transport state changes, closed by Close
+func (s *Stream) Changes() <-chan StreamState {
+	return s.changes
+}
+
+// SetVolume set the transport volume, from 0 to 127
+func (s *Stream) SetVolume(volume uint16) error {
+	if s.transport == nil {
+		return errors.New("Stream has no transport")
+	}
+	return s.transport.SetVolume(volume)
+}
+
+func (s *Stream) watch() {
+	defer close(s.changes)
+	for {
+		select {
+		case <-s.done:
+			return
+		case sig := <-s.signals:
+			if sig == nil {
+				return
+			}
+			if sig.Name != bluez.PropertiesChanged || sig.Path != s.transport.Path() || len(sig.Body) < 2 {
+				continue
+			}
+			if iface, ok := sig.Body[0].(string); !ok || iface != MediaTransport1Interface {
+				continue
+			}
+			changes, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
+			s.apply(changes)
+		}
+	}
+}
+
+// apply the changed properties, sending the new state
+func (s *Stream) apply(changes map[string]dbus.Variant) {
+	s.lock.Lock()
+	changed := false
+	if v, ok := changes["State"].Value().(string); ok {
+		s.state.State = v
+		changed = true
+	}
+	if v, ok := changes["Volume"].Value().(uint16); ok {
+		s.state.Volume = v
+		changed = true
+	}
+	if v, ok := changes["Delay"].Value().(uint16); ok {
+		s.state.Delay = v
+		changed = true
+	}
+	state := s.state
+	s.lock.Unlock()
+
+	if !changed {
+		return
+	}
+	select {
+	case s.changes <- state:
+	default:
+		log.Debugf("Stream %s: changes channel full", s.Transport())
+	}
+}
+
+// Read a packet, b should be ReadMTU long not to truncate it
+func (s *Stream) Read(b []byte) (int, error) {
+	return s.file.Read(b)
+}
+
+// Write a packet of at most WriteMTU bytes
+func (s *Stream) Write(b []byte) (int, error) {
+	if s.WriteMTU > 0 && len(b) > s.WriteMTU {
+		return 0, ErrPacketTooLarge
+	}
+	return s.file.Write(b)
+}
+
+// ReadPacket read an RTP packet, returning its header and payload
+func (s *Stream) ReadPacket() (RTPHeader, []byte, error) {
+	size := s.ReadMTU
+	if size <= 0 {
+		size = 1024
+	}
+	b := make([]byte, size)
+	n, err := s.Read(b)
+	if err != nil {
+		return RTPHeader{}, nil, err
+	}
+	h, hlen, err := ParseRTPHeader(b[:n])
+	if err != nil {
+		return RTPHeader{}, nil, err
+	}
+	return h, b[hlen:n], nil
+}
+
+// WritePacket write payload in an RTP packet, then advance the timestamp
+// of samples
+func (s *Stream) WritePacket(payload []byte, samples uint32) error {
+	h := RTPHeader{
+		Version:        2,
+		PayloadType:    RTPPayloadTypeA2DP,
+		SequenceNumber: s.sequence,
+		Timestamp:      s.timestamp,
+		SSRC:           s.ssrc,
+	}
+	_, err := s.Write(append(h.Bytes(), payload...))
+	if err != nil {
+		return err
+	}
+	s.sequence++
+	s.timestamp += samples
+	return nil
+}
+
+// ReadSBCFrames read a packet and split its SBC frames
+func (s *Stream) ReadSBCFrames() ([][]byte, error) {
+	_, payload, err := s.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	return SplitSBCFrames(payload)
+}
+
+// WriteSBCFrames write the SBC frames in as few packets as the MTU allows,
+// samples is the number of samples per frame
+func (s *Stream) WriteSBCFrames(frames [][]byte, samples int) error {
+
+	for len(frames) > 0 {
+		size := RTPHeaderSize + 1
+		count := 0
+		for count < len(frames) && count < 15 {
+			if s.WriteMTU > 0 && size+len(frames[count]) > s.WriteMTU {
+				break
+			}
+			size += len(frames[count])
+			count++
+		}
+		if count == 0 {
+			return ErrPacketTooLarge
+		}
+
+		payload := make([]byte, 0, size-RTPHeaderSize)
+		payload = append(payload, SBCPayloadHeader{Frames: byte(count)}.Byte())
+		for _, frame := range frames[:count] {
+			payload = append(payload, frame...)
+		}
+
+		err := s.WritePacket(payload, uint32(count*samples))
+		if err != nil {
+			return err
+		}
+		frames = frames[count:]
+	}
+
+	return nil
+}
+
+func (s *Stream) SetDeadline(t time.Time) error {
+	return s.file.SetDeadline(t)
+}
+
+func (s *Stream) SetReadDeadline(t time.Time) error {
+	return s.file.SetReadDeadline(t)
+}
+
+func (s *Stream) SetWriteDeadline(t time.Time) error {
+	return s.file.SetWriteDeadline(t)
+}
+
+// Close the stream and release the transport
+func (s *Stream) Close() error {
+
+	err := s.file.Close()
+
+	s.once.Do(func() {
+		close(s.done)
+		if s.transport == nil {
+			close(s.changes)
+			return
+		}
+
+		// Release emits the state change, watch may be gone already
+		path := s.transport.Path()
+		bluez.DrainSignals(s.signals, func() {
+			rerr := s.transport.Release()
+			if rerr != nil {
+				// the transport may be gone with the device
+				log.Debugf("Stream %s: Release: %s", path, rerr)
+			}
+			uerr := s.transport.Client().Unregister(path, bluez.PropertiesInterface, s.signals)
+			if uerr != nil {
+				log.Warnf("Stream %s: %s", path, uerr)
+			}
+		})
+		s.transport.Close()
+	})
+
+	return err
+}
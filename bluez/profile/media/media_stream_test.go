@@ -0,0 +1,116 @@
+package media
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+// a joint stereo, 44.1 kHz, 16 blocks, 8 subbands, loudness, bitpool 53 frame
+func testSBCFrame() []byte {
+	config := SBCCapabilities{
+		Frequencies:       SBCFrequency44100,
+		ChannelModes:      SBCChannelModeJointStereo,
+		BlockLengths:      SBCBlockLength16,
+		Subbands:          SBCSubbands8,
+		AllocationMethods: SBCAllocationLoudness,
+	}
+	frame := make([]byte, config.FrameLength(53))
+	frame[0] = SBCSyncWord
+	frame[1] = 0xbd
+	frame[2] = 53
+	return frame
+}
+
+func TestRTPHeader(t *testing.T) {
+
+	h := RTPHeader{
+		Version:        2,
+		Marker:         true,
+		PayloadType:    RTPPayloadTypeA2DP,
+		SequenceNumber: 0x1234,
+		Timestamp:      0x01020304,
+		SSRC:           1,
+		CSRC:           []uint32{7},
+	}
+	b := h.Bytes()
+	assert.Equal(t, []byte{0x81, 0xe0, 0x12, 0x34, 1, 2, 3, 4, 0, 0, 0, 1, 0, 0, 0, 7}, b)
+
+	parsed, n, err := ParseRTPHeader(append(b, 0xff))
+	assert.Nil(t, err)
+	assert.Equal(t, 16, n)
+	assert.Equal(t, h, parsed)
+
+	_, _, err = ParseRTPHeader(b[:8])
+	assert.Equal(t, ErrShortPacket, err)
+}
+
+func TestSBCFrames(t *testing.T) {
+
+	frame := testSBCFrame()
+	assert.Len(t, frame, 119)
+
+	h, err := ParseSBCFrameHeader(frame)
+	assert.Nil(t, err)
+	assert.Equal(t, SBCFrequency44100, h.Frequency)
+	assert.Equal(t, SBCChannelModeJointStereo, h.ChannelMode)
+	assert.Equal(t, 128, h.Config().Samples())
+
+	ph := SBCPayloadHeader{Frames: 2}
+	assert.Equal(t, ph, ParseSBCPayloadHeader(ph.Byte()))
+
+	payload := append([]byte{ph.Byte()}, frame...)
+	payload = append(payload, frame...)
+	frames, err := SplitSBCFrames(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{frame, frame}, frames)
+
+	_, err = SplitSBCFrames(payload[:100])
+	assert.NotNil(t, err)
+}
+
+func TestStream(t *testing.T) {
+
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET, 0)
+	assert.Nil(t, err)
+
+	// room for 5 frames per packet
+	mtu := RTPHeaderSize + 1 + 5*119
+	local, err := NewStream(fds[0], mtu, mtu)
+	assert.Nil(t, err)
+	remote, err := NewStream(fds[1], mtu, mtu)
+	assert.Nil(t, err)
+	defer remote.Close()
+
+	frame := testSBCFrame()
+	frames := [][]byte{}
+	for i := 0; i < 7; i++ {
+		frames = append(frames, frame)
+	}
+	err = local.WriteSBCFrames(frames, 128)
+	assert.Nil(t, err)
+
+	h, payload, err := remote.ReadPacket()
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(0), h.SequenceNumber)
+	assert.Equal(t, uint32(0), h.Timestamp)
+	assert.Equal(t, byte(5), ParseSBCPayloadHeader(payload[0]).Frames)
+
+	read, err := remote.ReadSBCFrames()
+	assert.Nil(t, err)
+	assert.Len(t, read, 2)
+
+	err = local.WritePacket(make([]byte, mtu), 0)
+	assert.Equal(t, ErrPacketTooLarge, err)
+
+	local.apply(map[string]dbus.Variant{"State": dbus.MakeVariant(TransportStateActive)})
+	state := <-local.Changes()
+	assert.Equal(t, TransportStateActive, state.State)
+	assert.Equal(t, TransportStateActive, local.State().State)
+
+	assert.Nil(t, local.Close())
+	_, ok := <-local.Changes()
+	assert.False(t, ok)
+}
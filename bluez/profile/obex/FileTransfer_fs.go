@@ -0,0 +1,489 @@
+//go:build go1.16
+// +build go1.16
+
+package obex
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// FS return a filesystem over the session. Paths are slash separated and
+// relative to the root folder, the transfers are cancelled when ctx is done.
+func (s *FileTransferSession) FS(ctx context.Context) *FileTransferFS {
+	return &FileTransferFS{
+		session: s,
+		ctx:     ctx,
+	}
+}
+
+// FileTransferFS implement fs.FS, fs.ReadDirFS and fs.StatFS over an ftp
+// session, plus write operations. Files are downloaded on Open, the calls
+// are serialized as the session has a current folder.
+type FileTransferFS struct {
+	// TempDir hold the downloaded files, the default temp dir if empty
+	TempDir string
+
+	session *FileTransferSession
+	ctx     context.Context
+
+	lock sync.Mutex
+	// cwd is the current folder, unknown if empty
+	cwd string
+}
+
+// remoteInfo describe a ListFolder entry
+type remoteInfo struct {
+	name    string
+	dir     bool
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	props   map[string]dbus.Variant
+}
+
+func newRemoteInfo(props map[string]dbus.Variant) *remoteInfo {
+
+	i := &remoteInfo{props: props}
+	i.name, _ = props["Name"].Value().(string)
+	kind, _ := props["Type"].Value().(string)
+	i.dir = kind == "folder"
+	if size, ok := props["Size"].Value().(uint64); ok {
+		i.size = int64(size)
+	}
+	if modified, ok := props["Modified"].Value().(uint64); ok && modified > 0 {
+		i.modTime = time.Unix(int64(modified), 0)
+	}
+
+	// Permission list the user permissions, eg. RWD
+	perm, ok := props["Permission"].Value().(string)
+	if !ok {
+		perm = "RW"
+	}
+	if strings.Contains(perm, "R") {
+		i.mode |= 0444
+	}
+	if strings.Contains(perm, "W") {
+		i.mode |= 0200
+	}
+	if i.dir {
+		i.mode |= fs.ModeDir | 0111
+	}
+
+	return i
+}
+
+func (i *remoteInfo) Name() string               { return i.name }
+func (i *remoteInfo) Size() int64                { return i.size }
+func (i *remoteInfo) Mode() fs.FileMode          { return i.mode }
+func (i *remoteInfo) ModTime() time.Time         { return i.modTime }
+func (i *remoteInfo) IsDir() bool                { return i.dir }
+func (i *remoteInfo) Sys() interface{}           { return i.props }
+func (i *remoteInfo) Type() fs.FileMode          { return i.mode.Type() }
+func (i *remoteInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// remoteDir is an opened folder
+type remoteDir struct {
+	info    *remoteInfo
+	entries []fs.DirEntry
+}
+
+func (d *remoteDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *remoteDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *remoteDir) Close() error {
+	return nil
+}
+
+func (d *remoteDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// remoteFile is a downloaded file, removed on Close
+type remoteFile struct {
+	*os.File
+	info *remoteInfo
+}
+
+func (f *remoteFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *remoteFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+	return err
+}
+
+// Open download the file name, or list the folder name
+func (f *FileTransferFS) Open(name string) (fs.File, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if info.dir {
+		entries, err := f.readDir(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &remoteDir{info: info, entries: entries}, nil
+	}
+
+	tmp, err := ioutil.TempFile(f.TempDir, "obex")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+
+	err = f.get(tmp.Name(), name)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	file, err := os.Open(tmp.Name())
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return &remoteFile{File: file, info: info}, nil
+}
+
+// ReadDir list the folder name sorted by file name
+func (f *FileTransferFS) ReadDir(name string) ([]fs.DirEntry, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return entries, nil
+}
+
+// Stat return the ListFolder entry of name
+func (f *FileTransferFS) Stat(name string) (fs.FileInfo, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// Get download the file name to the local targetfile
+func (f *FileTransferFS) Get(name, targetfile string) error {
+
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "get", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.get(targetfile, name)
+	if err != nil {
+		return &fs.PathError{Op: "get", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Put upload the local sourcefile as name
+func (f *FileTransferFS) Put(sourcefile, name string) error {
+
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "put", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.chdir(path.Dir(name))
+	if err == nil {
+		_, err = f.session.Put(f.ctx, sourcefile, path.Base(name))
+	}
+	if err != nil {
+		return &fs.PathError{Op: "put", Path: name, Err: err}
+	}
+	return nil
+}
+
+// WriteFile upload data as name
+func (f *FileTransferFS) WriteFile(name string, data []byte) error {
+
+	tmp, err := ioutil.TempFile(f.TempDir, "obex")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return f.Put(tmp.Name(), name)
+}
+
+// Mkdir create the folder name, its parent must exist
+func (f *FileTransferFS) Mkdir(name string) error {
+
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.chdir(path.Dir(name))
+	if err == nil {
+		err = f.session.CreateFolder(path.Base(name))
+		// obexd enter the created folder
+		f.cwd = ""
+		if err == nil {
+			f.cwd = name
+		}
+	}
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// MkdirAll create the folder name and its missing parents
+func (f *FileTransferFS) MkdirAll(name string) error {
+
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil
+	}
+
+	info, err := f.Stat(name)
+	if err == nil {
+		if !info.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+		return nil
+	}
+
+	err = f.MkdirAll(path.Dir(name))
+	if err != nil {
+		return err
+	}
+	return f.Mkdir(name)
+}
+
+// Remove delete the file or empty folder name
+func (f *FileTransferFS) Remove(name string) error {
+
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.chdir(path.Dir(name))
+	if err == nil {
+		err = f.session.Delete(path.Base(name))
+	}
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Rename move oldname to newname on the device
+func (f *FileTransferFS) Rename(oldname, newname string) error {
+	return f.action("rename", oldname, newname, f.session.MoveFile)
+}
+
+// Copy copy oldname to newname on the device
+func (f *FileTransferFS) Copy(oldname, newname string) error {
+	return f.action("copy", oldname, newname, f.session.CopyFile)
+}
+
+func (f *FileTransferFS) action(op, oldname, newname string, fn func(string, string) error) error {
+
+	if !fs.ValidPath(oldname) || oldname == "." {
+		return &fs.PathError{Op: op, Path: oldname, Err: fs.ErrInvalid}
+	}
+	if !fs.ValidPath(newname) || newname == "." {
+		return &fs.PathError{Op: op, Path: newname, Err: fs.ErrInvalid}
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	dir := path.Dir(oldname)
+	err := f.chdir(dir)
+	if err == nil {
+		// the target is relative to the current folder
+		err = fn(path.Base(oldname), relativePath(dir, newname))
+	}
+	if err != nil {
+		return &fs.PathError{Op: op, Path: oldname, Err: err}
+	}
+	return nil
+}
+
+// chdir change the current folder to dir, relative to the root
+func (f *FileTransferFS) chdir(dir string) error {
+	if f.cwd == dir {
+		return nil
+	}
+	f.cwd = ""
+	folder := "/"
+	if dir != "." {
+		folder += dir
+	}
+	err := f.session.ChangeFolder(folder)
+	if err != nil {
+		return err
+	}
+	f.cwd = dir
+	return nil
+}
+
+func (f *FileTransferFS) list(dir string) ([]*remoteInfo, error) {
+
+	err := f.chdir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// the generated ListFolder cannot store the aa{sv} reply
+	var list []map[string]dbus.Variant
+	err = f.session.FileTransfer.Client().Call("ListFolder", 0).Store(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []*remoteInfo{}
+	for _, props := range list {
+		info := newRemoteInfo(props)
+		if info.name == "" || info.name == "." || info.name == ".." {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].name < infos[j].name
+	})
+
+	return infos, nil
+}
+
+func (f *FileTransferFS) readDir(name string) ([]fs.DirEntry, error) {
+	infos, err := f.list(name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = info
+	}
+	return entries, nil
+}
+
+func (f *FileTransferFS) stat(name string) (*remoteInfo, error) {
+
+	if name == "." {
+		return &remoteInfo{name: ".", dir: true, mode: fs.ModeDir | 0755}, nil
+	}
+
+	infos, err := f.list(path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	base := path.Base(name)
+	for _, info := range infos {
+		if info.name == base {
+			return info, nil
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+func (f *FileTransferFS) get(targetfile, name string) error {
+	err := f.chdir(path.Dir(name))
+	if err != nil {
+		return err
+	}
+	_, err = f.session.Get(f.ctx, targetfile, path.Base(name))
+	return err
+}
+
+// relativePath return the path of target relative to the folder dir, both
+// relative to the root
+func relativePath(dir, target string) string {
+	if dir == "." {
+		return target
+	}
+	from := strings.Split(dir, "/")
+	to := strings.Split(target, "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	parts := []string{}
+	for range from[i:] {
+		parts = append(parts, "..")
+	}
+	return strings.Join(append(parts, to[i:]...), "/")
+}
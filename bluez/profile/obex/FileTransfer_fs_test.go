@@ -0,0 +1,124 @@
+//go:build go1.16
+// +build go1.16
+
+package obex
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTransferFS(t *testing.T) {
+	var fsys fs.FS = &FileTransferFS{}
+	_, ok := fsys.(fs.ReadDirFS)
+	assert.True(t, ok)
+	_, ok = fsys.(fs.StatFS)
+	assert.True(t, ok)
+
+	_, err := fsys.Open("../etc")
+	assert.True(t, errors.Is(err, fs.ErrInvalid))
+}
+
+func TestRemoteInfo(t *testing.T) {
+
+	info := newRemoteInfo(map[string]dbus.Variant{
+		"Name":       dbus.MakeVariant("DCIM"),
+		"Type":       dbus.MakeVariant("folder"),
+		"Permission": dbus.MakeVariant("RWD"),
+		"Modified":   dbus.MakeVariant(uint64(1500000000)),
+	})
+	assert.Equal(t, "DCIM", info.Name())
+	assert.True(t, info.IsDir())
+	assert.Equal(t, fs.ModeDir, info.Type())
+	assert.Equal(t, fs.ModeDir|0755, info.Mode())
+	assert.Equal(t, time.Unix(1500000000, 0), info.ModTime())
+
+	info = newRemoteInfo(map[string]dbus.Variant{
+		"Name":       dbus.MakeVariant("photo.jpg"),
+		"Type":       dbus.MakeVariant("file"),
+		"Size":       dbus.MakeVariant(uint64(1024)),
+		"Permission": dbus.MakeVariant("R"),
+	})
+	assert.False(t, info.IsDir())
+	assert.Equal(t, int64(1024), info.Size())
+	assert.Equal(t, fs.FileMode(0444), info.Mode())
+	assert.True(t, info.ModTime().IsZero())
+}
+
+func TestRemoteDir(t *testing.T) {
+
+	a := &remoteInfo{name: "a"}
+	b := &remoteInfo{name: "b"}
+	d := &remoteDir{info: &remoteInfo{name: "."}, entries: []fs.DirEntry{a, b}}
+
+	entries, err := d.ReadDir(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []fs.DirEntry{a}, entries)
+	entries, err = d.ReadDir(5)
+	assert.Nil(t, err)
+	assert.Equal(t, []fs.DirEntry{b}, entries)
+	_, err = d.ReadDir(1)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = d.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+}
+
+func TestRelativePath(t *testing.T) {
+	assert.Equal(t, "b.txt", relativePath(".", "b.txt"))
+	assert.Equal(t, "b.txt", relativePath("x", "x/b.txt"))
+	assert.Equal(t, "../b.txt", relativePath("x", "b.txt"))
+	assert.Equal(t, "../y/b.txt", relativePath("x/z", "x/y/b.txt"))
+	assert.Equal(t, "../../y", relativePath("a/b", "y"))
+}
+
+func TestFileTransferFSOpen(t *testing.T) {
+
+	newFakeObexd(t, map[string]string{
+		"/a.txt":      "first",
+		"/b.txt":      "second",
+		"/docs/c.txt": "third",
+	})
+
+	ctx := context.Background()
+	s, err := OpenFileTransfer(ctx, "00:11:22:33:44:55", SessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fsys := s.FS(ctx)
+
+	// every file is downloaded over the same session
+	for name, content := range map[string]string{"a.txt": "first", "b.txt": "second"} {
+		b, err := fs.ReadFile(fsys, name)
+		if assert.Nil(t, err, name) {
+			assert.Equal(t, content, string(b))
+		}
+	}
+
+	files := []string{}
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, name+":"+string(b))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a.txt:first", "b.txt:second", "docs/c.txt:third"}, files)
+}
@@ -0,0 +1,285 @@
+package obex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// bMessage types
+const (
+	BMessageSMSGSM  = "SMS_GSM"
+	BMessageSMSCDMA = "SMS_CDMA"
+	BMessageMMS     = "MMS"
+	BMessageEmail   = "EMAIL"
+	BMessageIM      = "IM"
+)
+
+// bMessage status
+const (
+	BMessageRead   = "READ"
+	BMessageUnread = "UNREAD"
+)
+
+// BMessageBody is the bBody of a message. Content hold the text of SMS, or
+// the RFC 2822 message of MMS and emails.
+type BMessageBody struct {
+	PartID   string
+	Encoding string
+	Charset  string
+	Language string
+	Content  string
+}
+
+// BMessage is a MAP message in the bMessage format
+type BMessage struct {
+	Version string
+	Status  string
+	Type    string
+	Folder  string
+	// Originator is the sender, it may be omitted when pushing
+	Originator *VCard
+	Recipients []VCard
+	Body       BMessageBody
+}
+
+// NewSMS return a UTF-8 SMS for number, to be pushed to the outbox
+func NewSMS(number, text string) *BMessage {
+	return &BMessage{
+		Version: "1.0",
+		Status:  BMessageRead,
+		Type:    BMessageSMSGSM,
+		Folder:  "TELECOM/MSG/OUTBOX",
+		Recipients: []VCard{
+			{
+				Version: "2.1",
+				Phones:  []VCardPhone{{Number: number}},
+			},
+		},
+		Body: BMessageBody{
+			Charset: "UTF-8",
+			Content: text,
+		},
+	}
+}
+
+// ParseBMessage parse a message downloaded with Message1.Get
+func ParseBMessage(r io.Reader) (*BMessage, error) {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	m := &BMessage{}
+	started := false
+	ended := false
+	envelopes := 0
+	var vcard []string
+	var content []string
+	inMsg := false
+	inBody := false
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		// the message content is kept verbatim
+		if inMsg {
+			if line == "END:MSG" {
+				inMsg = false
+				continue
+			}
+			content = append(content, line)
+			continue
+		}
+
+		if vcard != nil {
+			vcard = append(vcard, line)
+			if !strings.EqualFold(line, "END:VCARD") {
+				continue
+			}
+			cards, err := ParseVCards(strings.NewReader(strings.Join(vcard, "\r\n")))
+			vcard = nil
+			if err != nil {
+				return nil, err
+			}
+			if len(cards) == 0 {
+				continue
+			}
+			if envelopes == 0 {
+				m.Originator = &cards[0]
+			} else {
+				m.Recipients = append(m.Recipients, cards[0])
+			}
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("Invalid bMessage line: %s", line)
+		}
+		key, value := strings.ToUpper(line[:i]), line[i+1:]
+
+		if !started {
+			if key != "BEGIN" || value != "BMSG" {
+				return nil, fmt.Errorf("Missing BEGIN:BMSG")
+			}
+			started = true
+			continue
+		}
+
+		switch key {
+		case "BEGIN":
+			switch value {
+			case "VCARD":
+				vcard = []string{line}
+			case "BENV":
+				envelopes++
+			case "BBODY":
+				inBody = true
+			case "MSG":
+				inMsg = true
+				if len(content) > 0 {
+					content = append(content, "")
+				}
+			}
+		case "END":
+			switch value {
+			case "BBODY":
+				inBody = false
+			case "BMSG":
+				ended = true
+			}
+		case "VERSION":
+			m.Version = value
+		case "STATUS":
+			m.Status = value
+		case "TYPE":
+			m.Type = value
+		case "FOLDER":
+			m.Folder = value
+		case "PARTID":
+			m.Body.PartID = value
+		case "ENCODING":
+			m.Body.Encoding = value
+		case "CHARSET":
+			m.Body.Charset = value
+		case "LANGUAGE":
+			m.Body.Language = value
+		}
+		if ended {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !ended || inMsg || inBody || vcard != nil {
+		return nil, fmt.Errorf("Truncated bMessage")
+	}
+
+	m.Body.Content = strings.Join(content, "\r\n")
+
+	return m, nil
+}
+
+// Bytes encode the message in the bMessage format
+func (m *BMessage) Bytes() []byte {
+
+	buf := new(bytes.Buffer)
+	crlf := func(format string, args ...interface{}) {
+		fmt.Fprintf(buf, format+"\r\n", args...)
+	}
+
+	version := m.Version
+	if version == "" {
+		version = "1.0"
+	}
+	status := m.Status
+	if status == "" {
+		status = BMessageRead
+	}
+
+	crlf("BEGIN:BMSG")
+	crlf("VERSION:%s", version)
+	crlf("STATUS:%s", status)
+	crlf("TYPE:%s", m.Type)
+	crlf("FOLDER:%s", m.Folder)
+	if m.Originator != nil {
+		writeVCard(buf, m.Originator)
+	}
+	crlf("BEGIN:BENV")
+	for i := range m.Recipients {
+		writeVCard(buf, &m.Recipients[i])
+	}
+	crlf("BEGIN:BBODY")
+	if m.Body.PartID != "" {
+		crlf("PARTID:%s", m.Body.PartID)
+	}
+	if m.Body.Encoding != "" {
+		crlf("ENCODING:%s", m.Body.Encoding)
+	}
+	if m.Body.Charset != "" {
+		crlf("CHARSET:%s", m.Body.Charset)
+	}
+	if m.Body.Language != "" {
+		crlf("LANGUAGE:%s", m.Body.Language)
+	}
+
+	// the length counts the message delimiters
+	content := strings.Replace(strings.Replace(m.Body.Content, "\r\n", "\n", -1), "\n", "\r\n", -1)
+	msg := "BEGIN:MSG\r\n" + content + "\r\nEND:MSG\r\n"
+	crlf("LENGTH:%d", len(msg))
+	buf.WriteString(msg)
+
+	crlf("END:BBODY")
+	crlf("END:BENV")
+	crlf("END:BMSG")
+
+	return buf.Bytes()
+}
+
+// writeVCard encode the vCard fields used by bMessage envelopes
+func writeVCard(w io.Writer, c *VCard) {
+
+	version := c.Version
+	if version == "" {
+		version = "2.1"
+	}
+
+	fmt.Fprintf(w, "BEGIN:VCARD\r\n")
+	fmt.Fprintf(w, "VERSION:%s\r\n", version)
+	fmt.Fprintf(w, "N:%s\r\n", strings.Join([]string{
+		escapeVCardText(c.Name.Family),
+		escapeVCardText(c.Name.Given),
+		escapeVCardText(c.Name.Additional),
+		escapeVCardText(c.Name.Prefix),
+		escapeVCardText(c.Name.Suffix),
+	}, ";"))
+	if c.FormattedName != "" {
+		fmt.Fprintf(w, "FN:%s\r\n", escapeVCardText(c.FormattedName))
+	}
+	for _, p := range c.Phones {
+		fmt.Fprintf(w, "TEL:%s\r\n", p.Number)
+	}
+	for _, e := range c.Emails {
+		fmt.Fprintf(w, "EMAIL:%s\r\n", e.Address)
+	}
+	fmt.Fprintf(w, "END:VCARD\r\n")
+}
+
+var vcardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	",", `\,`,
+	";", `\;`,
+)
+
+func escapeVCardText(value string) string {
+	return vcardEscaper.Replace(value)
+}
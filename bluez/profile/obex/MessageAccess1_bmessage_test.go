@@ -0,0 +1,104 @@
+package obex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBMessage = "BEGIN:BMSG\r\n" +
+	"VERSION:1.0\r\n" +
+	"STATUS:UNREAD\r\n" +
+	"TYPE:SMS_GSM\r\n" +
+	"FOLDER:TELECOM/MSG/INBOX\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:2.1\r\n" +
+	"N:Doe;John\r\n" +
+	"TEL:+15550100\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:BENV\r\n" +
+	"BEGIN:BBODY\r\n" +
+	"CHARSET:UTF-8\r\n" +
+	"LENGTH:38\r\n" +
+	"BEGIN:MSG\r\n" +
+	"Hello\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"END:MSG\r\n" +
+	"END:BBODY\r\n" +
+	"END:BENV\r\n" +
+	"END:BMSG\r\n"
+
+func TestParseBMessage(t *testing.T) {
+
+	m, err := ParseBMessage(strings.NewReader(testBMessage))
+	assert.Nil(t, err)
+	assert.Equal(t, "1.0", m.Version)
+	assert.Equal(t, BMessageUnread, m.Status)
+	assert.Equal(t, BMessageSMSGSM, m.Type)
+	assert.Equal(t, "TELECOM/MSG/INBOX", m.Folder)
+	assert.Equal(t, "John Doe", m.Originator.DisplayName())
+	assert.Equal(t, "+15550100", m.Originator.Phones[0].Number)
+	assert.Len(t, m.Recipients, 0)
+	assert.Equal(t, "UTF-8", m.Body.Charset)
+	assert.Equal(t, "Hello\r\nBEGIN:VCARD", m.Body.Content)
+
+	_, err = ParseBMessage(strings.NewReader("BEGIN:BMSG\r\nBEGIN:BENV\r\n"))
+	assert.NotNil(t, err)
+	_, err = ParseBMessage(strings.NewReader("BEGIN:VCARD\r\n"))
+	assert.NotNil(t, err)
+}
+
+func TestBMessageBytes(t *testing.T) {
+
+	sms := NewSMS("+15550100", "Héllo; world")
+	b := sms.Bytes()
+
+	msg := "BEGIN:MSG\r\nHéllo; world\r\nEND:MSG\r\n"
+	// the length is in bytes
+	assert.True(t, bytes.Contains(b, []byte("LENGTH:35\r\n"+msg)), string(b))
+
+	m, err := ParseBMessage(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.Nil(t, m.Originator)
+	assert.Len(t, m.Recipients, 1)
+	assert.Equal(t, "+15550100", m.Recipients[0].Phones[0].Number)
+	assert.Equal(t, "TELECOM/MSG/OUTBOX", m.Folder)
+	assert.Equal(t, "Héllo; world", m.Body.Content)
+}
+
+func TestMapMessage(t *testing.T) {
+
+	m := newMapMessage("/org/bluez/obex/client/session0/message1", map[string]dbus.Variant{
+		"Folder":        dbus.MakeVariant("/telecom/msg/inbox"),
+		"Subject":       dbus.MakeVariant("Hello"),
+		"Timestamp":     dbus.MakeVariant("20200320T100000+0100"),
+		"SenderAddress": dbus.MakeVariant("+15550100"),
+		"Type":          dbus.MakeVariant("sms-gsm"),
+		"Size":          dbus.MakeVariant(uint64(5)),
+		"Read":          dbus.MakeVariant(true),
+	})
+	assert.Equal(t, "/telecom/msg/inbox", m.Folder)
+	assert.Equal(t, "Hello", m.Subject)
+	assert.True(t, m.Timestamp.Equal(time.Date(2020, 3, 20, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, uint64(5), m.Size)
+	assert.True(t, m.Read)
+	assert.False(t, m.Sent)
+
+	read := false
+	filter := MessageFilter{
+		MaxCount:    10,
+		Types:       []string{"sms"},
+		PeriodBegin: time.Date(2020, 3, 20, 10, 0, 0, 0, time.UTC),
+		Read:        &read,
+	}
+	assert.Equal(t, map[string]interface{}{
+		"MaxCount":    uint16(10),
+		"Types":       []string{"sms"},
+		"PeriodBegin": "20200320T100000",
+		"Read":        false,
+	}, filter.ToMap())
+}
@@ -0,0 +1,416 @@
+package obex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	log "github.com/sirupsen/logrus"
+)
+
+// MessageRoot is the MAP folder holding inbox, outbox, sent and deleted
+const MessageRoot = "/telecom/msg"
+
+// MapMessage is a message listed by ListMessages or notified by the phone
+type MapMessage struct {
+	Path             dbus.ObjectPath
+	Folder           string
+	Subject          string
+	Timestamp        time.Time
+	Sender           string
+	SenderAddress    string
+	ReplyTo          string
+	Recipient        string
+	RecipientAddress string
+	// Type is email, sms-gsm, sms-cdma or mms
+	Type           string
+	Size           uint64
+	Text           bool
+	Status         string
+	AttachmentSize uint64
+	Priority       bool
+	Read           bool
+	Sent           bool
+	Protected      bool
+}
+
+// newMapMessage read the Message1 properties
+func newMapMessage(path dbus.ObjectPath, props map[string]dbus.Variant) MapMessage {
+
+	m := MapMessage{Path: path}
+
+	str := func(name string) string {
+		v, _ := props[name].Value().(string)
+		return v
+	}
+	u64 := func(name string) uint64 {
+		v, _ := props[name].Value().(uint64)
+		return v
+	}
+	flag := func(name string) bool {
+		v, _ := props[name].Value().(bool)
+		return v
+	}
+
+	m.Folder = str("Folder")
+	m.Subject = str("Subject")
+	m.Timestamp, _ = parseVCardTime(str("Timestamp"))
+	m.Sender = str("Sender")
+	m.SenderAddress = str("SenderAddress")
+	m.ReplyTo = str("ReplyTo")
+	m.Recipient = str("Recipient")
+	m.RecipientAddress = str("RecipientAddress")
+	m.Type = str("Type")
+	m.Size = u64("Size")
+	m.Text = flag("Text")
+	m.Status = str("Status")
+	m.AttachmentSize = u64("AttachmentSize")
+	m.Priority = flag("Priority")
+	m.Read = flag("Read")
+	m.Sent = flag("Sent")
+	m.Protected = flag("Protected")
+
+	return m
+}
+
+// MessageFilter are the ListMessages filters, zero values are not sent
+type MessageFilter struct {
+	Offset        uint16
+	MaxCount      uint16
+	SubjectLength byte
+	Fields        []string
+	// Types is sms, email or mms
+	Types       []string
+	PeriodBegin time.Time
+	PeriodEnd   time.Time
+	// Read filter on the read flag when set
+	Read      *bool
+	Recipient string
+	Sender    string
+	// Priority filter on the priority flag when set
+	Priority *bool
+}
+
+// ToMap convert the filter to the ListMessages dictionary
+func (f MessageFilter) ToMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	if f.Offset != 0 {
+		m["Offset"] = f.Offset
+	}
+	if f.MaxCount != 0 {
+		m["MaxCount"] = f.MaxCount
+	}
+	if f.SubjectLength != 0 {
+		m["SubjectLength"] = f.SubjectLength
+	}
+	if len(f.Fields) > 0 {
+		m["Fields"] = f.Fields
+	}
+	if len(f.Types) > 0 {
+		m["Types"] = f.Types
+	}
+	if !f.PeriodBegin.IsZero() {
+		m["PeriodBegin"] = f.PeriodBegin.Format("20060102T150405")
+	}
+	if !f.PeriodEnd.IsZero() {
+		m["PeriodEnd"] = f.PeriodEnd.Format("20060102T150405")
+	}
+	if f.Read != nil {
+		m["Read"] = *f.Read
+	}
+	if f.Recipient != "" {
+		m["Recipient"] = f.Recipient
+	}
+	if f.Sender != "" {
+		m["Sender"] = f.Sender
+	}
+	if f.Priority != nil {
+		m["Priority"] = *f.Priority
+	}
+	return m
+}
+
+// Folders return the subfolders of folder. An absolute folder is relative
+// to the root, else to the current folder.
+func (s *MessageAccessSession) Folders(folder string) ([]string, error) {
+
+	err := s.SetFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	// the generated ListFolders cannot store the aa{sv} reply
+	var list []map[string]dbus.Variant
+	err = s.MessageAccess1.Client().Call("ListFolders", 0, map[string]interface{}{}).Store(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, props := range list {
+		if name, ok := props["Name"].Value().(string); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Messages list the messages of folder, eg. /telecom/msg/inbox, by
+// timestamp
+func (s *MessageAccessSession) Messages(folder string, filter MessageFilter) ([]MapMessage, error) {
+
+	err := s.SetFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	// ListMessages return a{oa{sv}}, obexd add the listed objects meanwhile
+	var list map[dbus.ObjectPath]map[string]dbus.Variant
+	s.listed.begin()
+	err = s.MessageAccess1.Client().Call("ListMessages", 0, "", filter.ToMap()).Store(&list)
+	s.listed.end(list)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []MapMessage{}
+	for path, props := range list {
+		m := newMapMessage(path, props)
+		if m.Folder == "" {
+			m.Folder = folder
+		}
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].Timestamp.Equal(messages[j].Timestamp) {
+			return messages[i].Path < messages[j].Path
+		}
+		return messages[i].Timestamp.Before(messages[j].Timestamp)
+	})
+
+	return messages, nil
+}
+
+// GetMessage download and parse a listed message
+func (s *MessageAccessSession) GetMessage(ctx context.Context, path dbus.ObjectPath, attachment bool) (*BMessage, error) {
+
+	// msg share the session bus of the session, it is not closed
+	msg, err := NewMessage1(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "map")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	targetfile := filepath.Join(dir, "message.bmsg")
+
+	_, err = s.Transfer(ctx, func() (dbus.ObjectPath, map[string]interface{}, error) {
+		return msg.Get(targetfile, attachment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(targetfile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseBMessage(f)
+}
+
+// SendMessage push a message to the outbox for the phone to send it
+func (s *MessageAccessSession) SendMessage(ctx context.Context, m *BMessage) (TransferProgress, error) {
+
+	err := s.SetFolder(MessageRoot)
+	if err != nil {
+		return TransferProgress{}, err
+	}
+
+	f, err := ioutil.TempFile("", "map")
+	if err != nil {
+		return TransferProgress{}, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(m.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return TransferProgress{}, err
+	}
+
+	args := map[string]interface{}{}
+	if strings.EqualFold(m.Body.Charset, "UTF-8") {
+		args["Charset"] = "utf8"
+	}
+
+	return s.Transfer(ctx, func() (dbus.ObjectPath, map[string]interface{}, error) {
+		// PushMessage return the transfer, as Get does
+		var path dbus.ObjectPath
+		var props map[string]interface{}
+		err := s.MessageAccess1.Client().Call("PushMessage", 0, f.Name(), "outbox", args).Store(&path, &props)
+		return path, props, err
+	})
+}
+
+// SendSMS send a text to number
+func (s *MessageAccessSession) SendSMS(ctx context.Context, number, text string) error {
+	_, err := s.SendMessage(ctx, NewSMS(number, text))
+	return err
+}
+
+// messageIndex track the messages listed by the session. obexd add an object
+// for each listed message, as it does for the notified ones.
+type messageIndex struct {
+	lock    sync.Mutex
+	paths   map[dbus.ObjectPath]bool
+	listing int
+	// idle is closed once no listing is in progress
+	idle chan struct{}
+}
+
+// begin a listing
+func (i *messageIndex) begin() {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.listing == 0 {
+		i.idle = make(chan struct{})
+	}
+	i.listing++
+}
+
+// end a listing, recording the listed paths
+func (i *messageIndex) end(list map[dbus.ObjectPath]map[string]dbus.Variant) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.paths == nil {
+		i.paths = map[dbus.ObjectPath]bool{}
+	}
+	for path := range list {
+		i.paths[path] = true
+	}
+	i.listing--
+	if i.listing == 0 {
+		close(i.idle)
+	}
+}
+
+// state return if path was listed, and the channel closed once the listing
+// in progress ends, nil when none is
+func (i *messageIndex) state(path dbus.ObjectPath) (bool, chan struct{}) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.listing == 0 {
+		return i.paths[path], nil
+	}
+	return i.paths[path], i.idle
+}
+
+// WatchMessages send the messages notified by the phone, eg. incoming texts,
+// until ctx is done. The messages listed by Messages are not sent, those
+// added while listing are sent once the listing ends if it did not return them.
+func (s *MessageAccessSession) WatchMessages(ctx context.Context) (chan MapMessage, error) {
+
+	conn, err := bluez.GetConnection(bluez.SessionBus)
+	if err != nil {
+		return nil, err
+	}
+
+	match := fmt.Sprintf("type='signal',interface='%s',member='InterfacesAdded'", bluez.ObjectManagerInterface)
+	err = conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Store()
+	if err != nil {
+		return nil, err
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	prefix := string(s.Path()) + "/"
+	ch := make(chan MapMessage, 16)
+
+	go func() {
+
+		defer close(ch)
+		defer bluez.DrainSignals(signals, func() {
+			conn.RemoveSignal(signals)
+			err := conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match).Store()
+			if err != nil {
+				log.Warnf("MessageAccess %s: %s", s.Path(), err)
+			}
+		})
+
+		// the messages added while listing, sent once the listing ends
+		var pending []MapMessage
+		var idle chan struct{}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-idle:
+				idle = nil
+				for _, m := range pending {
+					if listed, _ := s.listed.state(m.Path); listed {
+						continue
+					}
+					select {
+					case ch <- m:
+					case <-ctx.Done():
+						return
+					}
+				}
+				pending = nil
+			case sig := <-signals:
+				if sig == nil {
+					return
+				}
+				if sig.Name != bluez.InterfacesAdded || len(sig.Body) < 2 {
+					continue
+				}
+				path, ok := sig.Body[0].(dbus.ObjectPath)
+				if !ok || !strings.HasPrefix(string(path), prefix) {
+					continue
+				}
+				ifaces, ok := sig.Body[1].(map[string]map[string]dbus.Variant)
+				if !ok {
+					continue
+				}
+				props, ok := ifaces[Message1Interface]
+				if !ok {
+					continue
+				}
+				listed, listing := s.listed.state(path)
+				if listed {
+					continue
+				}
+				if listing != nil {
+					pending = append(pending, newMapMessage(path, props))
+					idle = listing
+					continue
+				}
+				select {
+				case ch <- newMapMessage(path, props):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,94 @@
+package obex
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMessageAccess list a single inbox message, adding its object as obexd
+// does
+type fakeMessageAccess struct {
+	d *fakeObexd
+}
+
+func (a *fakeMessageAccess) SetFolder(name string) *dbus.Error {
+	return nil
+}
+
+func (a *fakeMessageAccess) ListMessages(folder string, filter map[string]dbus.Variant) (map[dbus.ObjectPath]map[string]dbus.Variant, *dbus.Error) {
+	path, props := fakeMessage(0, "listed")
+	if err := a.d.addMessage(path, props); err != nil {
+		return nil, dbus.MakeFailedError(err)
+	}
+	return map[dbus.ObjectPath]map[string]dbus.Variant{path: props}, nil
+}
+
+func fakeMessage(i int, subject string) (dbus.ObjectPath, map[string]dbus.Variant) {
+	path := dbus.ObjectPath(fmt.Sprintf("%s/message%d", fakeSessionPath, i))
+	return path, map[string]dbus.Variant{
+		"Subject": dbus.MakeVariant(subject),
+		"Type":    dbus.MakeVariant("sms-gsm"),
+	}
+}
+
+// addMessage emit the InterfacesAdded of a message object
+func (d *fakeObexd) addMessage(path dbus.ObjectPath, props map[string]dbus.Variant) error {
+	return d.conn.Emit("/", bluez.InterfacesAdded, path, map[string]map[string]dbus.Variant{
+		Message1Interface: props,
+	})
+}
+
+func TestWatchMessages(t *testing.T) {
+
+	d := newFakeObexd(t, nil)
+	err := d.conn.Export(&fakeMessageAccess{d}, fakeSessionPath, MessageAccess1Interface)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := OpenMessageAccess(ctx, "00:11:22:33:44:55", SessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ch, err := s.WatchMessages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := s.Messages("/telecom/msg/inbox", MessageFilter{})
+	if assert.Nil(t, err) && assert.Len(t, messages, 1) {
+		assert.Equal(t, "listed", messages[0].Subject)
+	}
+
+	// only the notified message is reported
+	path, props := fakeMessage(1, "notified")
+	if err := d.addMessage(path, props); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-ch:
+		assert.Equal(t, path, m.Path)
+		assert.Equal(t, "notified", m.Subject)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not notified")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed")
+	}
+}
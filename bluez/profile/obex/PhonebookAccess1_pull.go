@@ -0,0 +1,221 @@
+package obex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Phonebook locations
+const (
+	LocationInternal = "int"
+	LocationSIM1     = "sim1"
+	LocationSIM2     = "sim2"
+)
+
+// Phonebook objects
+const (
+	PhonebookContacts  = "pb"
+	PhonebookIncoming  = "ich"
+	PhonebookOutgoing  = "och"
+	PhonebookMissed    = "mch"
+	PhonebookCalls     = "cch"
+	PhonebookSpeedDial = "spd"
+	PhonebookFavorites = "fav"
+)
+
+// vCard formats
+const (
+	FormatVCard21 = "vcard21"
+	FormatVCard30 = "vcard30"
+)
+
+// PhonebookOptions select the phonebook to pull and its filters, zero values
+// use the obexd defaults
+type PhonebookOptions struct {
+	// Location defaults to LocationInternal
+	Location string
+	// Phonebook defaults to PhonebookContacts
+	Phonebook string
+	Format    string
+	// Order is indexed, alphanumeric or phonetic
+	Order    string
+	Offset   uint16
+	MaxCount uint16
+	// Fields restrict the vCard fields, see ListFilterFields
+	Fields []string
+}
+
+func (o PhonebookOptions) location() string {
+	if o.Location == "" {
+		return LocationInternal
+	}
+	return o.Location
+}
+
+func (o PhonebookOptions) phonebook() string {
+	if o.Phonebook == "" {
+		return PhonebookContacts
+	}
+	return o.Phonebook
+}
+
+// ToFilters convert the options to the PullAll filters
+func (o PhonebookOptions) ToFilters() map[string]interface{} {
+	m := map[string]interface{}{}
+	if o.Format != "" {
+		m["Format"] = o.Format
+	}
+	if o.Order != "" {
+		m["Order"] = o.Order
+	}
+	if o.Offset != 0 {
+		m["Offset"] = o.Offset
+	}
+	if o.MaxCount != 0 {
+		m["MaxCount"] = o.MaxCount
+	}
+	if len(o.Fields) > 0 {
+		m["Fields"] = o.Fields
+	}
+	return m
+}
+
+// PhonebookVersion identify a phonebook state, the counters change with the
+// entries and the identifier when the vCard handles are no longer valid.
+// It is empty when the phone does not support PBAP 1.2.
+type PhonebookVersion struct {
+	DatabaseIdentifier string
+	PrimaryCounter     string
+	SecondaryCounter   string
+}
+
+// Known return true if the phone reported a version
+func (v PhonebookVersion) Known() bool {
+	return v.DatabaseIdentifier != "" || v.PrimaryCounter != "" || v.SecondaryCounter != ""
+}
+
+// Changed return true if the phonebook may have changed since v
+func (v PhonebookVersion) Changed(since PhonebookVersion) bool {
+	if !v.Known() || !since.Known() {
+		return true
+	}
+	return v != since
+}
+
+// Phonebook is a pulled phonebook object
+type Phonebook struct {
+	Location  string
+	Phonebook string
+	Version   PhonebookVersion
+	Cards     []VCard
+}
+
+// Version return the version of the selected phonebook, after asking the
+// phone to update it
+func (s *PhonebookSession) Version() (PhonebookVersion, error) {
+
+	err := s.UpdateVersion()
+	if err != nil {
+		log.Debugf("Phonebook %s: UpdateVersion: %s", s.Path(), err)
+	}
+
+	return s.version()
+}
+
+func (s *PhonebookSession) version() (PhonebookVersion, error) {
+
+	props, err := s.GetProperties()
+	if err != nil {
+		return PhonebookVersion{}, err
+	}
+
+	props.Lock()
+	defer props.Unlock()
+	return PhonebookVersion{
+		DatabaseIdentifier: props.DatabaseIdentifier,
+		PrimaryCounter:     props.PrimaryCounter,
+		SecondaryCounter:   props.SecondaryCounter,
+	}, nil
+}
+
+// PullPhonebook select and download a phonebook through a temporary file,
+// returning the parsed vCards
+func (s *PhonebookSession) PullPhonebook(ctx context.Context, options PhonebookOptions) (*Phonebook, error) {
+
+	err := s.Select(options.location(), options.phonebook())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.pull(ctx, options)
+}
+
+// SyncPhonebook pull a phonebook unless its version did not change since the
+// one of a previous pull. The returned phonebook is nil when unchanged.
+func (s *PhonebookSession) SyncPhonebook(ctx context.Context, options PhonebookOptions, since PhonebookVersion) (*Phonebook, bool, error) {
+
+	err := s.Select(options.location(), options.phonebook())
+	if err != nil {
+		return nil, false, err
+	}
+
+	version, err := s.Version()
+	if err != nil {
+		return nil, false, err
+	}
+	if !version.Changed(since) {
+		return nil, false, nil
+	}
+
+	pb, err := s.pull(ctx, options)
+	if err != nil {
+		return nil, false, err
+	}
+	return pb, true, nil
+}
+
+func (s *PhonebookSession) pull(ctx context.Context, options PhonebookOptions) (*Phonebook, error) {
+
+	dir, err := ioutil.TempDir("", "pbap")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	targetfile := filepath.Join(dir, options.phonebook()+".vcf")
+
+	_, err = s.Download(ctx, targetfile, options.ToFilters())
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(targetfile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cards, err := ParseVCards(f)
+	if err != nil {
+		return nil, err
+	}
+
+	pb := &Phonebook{
+		Location:  options.location(),
+		Phonebook: options.phonebook(),
+		Cards:     cards,
+	}
+
+	// obexd update the counters from the PullAll response
+	pb.Version, err = s.version()
+	if err != nil {
+		return nil, fmt.Errorf("Phonebook version: %w", err)
+	}
+
+	return pb, nil
+}
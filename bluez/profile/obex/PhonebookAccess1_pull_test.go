@@ -0,0 +1,51 @@
+package obex
+
+import (
+	"context"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncPhonebook(t *testing.T) {
+
+	d := newFakeObexd(t, map[string]string{
+		"/telecom/pb.vcf": testVCards30,
+	})
+	d.setProps(PhonebookAccess1Interface, map[string]dbus.Variant{
+		"DatabaseIdentifier": dbus.MakeVariant("A1A2A3A4B1B2C1C2D1D2E1E2E3E4E5E6"),
+		"PrimaryCounter":     dbus.MakeVariant("00000000000000000000000000000001"),
+	})
+
+	ctx := context.Background()
+	s, err := OpenPhonebook(ctx, "00:11:22:33:44:55", SessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	pb, err := s.PullPhonebook(ctx, PhonebookOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, pb.Cards, 1)
+	assert.Equal(t, "00000000000000000000000000000001", pb.Version.PrimaryCounter)
+
+	// the phonebook is not pulled again until its version changes
+	pb2, changed, err := s.SyncPhonebook(ctx, PhonebookOptions{}, pb.Version)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Nil(t, pb2)
+
+	d.setProps(PhonebookAccess1Interface, map[string]dbus.Variant{
+		"DatabaseIdentifier": dbus.MakeVariant("A1A2A3A4B1B2C1C2D1D2E1E2E3E4E5E6"),
+		"PrimaryCounter":     dbus.MakeVariant("00000000000000000000000000000002"),
+	})
+	pb2, changed, err = s.SyncPhonebook(ctx, PhonebookOptions{}, pb.Version)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	if assert.NotNil(t, pb2) {
+		assert.Equal(t, "00000000000000000000000000000002", pb2.Version.PrimaryCounter)
+	}
+}
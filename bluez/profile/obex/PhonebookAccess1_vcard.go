@@ -0,0 +1,373 @@
+package obex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// Call history types, as reported by X-IRMC-CALL-DATETIME
+const (
+	CallMissed   = "missed"
+	CallReceived = "received"
+	CallDialed   = "dialed"
+)
+
+// VCardProperty is a content line of a vCard. Value is decoded from
+// quoted-printable but not unescaped.
+type VCardProperty struct {
+	Group string
+	Name  string
+	// Params keys are upper case, TYPE values are lower case
+	Params map[string][]string
+	Value  string
+}
+
+// Types return the TYPE parameters, eg. cell or home
+func (p VCardProperty) Types() []string {
+	return p.Params["TYPE"]
+}
+
+// HasType return true if the property has the type t
+func (p VCardProperty) HasType(t string) bool {
+	for _, pt := range p.Params["TYPE"] {
+		if pt == strings.ToLower(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// VCardName is the structured N property
+type VCardName struct {
+	Family     string
+	Given      string
+	Additional string
+	Prefix     string
+	Suffix     string
+}
+
+// VCardPhone is a TEL property
+type VCardPhone struct {
+	Number string
+	Types  []string
+}
+
+// VCardEmail is an EMAIL property
+type VCardEmail struct {
+	Address string
+	Types   []string
+}
+
+// VCardCall is the call history entry of ich, och, mch and cch vCards
+type VCardCall struct {
+	// Type is one of CallMissed, CallReceived or CallDialed
+	Type string
+	// Time is in the local timezone unless the phone reported an UTC offset
+	Time time.Time
+}
+
+// VCard is a phonebook entry
+type VCard struct {
+	Version       string
+	FormattedName string
+	Name          VCardName
+	Phones        []VCardPhone
+	Emails        []VCardEmail
+	Organization  string
+	// Call is set for call history entries
+	Call *VCardCall
+	// Properties hold all the content lines, including the parsed ones
+	Properties []VCardProperty
+}
+
+// Property return the first property named name
+func (c *VCard) Property(name string) (VCardProperty, bool) {
+	for _, p := range c.Properties {
+		if p.Name == strings.ToUpper(name) {
+			return p, true
+		}
+	}
+	return VCardProperty{}, false
+}
+
+// DisplayName return the formatted name, falling back on N or the first number
+func (c *VCard) DisplayName() string {
+	if c.FormattedName != "" {
+		return c.FormattedName
+	}
+	n := strings.TrimSpace(strings.Join([]string{c.Name.Given, c.Name.Family}, " "))
+	if n != "" {
+		return n
+	}
+	if len(c.Phones) > 0 {
+		return c.Phones[0].Number
+	}
+	return ""
+}
+
+// ParseVCards parse vCard 2.1 and 3.0 entries, as returned by PullAll
+func ParseVCards(r io.Reader) ([]VCard, error) {
+
+	lines, err := readVCardLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := []VCard{}
+	var card *VCard
+	for _, line := range lines {
+
+		prop, err := parseVCardLine(line)
+		if err != nil {
+			// skip the invalid lines as most readers do
+			continue
+		}
+
+		switch {
+		case prop.Name == "BEGIN" && strings.EqualFold(prop.Value, "VCARD"):
+			if card != nil {
+				return cards, fmt.Errorf("vCard %d: nested BEGIN:VCARD", len(cards)+1)
+			}
+			card = &VCard{}
+		case prop.Name == "END" && strings.EqualFold(prop.Value, "VCARD"):
+			if card == nil {
+				return cards, fmt.Errorf("vCard %d: END:VCARD without BEGIN", len(cards)+1)
+			}
+			cards = append(cards, *card)
+			card = nil
+		case card != nil:
+			card.add(prop)
+		}
+	}
+
+	if card != nil {
+		return cards, fmt.Errorf("vCard %d: missing END:VCARD", len(cards)+1)
+	}
+
+	return cards, nil
+}
+
+func (c *VCard) add(p VCardProperty) {
+
+	c.Properties = append(c.Properties, p)
+
+	switch p.Name {
+	case "VERSION":
+		c.Version = strings.TrimSpace(p.Value)
+	case "FN":
+		c.FormattedName = unescapeVCardText(p.Value)
+	case "N":
+		fields := splitVCardValue(p.Value, 5)
+		c.Name = VCardName{
+			Family:     fields[0],
+			Given:      fields[1],
+			Additional: fields[2],
+			Prefix:     fields[3],
+			Suffix:     fields[4],
+		}
+	case "TEL":
+		c.Phones = append(c.Phones, VCardPhone{
+			Number: strings.TrimSpace(p.Value),
+			Types:  p.Types(),
+		})
+	case "EMAIL":
+		c.Emails = append(c.Emails, VCardEmail{
+			Address: strings.TrimSpace(p.Value),
+			Types:   p.Types(),
+		})
+	case "ORG":
+		c.Organization = strings.Join(nonEmpty(splitVCardValue(p.Value, 0)), ", ")
+	case "X-IRMC-CALL-DATETIME":
+		call := &VCardCall{}
+		for _, t := range p.Types() {
+			switch t {
+			case CallMissed, CallReceived, CallDialed:
+				call.Type = t
+			}
+		}
+		call.Time, _ = parseVCardTime(strings.TrimSpace(p.Value))
+		c.Call = call
+	}
+}
+
+// readVCardLines return the unfolded content lines, joining the 3.0 folded
+// lines and the 2.1 quoted-printable soft line breaks
+func readVCardLines(r io.Reader) ([]string, error) {
+
+	scanner := bufio.NewScanner(r)
+	// photos may be large base64 lines
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	lines := []string{}
+	softBreak := false
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		last := len(lines) - 1
+		switch {
+		case softBreak:
+			lines[last] = lines[last][:len(lines[last])-1] + line
+		case last >= 0 && len(line) > 0 && (line[0] == ' ' || line[0] == '\t'):
+			lines[last] += line[1:]
+		case line == "":
+			continue
+		default:
+			lines = append(lines, line)
+		}
+
+		last = len(lines) - 1
+		softBreak = strings.HasSuffix(lines[last], "=") && isQuotedPrintable(lines[last])
+	}
+
+	return lines, scanner.Err()
+}
+
+func isQuotedPrintable(line string) bool {
+	i := strings.Index(line, ":")
+	if i == -1 {
+		return false
+	}
+	return strings.Contains(strings.ToUpper(line[:i]), "QUOTED-PRINTABLE")
+}
+
+var errVCardLine = errors.New("Invalid vCard line")
+
+// parseVCardLine parse [group.]name *(;param):value
+func parseVCardLine(line string) (VCardProperty, error) {
+
+	prop := VCardProperty{
+		Params: map[string][]string{},
+	}
+
+	// the separator is the first colon out of a quoted parameter value
+	sep := -1
+	quoted := false
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			sep = i
+			break
+		}
+	}
+	if sep == -1 {
+		return prop, errVCardLine
+	}
+
+	parts := strings.Split(line[:sep], ";")
+	name := parts[0]
+	if i := strings.Index(name, "."); i > -1 {
+		prop.Group = name[:i]
+		name = name[i+1:]
+	}
+	if name == "" {
+		return prop, errVCardLine
+	}
+	prop.Name = strings.ToUpper(name)
+
+	for _, param := range parts[1:] {
+		key, value := "", param
+		if i := strings.Index(param, "="); i > -1 {
+			key, value = strings.ToUpper(strings.TrimSpace(param[:i])), param[i+1:]
+		}
+		value = strings.Trim(strings.TrimSpace(value), "\"")
+
+		// vCard 2.1 allows bare parameter values
+		if key == "" {
+			switch strings.ToUpper(value) {
+			case "QUOTED-PRINTABLE", "BASE64", "8BIT", "7BIT":
+				key = "ENCODING"
+			default:
+				key = "TYPE"
+			}
+		}
+
+		for _, v := range strings.Split(value, ",") {
+			if key == "TYPE" || key == "ENCODING" {
+				v = strings.ToLower(v)
+			}
+			prop.Params[key] = append(prop.Params[key], v)
+		}
+	}
+
+	prop.Value = line[sep+1:]
+
+	if enc, ok := prop.Params["ENCODING"]; ok && enc[0] == "quoted-printable" {
+		value, err := ioutil.ReadAll(quotedprintable.NewReader(bytes.NewBufferString(prop.Value)))
+		if err == nil {
+			prop.Value = string(value)
+		}
+	}
+
+	return prop, nil
+}
+
+// splitVCardValue split a structured value on the unescaped semicolons,
+// padding the result to n fields
+func splitVCardValue(value string, n int) []string {
+	fields := []string{}
+	var field strings.Builder
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			field.WriteRune('\\')
+			field.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ';':
+			fields = append(fields, unescapeVCardText(field.String()))
+			field.Reset()
+		default:
+			field.WriteRune(c)
+		}
+	}
+	fields = append(fields, unescapeVCardText(field.String()))
+	for len(fields) < n {
+		fields = append(fields, "")
+	}
+	return fields
+}
+
+var vcardUnescaper = strings.NewReplacer(
+	`\n`, "\n",
+	`\N`, "\n",
+	`\,`, ",",
+	`\;`, ";",
+	`\:`, ":",
+	`\\`, `\`,
+)
+
+func unescapeVCardText(value string) string {
+	return vcardUnescaper.Replace(value)
+}
+
+func nonEmpty(list []string) []string {
+	res := []string{}
+	for _, item := range list {
+		if item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// parseVCardTime parse the basic ISO 8601 timestamps, eg. 20050320T100000,
+// in the local timezone unless an UTC offset is given
+func parseVCardTime(value string) (time.Time, error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case len(value) > 15:
+		return time.Parse("20060102T150405-0700", value)
+	}
+	return time.ParseInLocation("20060102T150405", value, time.Local)
+}
@@ -0,0 +1,119 @@
+package obex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testVCards21 = "BEGIN:VCARD\r\n" +
+	"VERSION:2.1\r\n" +
+	"N;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:M=C3=BCller;J=\r\n" +
+	"=C3=BCrgen;;;\r\n" +
+	"TEL;CELL;VOICE:+491701234567\r\n" +
+	"TEL;HOME:0301234567\r\n" +
+	"EMAIL;INTERNET:juergen@example.com\r\n" +
+	"X-IRMC-CALL-DATETIME;MISSED:20200320T100000\r\n" +
+	"END:VCARD\r\n" +
+	"BEGIN:VCARD\r\n" +
+	"VERSION:2.1\r\n" +
+	"N:;;;;\r\n" +
+	"TEL:+33123456789\r\n" +
+	"X-IRMC-CALL-DATETIME;DIALED:20200321T080910Z\r\n" +
+	"END:VCARD\r\n"
+
+const testVCards30 = `BEGIN:VCARD
+VERSION:3.0
+FN:Jane Doe\, PhD
+N:Doe;Jane;;Dr.;PhD
+ORG:Example;R&D
+TEL;TYPE=WORK,VOICE:+1 555 0100
+EMAIL;TYPE="internet,pref":jane@exa
+ mple.com
+item1.X-CUSTOM:value
+END:VCARD
+`
+
+func TestParseVCards21(t *testing.T) {
+
+	cards, err := ParseVCards(strings.NewReader(testVCards21))
+	assert.Nil(t, err)
+	assert.Len(t, cards, 2)
+
+	c := cards[0]
+	assert.Equal(t, "2.1", c.Version)
+	assert.Equal(t, "Müller", c.Name.Family)
+	assert.Equal(t, "Jürgen", c.Name.Given)
+	assert.Equal(t, "Jürgen Müller", c.DisplayName())
+	assert.Equal(t, []VCardPhone{
+		{Number: "+491701234567", Types: []string{"cell", "voice"}},
+		{Number: "0301234567", Types: []string{"home"}},
+	}, c.Phones)
+	assert.Equal(t, []VCardEmail{
+		{Address: "juergen@example.com", Types: []string{"internet"}},
+	}, c.Emails)
+	assert.Equal(t, CallMissed, c.Call.Type)
+	assert.Equal(t, time.Date(2020, 3, 20, 10, 0, 0, 0, time.Local), c.Call.Time)
+
+	c = cards[1]
+	assert.Equal(t, "+33123456789", c.DisplayName())
+	assert.Equal(t, CallDialed, c.Call.Type)
+	assert.Equal(t, time.Date(2020, 3, 21, 8, 9, 10, 0, time.UTC), c.Call.Time)
+}
+
+func TestParseVCards30(t *testing.T) {
+
+	cards, err := ParseVCards(strings.NewReader(testVCards30))
+	assert.Nil(t, err)
+	assert.Len(t, cards, 1)
+
+	c := cards[0]
+	assert.Equal(t, "3.0", c.Version)
+	assert.Equal(t, "Jane Doe, PhD", c.DisplayName())
+	assert.Equal(t, VCardName{Family: "Doe", Given: "Jane", Prefix: "Dr.", Suffix: "PhD"}, c.Name)
+	assert.Equal(t, "Example, R&D", c.Organization)
+	assert.Equal(t, []string{"work", "voice"}, c.Phones[0].Types)
+	assert.Equal(t, "jane@example.com", c.Emails[0].Address)
+	assert.Equal(t, []string{"internet", "pref"}, c.Emails[0].Types)
+	assert.Nil(t, c.Call)
+
+	p, ok := c.Property("x-custom")
+	assert.True(t, ok)
+	assert.Equal(t, "item1", p.Group)
+	assert.Equal(t, "value", p.Value)
+}
+
+func TestParseVCardsInvalid(t *testing.T) {
+	_, err := ParseVCards(strings.NewReader("BEGIN:VCARD\nVERSION:3.0\n"))
+	assert.NotNil(t, err)
+
+	cards, err := ParseVCards(strings.NewReader(""))
+	assert.Nil(t, err)
+	assert.Len(t, cards, 0)
+}
+
+func TestPhonebookVersion(t *testing.T) {
+	v1 := PhonebookVersion{DatabaseIdentifier: "01", PrimaryCounter: "02", SecondaryCounter: "03"}
+	v2 := v1
+	assert.False(t, v1.Changed(v2))
+	v2.PrimaryCounter = "04"
+	assert.True(t, v2.Changed(v1))
+	assert.True(t, v1.Changed(PhonebookVersion{}))
+	assert.True(t, PhonebookVersion{}.Changed(PhonebookVersion{}))
+}
+
+func TestPhonebookOptions(t *testing.T) {
+	o := PhonebookOptions{}
+	assert.Equal(t, LocationInternal, o.location())
+	assert.Equal(t, PhonebookContacts, o.phonebook())
+	assert.Equal(t, map[string]interface{}{}, o.ToFilters())
+
+	o = PhonebookOptions{Format: FormatVCard30, MaxCount: 10, Fields: []string{"N"}}
+	assert.Equal(t, map[string]interface{}{
+		"Format":   FormatVCard30,
+		"MaxCount": uint16(10),
+		"Fields":   []string{"N"},
+	}, o.ToFilters())
+}
@@ -0,0 +1,369 @@
+package obex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/props"
+	log "github.com/sirupsen/logrus"
+)
+
+// Session targets
+const (
+	TargetFTP  = "ftp"
+	TargetMAP  = "map"
+	TargetOPP  = "opp"
+	TargetPBAP = "pbap"
+	TargetSync = "sync"
+)
+
+// SessionOptions are the optional CreateSession parameters
+type SessionOptions struct {
+	// Source is the local adapter address
+	Source string
+	// Channel is the RFCOMM channel, discovered with SDP if 0
+	Channel byte
+}
+
+func (o SessionOptions) toMap(target string) map[string]interface{} {
+	m := map[string]interface{}{
+		"Target": target,
+	}
+	if o.Source != "" {
+		m["Source"] = o.Source
+	}
+	if o.Channel != 0 {
+		m["Channel"] = o.Channel
+	}
+	return m
+}
+
+// Session is an OBEX session opened with CreateSession
+type Session struct {
+	Path        dbus.ObjectPath
+	Target      string
+	Destination string
+
+	client *ObexClient1
+	once   sync.Once
+}
+
+// OpenSession create an OBEX session to the device at destination. obexd
+// connects before replying, the session is removed if ctx is done first.
+func OpenSession(ctx context.Context, destination, target string, options SessionOptions) (*Session, error) {
+
+	client := NewObexClient1()
+
+	type result struct {
+		path string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		path, err := client.CreateSession(destination, options.toMap(target))
+		resc <- result{path, err}
+	}()
+
+	select {
+	case res := <-resc:
+		if res.err != nil {
+			client.Close()
+			return nil, res.err
+		}
+		return &Session{
+			Path:        dbus.ObjectPath(res.path),
+			Target:      target,
+			Destination: destination,
+			client:      client,
+		}, nil
+	case <-ctx.Done():
+		// drop the session once created
+		go func() {
+			res := <-resc
+			if res.err == nil {
+				err := client.RemoveSession(res.path)
+				if err != nil {
+					log.Warnf("RemoveSession %s: %s", res.path, err)
+				}
+			}
+			client.Close()
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// Properties return the session properties
+func (s *Session) Properties() (*ObexSession1Properties, error) {
+	return NewObexSession1(string(s.Path)).GetProperties()
+}
+
+// Close remove the session, aborting its pending transfers
+func (s *Session) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.client.RemoveSession(string(s.Path))
+		s.client.Close()
+	})
+	return err
+}
+
+// Transfer run a transfer started by start and wait for it to complete. The
+// signals of the session are watched before starting, so even short
+// transfers are tracked. The transfer is cancelled when ctx is done first.
+func (s *Session) Transfer(ctx context.Context, start func() (dbus.ObjectPath, map[string]interface{}, error)) (TransferProgress, error) {
+	ch, err := s.TransferProgress(ctx, start)
+	if err != nil {
+		return TransferProgress{}, err
+	}
+	return waitProgress(ctx, ch)
+}
+
+// TransferProgress run a transfer started by start, returning its progress
+// as ObexTransfer1.Progress does
+func (s *Session) TransferProgress(ctx context.Context, start func() (dbus.ObjectPath, map[string]interface{}, error)) (chan TransferProgress, error) {
+
+	conn, err := bluez.GetConnection(bluez.SessionBus)
+	if err != nil {
+		return nil, err
+	}
+
+	// watch every transfer of the session, the path is known once started
+	match := fmt.Sprintf("type='signal',interface='%s',path_namespace='%s'", bluez.PropertiesInterface, s.Path)
+	err = conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Store()
+	if err != nil {
+		return nil, err
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	release := func() {
+		conn.RemoveSignal(signals)
+		err := conn.BusObject().Call("org.freedesktop.DBus.RemoveMatch", 0, match).Store()
+		if err != nil {
+			log.Warnf("Session %s: %s", s.Path, err)
+		}
+	}
+
+	path, values, err := start()
+	if err != nil {
+		bluez.DrainSignals(signals, release)
+		return nil, err
+	}
+
+	meter := &transferMeter{}
+	meter.apply(props.ToVariantMap(values))
+	if meter.progress.Status == "" {
+		meter.progress.Status = TransferStatusQueued
+	}
+
+	t := &ObexTransfer1{
+		client: bluez.NewClient(
+			&bluez.Config{
+				Name:  "org.bluez.obex",
+				Iface: "org.bluez.obex.Transfer1",
+				Path:  path,
+				Bus:   bluez.SessionBus,
+			},
+		),
+		Properties: new(ObexTransfer1Properties),
+	}
+
+	return followTransfer(ctx, t, signals, meter, release), nil
+}
+
+// waitProgress consume the progress until the transfer is done
+func waitProgress(ctx context.Context, ch chan TransferProgress) (TransferProgress, error) {
+
+	var last TransferProgress
+	for p := range ch {
+		last = p
+	}
+
+	switch last.Status {
+	case TransferStatusComplete:
+		return last, nil
+	case TransferStatusError:
+		return last, ErrTransferFailed
+	}
+
+	if ctx.Err() != nil {
+		return last, ctx.Err()
+	}
+	return last, fmt.Errorf("Transfer watch ended with status %s", last.Status)
+}
+
+// FileTransferSession is an ftp session
+type FileTransferSession struct {
+	*Session
+	*FileTransfer
+}
+
+// OpenFileTransfer open a File Transfer session
+func OpenFileTransfer(ctx context.Context, destination string, options SessionOptions) (*FileTransferSession, error) {
+	s, err := OpenSession(ctx, destination, TargetFTP, options)
+	if err != nil {
+		return nil, err
+	}
+	ft, err := NewFileTransfer(s.Path)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &FileTransferSession{s, ft}, nil
+}
+
+// Close the session
+func (s *FileTransferSession) Close() error {
+	// remove the session before releasing the client
+	err := s.Session.Close()
+	s.FileTransfer.Close()
+	return err
+}
+
+// Path return the session object path
+func (s *FileTransferSession) Path() dbus.ObjectPath {
+	return s.Session.Path
+}
+
+// Get copy the remote sourcefile to the local targetfile
+func (s *FileTransferSession) Get(ctx context.Context, targetfile, sourcefile string) (TransferProgress, error) {
+	return s.Transfer(ctx, func() (dbus.ObjectPath, map[string]interface{}, error) {
+		return s.GetFile(targetfile, sourcefile)
+	})
+}
+
+// Put copy the local sourcefile to the remote targetfile
+func (s *FileTransferSession) Put(ctx context.Context, sourcefile, targetfile string) (TransferProgress, error) {
+	return s.Transfer(ctx, func() (dbus.ObjectPath, map[string]interface{}, error) {
+		return s.PutFile(sourcefile, targetfile)
+	})
+}
+
+// PhonebookSession is a pbap session
+type PhonebookSession struct {
+	*Session
+	*PhonebookAccess1
+}
+
+// OpenPhonebook open a Phonebook Access session
+func OpenPhonebook(ctx context.Context, destination string, options SessionOptions) (*PhonebookSession, error) {
+	s, err := OpenSession(ctx, destination, TargetPBAP, options)
+	if err != nil {
+		return nil, err
+	}
+	pb, err := NewPhonebookAccess1(s.Path)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &PhonebookSession{s, pb}, nil
+}
+
+// Close the session
+func (s *PhonebookSession) Close() error {
+	// remove the session before releasing the client
+	err := s.Session.Close()
+	s.PhonebookAccess1.Close()
+	return err
+}
+
+// Path return the session object path
+func (s *PhonebookSession) Path() dbus.ObjectPath {
+	return s.Session.Path
+}
+
+// Download pull the selected phonebook to the local targetfile
+func (s *PhonebookSession) Download(ctx context.Context, targetfile string, filters map[string]interface{}) (TransferProgress, error) {
+	if filters == nil {
+		filters = map[string]interface{}{}
+	}
+	return s.Transfer(ctx, func() (dbus.ObjectPath, map[string]interface{}, error) {
+		return s.PullAll(targetfile, filters)
+	})
+}
+
+// MessageAccessSession is a map session
+type MessageAccessSession struct {
+	*Session
+	*MessageAccess1
+
+	// the messages listed by Messages, not reported by WatchMessages
+	listed messageIndex
+}
+
+// OpenMessageAccess open a Message Access session
+func OpenMessageAccess(ctx context.Context, destination string, options SessionOptions) (*MessageAccessSession, error) {
+	s, err := OpenSession(ctx, destination, TargetMAP, options)
+	if err != nil {
+		return nil, err
+	}
+	ma, err := NewMessageAccess1(s.Path)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &MessageAccessSession{Session: s, MessageAccess1: ma}, nil
+}
+
+// Close the session
+func (s *MessageAccessSession) Close() error {
+	// remove the session before releasing the client
+	err := s.Session.Close()
+	s.MessageAccess1.Close()
+	return err
+}
+
+// Path return the session object path
+func (s *MessageAccessSession) Path() dbus.ObjectPath {
+	return s.Session.Path
+}
+
+// SynchronizationSession is a sync session
+type SynchronizationSession struct {
+	*Session
+	*Synchronization1
+}
+
+// OpenSynchronization open a Synchronization session
+func OpenSynchronization(ctx context.Context, destination string, options SessionOptions) (*SynchronizationSession, error) {
+	s, err := OpenSession(ctx, destination, TargetSync, options)
+	if err != nil {
+		return nil, err
+	}
+	sync, err := NewSynchronization1(s.Path)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return &SynchronizationSession{s, sync}, nil
+}
+
+// Close the session
+func (s *SynchronizationSession) Close() error {
+	// remove the session before releasing the client
+	err := s.Session.Close()
+	s.Synchronization1.Close()
+	return err
+}
+
+// Path return the session object path
+func (s *SynchronizationSession) Path() dbus.ObjectPath {
+	return s.Session.Path
+}
+
+// Download the phonebook to the local targetfile
+func (s *SynchronizationSession) Download(ctx context.Context, targetfile string) (TransferProgress, error) {
+	return s.Transfer(ctx, func() (dbus.ObjectPath, map[string]interface{}, error) {
+		return s.GetPhonebook(targetfile)
+	})
+}
+
+// Upload send the local sourcefile as the remote phonebook
+func (s *SynchronizationSession) Upload(ctx context.Context, sourcefile string) (TransferProgress, error) {
+	return s.Transfer(ctx, func() (dbus.ObjectPath, map[string]interface{}, error) {
+		return s.PutPhonebook(sourcefile)
+	})
+}
@@ -0,0 +1,318 @@
+package obex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/props"
+	"github.com/stretchr/testify/assert"
+)
+
+const fakeSessionPath = dbus.ObjectPath("/org/bluez/obex/client/session0")
+
+// fakeObexd own org.bluez.obex on a private session bus connection and
+// serve the files of a single ftp session from memory
+type fakeObexd struct {
+	conn *dbus.Conn
+
+	lock      sync.Mutex
+	files     map[string]string
+	cwd       string
+	props     map[string]map[string]dbus.Variant
+	transfers int
+	removed   []dbus.ObjectPath
+}
+
+// newFakeObexd skip the test when the session bus is not available or
+// obexd is running
+func newFakeObexd(t *testing.T, files map[string]string) *fakeObexd {
+
+	conn, err := dbus.SessionBusPrivate()
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+	if err = conn.Auth(nil); err == nil {
+		err = conn.Hello()
+	}
+	if err != nil {
+		conn.Close()
+		t.Skipf("session bus: %s", err)
+	}
+	reply, err := conn.RequestName("org.bluez.obex", dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		t.Skip("org.bluez.obex is already owned")
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	d := &fakeObexd{
+		conn:  conn,
+		files: files,
+		cwd:   "/",
+		props: map[string]map[string]dbus.Variant{
+			"org.bluez.obex.Session1": {
+				"Destination": dbus.MakeVariant("00:11:22:33:44:55"),
+			},
+		},
+	}
+	exports := []struct {
+		obj   interface{}
+		path  dbus.ObjectPath
+		iface string
+	}{
+		{&fakeClient{d}, "/org/bluez/obex", "org.bluez.obex.Client1"},
+		{&fakeFileTransfer{d}, fakeSessionPath, FileTransferInterface},
+		{&fakePhonebook{d}, fakeSessionPath, PhonebookAccess1Interface},
+		{&fakeProperties{d}, fakeSessionPath, bluez.PropertiesInterface},
+	}
+	for _, e := range exports {
+		if err := conn.Export(e.obj, e.path, e.iface); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+type fakeClient struct {
+	d *fakeObexd
+}
+
+func (c *fakeClient) CreateSession(destination string, options map[string]dbus.Variant) (dbus.ObjectPath, *dbus.Error) {
+	return fakeSessionPath, nil
+}
+
+func (c *fakeClient) RemoveSession(session dbus.ObjectPath) *dbus.Error {
+	c.d.lock.Lock()
+	defer c.d.lock.Unlock()
+	c.d.removed = append(c.d.removed, session)
+	return nil
+}
+
+// setProps replace the properties of the session for iface
+func (d *fakeObexd) setProps(iface string, props map[string]dbus.Variant) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.props[iface] = props
+}
+
+type fakeProperties struct {
+	d *fakeObexd
+}
+
+func (p *fakeProperties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	p.d.lock.Lock()
+	defer p.d.lock.Unlock()
+	props, ok := p.d.props[iface]
+	if !ok {
+		return map[string]dbus.Variant{}, nil
+	}
+	return props, nil
+}
+
+// transfer write content to targetfile and complete the transfer at once,
+// as obexd does for small files
+func (d *fakeObexd) transfer(targetfile, content string) (dbus.ObjectPath, map[string]dbus.Variant, *dbus.Error) {
+
+	d.lock.Lock()
+	path := dbus.ObjectPath(fmt.Sprintf("%s/transfer%d", fakeSessionPath, d.transfers))
+	d.transfers++
+	d.lock.Unlock()
+
+	if err := ioutil.WriteFile(targetfile, []byte(content), 0644); err != nil {
+		return "", nil, dbus.MakeFailedError(err)
+	}
+
+	err := d.conn.Emit(path, bluez.PropertiesChanged, "org.bluez.obex.Transfer1", map[string]dbus.Variant{
+		"Status":      dbus.MakeVariant(string(TransferStatusComplete)),
+		"Transferred": dbus.MakeVariant(uint64(len(content))),
+	}, []string{})
+	if err != nil {
+		return "", nil, dbus.MakeFailedError(err)
+	}
+
+	return path, map[string]dbus.Variant{
+		"Status": dbus.MakeVariant(string(TransferStatusQueued)),
+		"Size":   dbus.MakeVariant(uint64(len(content))),
+	}, nil
+}
+
+type fakePhonebook struct {
+	d *fakeObexd
+}
+
+func (p *fakePhonebook) Select(location, phonebook string) *dbus.Error {
+	return nil
+}
+
+func (p *fakePhonebook) UpdateVersion() *dbus.Error {
+	return nil
+}
+
+func (p *fakePhonebook) PullAll(targetfile string, filters map[string]dbus.Variant) (dbus.ObjectPath, map[string]dbus.Variant, *dbus.Error) {
+	p.d.lock.Lock()
+	content := p.d.files["/telecom/pb.vcf"]
+	p.d.lock.Unlock()
+	return p.d.transfer(targetfile, content)
+}
+
+type fakeFileTransfer struct {
+	d *fakeObexd
+}
+
+func (f *fakeFileTransfer) ChangeFolder(folder string) *dbus.Error {
+	f.d.lock.Lock()
+	defer f.d.lock.Unlock()
+	if !strings.HasPrefix(folder, "/") {
+		folder = filepath.Join(f.d.cwd, folder)
+	}
+	f.d.cwd = filepath.Clean(folder)
+	return nil
+}
+
+func (f *fakeFileTransfer) ListFolder() ([]map[string]dbus.Variant, *dbus.Error) {
+	f.d.lock.Lock()
+	defer f.d.lock.Unlock()
+	list := []map[string]dbus.Variant{}
+	folders := map[string]bool{}
+	for name, content := range f.d.files {
+		rel, err := filepath.Rel(f.d.cwd, name)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if i := strings.Index(rel, "/"); i > -1 {
+			// the file is in a sub folder
+			if !folders[rel[:i]] {
+				folders[rel[:i]] = true
+				list = append(list, map[string]dbus.Variant{
+					"Name": dbus.MakeVariant(rel[:i]),
+					"Type": dbus.MakeVariant("folder"),
+				})
+			}
+			continue
+		}
+		list = append(list, map[string]dbus.Variant{
+			"Name": dbus.MakeVariant(rel),
+			"Type": dbus.MakeVariant("file"),
+			"Size": dbus.MakeVariant(uint64(len(content))),
+		})
+	}
+	return list, nil
+}
+
+func (f *fakeFileTransfer) GetFile(targetfile, sourcefile string) (dbus.ObjectPath, map[string]dbus.Variant, *dbus.Error) {
+	f.d.lock.Lock()
+	content, ok := f.d.files[filepath.Join(f.d.cwd, sourcefile)]
+	f.d.lock.Unlock()
+	if !ok {
+		return "", nil, dbus.MakeFailedError(fmt.Errorf("%s not found", sourcefile))
+	}
+	return f.d.transfer(targetfile, content)
+}
+
+func TestSessionOptions(t *testing.T) {
+	assert.Equal(t, map[string]interface{}{"Target": TargetFTP}, SessionOptions{}.toMap(TargetFTP))
+	assert.Equal(t, map[string]interface{}{
+		"Target":  TargetPBAP,
+		"Source":  "00:11:22:33:44:55",
+		"Channel": byte(19),
+	}, SessionOptions{Source: "00:11:22:33:44:55", Channel: 19}.toMap(TargetPBAP))
+}
+
+func TestTransferMeterApply(t *testing.T) {
+	m := &transferMeter{}
+	changed := m.apply(props.ToVariantMap(map[string]interface{}{
+		"Status": dbus.MakeVariant("active"),
+		"Size":   uint64(100),
+		"Name":   "file.txt",
+	}))
+	assert.True(t, changed)
+	assert.Equal(t, TransferStatusActive, m.progress.Status)
+	assert.Equal(t, uint64(100), m.progress.Size)
+
+	assert.False(t, m.apply(map[string]dbus.Variant{"Name": dbus.MakeVariant("x")}))
+}
+
+func TestWaitProgress(t *testing.T) {
+	ctx := context.Background()
+
+	ch := make(chan TransferProgress, 2)
+	ch <- TransferProgress{Status: TransferStatusActive}
+	ch <- TransferProgress{Status: TransferStatusComplete, Transferred: 10}
+	close(ch)
+	p, err := waitProgress(ctx, ch)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), p.Transferred)
+
+	ch = make(chan TransferProgress, 1)
+	ch <- TransferProgress{Status: TransferStatusError}
+	close(ch)
+	_, err = waitProgress(ctx, ch)
+	assert.Equal(t, ErrTransferFailed, err)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	ch = make(chan TransferProgress, 1)
+	ch <- TransferProgress{Status: TransferStatusActive}
+	close(ch)
+	_, err = waitProgress(cctx, ch)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestSessionTransfers(t *testing.T) {
+
+	d := newFakeObexd(t, map[string]string{
+		"/a.txt": "first",
+		"/b.txt": "second",
+	})
+
+	dir, err := ioutil.TempDir("", "obex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	s, err := OpenFileTransfer(ctx, "00:11:22:33:44:55", SessionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	props, err := s.Session.Properties()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "00:11:22:33:44:55", props.Destination)
+	}
+
+	// the session bus stays usable across transfers
+	for _, name := range []string{"a.txt", "b.txt"} {
+		p, err := s.Get(ctx, filepath.Join(dir, name), name)
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+		assert.Equal(t, TransferStatusComplete, p.Status)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "b.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(b))
+
+	assert.Nil(t, s.Close())
+	d.lock.Lock()
+	assert.Equal(t, []dbus.ObjectPath{fakeSessionPath}, d.removed)
+	d.lock.Unlock()
+
+	// and once the session is closed
+	s, err = OpenFileTransfer(ctx, "00:11:22:33:44:55", SessionOptions{})
+	if assert.Nil(t, err) {
+		assert.Nil(t, s.Close())
+	}
+}
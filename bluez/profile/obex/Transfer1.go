@@ -1,8 +1,11 @@
 package obex
 
 import (
+	"sync"
+
 	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/props"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -27,12 +30,14 @@ func NewObexTransfer1(path string) *ObexTransfer1 {
 
 // ObexTransfer1 client
 type ObexTransfer1 struct {
-	client     *bluez.Client
-	Properties *ObexTransfer1Properties
+	client                 *bluez.Client
+	Properties             *ObexTransfer1Properties
+	watchPropertiesChannel chan *dbus.Signal
 }
 
 // ObexTransfer1Properties exposed properties for ObexTransfer1
 type ObexTransfer1Properties struct {
+	lock        sync.RWMutex `dbus:"ignore"`
 	Status      string
 	Session     dbus.ObjectPath
 	Name        string
@@ -43,14 +48,65 @@ type ObexTransfer1Properties struct {
 	Filename    string
 }
 
+//Lock access to properties
+func (p *ObexTransfer1Properties) Lock() {
+	p.lock.Lock()
+}
+
+//Unlock access to properties
+func (p *ObexTransfer1Properties) Unlock() {
+	p.lock.Unlock()
+}
+
+// ToMap convert a ObexTransfer1Properties to map
+func (p *ObexTransfer1Properties) ToMap() (map[string]interface{}, error) {
+	return props.ToMap(p), nil
+}
+
 // Close the connection
 func (d *ObexTransfer1) Close() {
 	d.client.Disconnect()
 }
 
+// Path return ObexTransfer1 object path
+func (d *ObexTransfer1) Path() dbus.ObjectPath {
+	return d.client.Config.Path
+}
+
+// Client return ObexTransfer1 dbus client
+func (d *ObexTransfer1) Client() *bluez.Client {
+	return d.client
+}
+
+// ToProps return the properties interface
+func (d *ObexTransfer1) ToProps() bluez.Properties {
+	return d.Properties
+}
+
+// GetWatchPropertiesChannel return the dbus channel to receive properties interface
+func (d *ObexTransfer1) GetWatchPropertiesChannel() chan *dbus.Signal {
+	return d.watchPropertiesChannel
+}
+
+// SetWatchPropertiesChannel set the dbus channel to receive properties interface
+func (d *ObexTransfer1) SetWatchPropertiesChannel(c chan *dbus.Signal) {
+	d.watchPropertiesChannel = c
+}
+
+// WatchProperties updates on property changes
+func (d *ObexTransfer1) WatchProperties() (chan *bluez.PropertyChanged, error) {
+	return bluez.WatchProperties(d)
+}
+
+func (d *ObexTransfer1) UnwatchProperties(ch chan *bluez.PropertyChanged) error {
+	return bluez.UnwatchProperties(d, ch)
+}
+
 //GetProperties load all available properties
 func (d *ObexTransfer1) GetProperties() (*ObexTransfer1Properties, error) {
+	d.Properties.Lock()
 	err := d.client.GetProperties(d.Properties)
+	d.Properties.Unlock()
 	return d.Properties, err
 }
 
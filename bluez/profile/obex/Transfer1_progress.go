@@ -0,0 +1,197 @@
+package obex
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	log "github.com/sirupsen/logrus"
+)
+
+// TransferStatus is the Status of a transfer
+type TransferStatus string
+
+// Transfer status
+const (
+	TransferStatusQueued    TransferStatus = "queued"
+	TransferStatusActive    TransferStatus = "active"
+	TransferStatusSuspended TransferStatus = "suspended"
+	TransferStatusComplete  TransferStatus = "complete"
+	TransferStatusError     TransferStatus = "error"
+)
+
+// Done return true once the transfer is complete or failed
+func (s TransferStatus) Done() bool {
+	return s == TransferStatusComplete || s == TransferStatusError
+}
+
+// ErrTransferFailed is returned by Wait when the transfer status is error
+var ErrTransferFailed = errors.New("Transfer failed")
+
+// TransferProgress is a snapshot of a transfer
+type TransferProgress struct {
+	Status      TransferStatus
+	Transferred uint64
+	Size        uint64
+	// Rate is the average throughput in bytes per second
+	Rate float64
+	// ETA is the estimated time left, zero if unknown
+	ETA  time.Duration
+	Time time.Time
+}
+
+// Percent return the transferred percentage, -1 if the size is unknown
+func (p TransferProgress) Percent() float64 {
+	if p.Size == 0 {
+		return -1
+	}
+	return float64(p.Transferred) * 100 / float64(p.Size)
+}
+
+// transferMeter compute the throughput of a transfer
+type transferMeter struct {
+	start      time.Time
+	startBytes uint64
+	progress   TransferProgress
+}
+
+func (m *transferMeter) update(now time.Time) TransferProgress {
+
+	p := &m.progress
+	p.Time = now
+
+	// measure from the first active update
+	if m.start.IsZero() {
+		if p.Status != TransferStatusActive && !p.Status.Done() {
+			return *p
+		}
+		m.start = now
+		m.startBytes = p.Transferred
+		return *p
+	}
+
+	elapsed := now.Sub(m.start).Seconds()
+	if elapsed > 0 && p.Transferred >= m.startBytes {
+		p.Rate = float64(p.Transferred-m.startBytes) / elapsed
+	}
+
+	p.ETA = 0
+	if p.Rate > 0 && p.Size > p.Transferred && !p.Status.Done() {
+		p.ETA = time.Duration(float64(p.Size-p.Transferred) / p.Rate * float64(time.Second))
+	}
+
+	return *p
+}
+
+// Progress send the transfer progress on each Status or Transferred change.
+// The channel is closed once the transfer is done. When ctx is done before,
+// the transfer is cancelled.
+func (d *ObexTransfer1) Progress(ctx context.Context) (chan TransferProgress, error) {
+
+	signals, err := d.client.Register(d.Path(), bluez.PropertiesInterface)
+	if err != nil {
+		return nil, err
+	}
+
+	// read the state after registering, to not miss a change
+	props, err := d.GetProperties()
+	if err != nil {
+		d.client.Unregister(d.Path(), bluez.PropertiesInterface, signals)
+		return nil, err
+	}
+
+	meter := &transferMeter{}
+	d.Properties.Lock()
+	meter.progress.Status = TransferStatus(props.Status)
+	meter.progress.Size = props.Size
+	meter.progress.Transferred = props.Transferred
+	d.Properties.Unlock()
+
+	unregister := func() {
+		err := d.client.Unregister(d.Path(), bluez.PropertiesInterface, signals)
+		if err != nil {
+			log.Warnf("Transfer %s: %s", d.Path(), err)
+		}
+	}
+
+	return followTransfer(ctx, d, signals, meter, unregister), nil
+}
+
+// followTransfer send the progress of a transfer from its PropertiesChanged
+// signals, cancelling the transfer when ctx is done first. release is called
+// once the transfer is done.
+func followTransfer(ctx context.Context, d *ObexTransfer1, signals chan *dbus.Signal, meter *transferMeter, release func()) chan TransferProgress {
+
+	ch := make(chan TransferProgress, 1)
+	ch <- meter.update(time.Now())
+
+	go func() {
+
+		defer close(ch)
+		// the signals are no longer read, a full channel would block release
+		defer bluez.DrainSignals(signals, release)
+
+		for !meter.progress.Status.Done() {
+			select {
+			case <-ctx.Done():
+				err := d.Cancel()
+				if err != nil {
+					log.Debugf("Transfer %s: Cancel: %s", d.Path(), err)
+				}
+				return
+			case sig := <-signals:
+				if sig == nil {
+					return
+				}
+				if sig.Name != bluez.PropertiesChanged || sig.Path != d.Path() || len(sig.Body) < 2 {
+					continue
+				}
+				changes, ok := sig.Body[1].(map[string]dbus.Variant)
+				if !ok {
+					continue
+				}
+				if !meter.apply(changes) {
+					continue
+				}
+				select {
+				case ch <- meter.update(time.Now()):
+				case <-ctx.Done():
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+// apply the changed properties, return true on progress changes
+func (m *transferMeter) apply(changes map[string]dbus.Variant) bool {
+	changed := false
+	if v, ok := changes["Status"].Value().(string); ok {
+		m.progress.Status = TransferStatus(v)
+		changed = true
+	}
+	if v, ok := changes["Transferred"].Value().(uint64); ok {
+		m.progress.Transferred = v
+		changed = true
+	}
+	if v, ok := changes["Size"].Value().(uint64); ok {
+		m.progress.Size = v
+		changed = true
+	}
+	return changed
+}
+
+// Wait for the transfer to complete, returning the last progress. The
+// transfer is cancelled when ctx is done first.
+func (d *ObexTransfer1) Wait(ctx context.Context) (TransferProgress, error) {
+
+	ch, err := d.Progress(ctx)
+	if err != nil {
+		return TransferProgress{}, err
+	}
+
+	return waitProgress(ctx, ch)
+}
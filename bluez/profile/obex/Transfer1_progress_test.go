@@ -0,0 +1,101 @@
+package obex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferStatus(t *testing.T) {
+	assert.False(t, TransferStatusQueued.Done())
+	assert.False(t, TransferStatusActive.Done())
+	assert.False(t, TransferStatusSuspended.Done())
+	assert.True(t, TransferStatusComplete.Done())
+	assert.True(t, TransferStatusError.Done())
+}
+
+func TestTransferMeter(t *testing.T) {
+
+	start := time.Unix(1500000000, 0)
+	m := &transferMeter{}
+	m.progress.Status = TransferStatusQueued
+	m.progress.Size = 1000
+
+	p := m.update(start)
+	assert.Equal(t, float64(0), p.Rate)
+	assert.Equal(t, float64(0), p.Percent())
+
+	m.progress.Status = TransferStatusActive
+	m.update(start.Add(time.Second))
+
+	m.progress.Transferred = 250
+	p = m.update(start.Add(2 * time.Second))
+	assert.Equal(t, float64(250), p.Rate)
+	assert.Equal(t, 3*time.Second, p.ETA)
+	assert.Equal(t, float64(25), p.Percent())
+
+	m.progress.Transferred = 1000
+	m.progress.Status = TransferStatusComplete
+	p = m.update(start.Add(5 * time.Second))
+	assert.Equal(t, float64(250), p.Rate)
+	assert.Equal(t, time.Duration(0), p.ETA)
+
+	assert.Equal(t, float64(-1), TransferProgress{}.Percent())
+}
+
+func TestFollowTransferRelease(t *testing.T) {
+
+	d := newFakeObexd(t, nil)
+
+	path := fakeSessionPath + "/transfer0"
+	tr := &ObexTransfer1{
+		client: bluez.NewClient(
+			&bluez.Config{
+				Name:  "org.bluez.obex",
+				Iface: "org.bluez.obex.Transfer1",
+				Path:  path,
+				Bus:   bluez.SessionBus,
+			},
+		),
+		Properties: new(ObexTransfer1Properties),
+	}
+	signals, err := tr.client.Register(path, bluez.PropertiesInterface)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meter := &transferMeter{}
+	meter.progress.Status = TransferStatusQueued
+	ch := followTransfer(context.Background(), tr, signals, meter, func() {
+		tr.client.Unregister(path, bluez.PropertiesInterface, signals)
+	})
+
+	// the signals keep coming after the transfer is complete
+	for i := 0; i < 3; i++ {
+		err := d.conn.Emit(path, bluez.PropertiesChanged, "org.bluez.obex.Transfer1", map[string]dbus.Variant{
+			"Status": dbus.MakeVariant(string(TransferStatusComplete)),
+		}, []string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	var last TransferProgress
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case p, ok := <-ch:
+			if !ok {
+				assert.Equal(t, TransferStatusComplete, last.Status)
+				return
+			}
+			last = p
+		case <-timeout:
+			t.Fatal("progress not closed")
+		}
+	}
+}
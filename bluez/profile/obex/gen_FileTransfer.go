@@ -26,7 +26,7 @@ func NewFileTransfer(objectPath dbus.ObjectPath) (*FileTransfer, error) {
 			Name:  "org.bluez.obex",
 			Iface: FileTransferInterface,
 			Path:  dbus.ObjectPath(objectPath),
-			Bus:   bluez.SystemBus,
+			Bus:   bluez.SessionBus,
 		},
 	)
 	
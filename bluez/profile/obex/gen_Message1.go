@@ -26,7 +26,7 @@ func NewMessage1(objectPath dbus.ObjectPath) (*Message1, error) {
 			Name:  "org.bluez.obex",
 			Iface: Message1Interface,
 			Path:  dbus.ObjectPath(objectPath),
-			Bus:   bluez.SystemBus,
+			Bus:   bluez.SessionBus,
 		},
 	)
 	
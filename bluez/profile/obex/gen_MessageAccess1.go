@@ -26,7 +26,7 @@ func NewMessageAccess1(objectPath dbus.ObjectPath) (*MessageAccess1, error) {
 			Name:  "org.bluez.obex",
 			Iface: MessageAccess1Interface,
 			Path:  dbus.ObjectPath(objectPath),
-			Bus:   bluez.SystemBus,
+			Bus:   bluez.SessionBus,
 		},
 	)
 	
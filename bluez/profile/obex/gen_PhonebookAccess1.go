@@ -26,7 +26,7 @@ func NewPhonebookAccess1(objectPath dbus.ObjectPath) (*PhonebookAccess1, error)
 			Name:  "org.bluez.obex",
 			Iface: PhonebookAccess1Interface,
 			Path:  dbus.ObjectPath(objectPath),
-			Bus:   bluez.SystemBus,
+			Bus:   bluez.SessionBus,
 		},
 	)
 	
@@ -26,7 +26,7 @@ func NewSynchronization1(objectPath dbus.ObjectPath) (*Synchronization1, error)
 			Name:  "org.bluez.obex",
 			Iface: Synchronization1Interface,
 			Path:  dbus.ObjectPath(objectPath),
-			Bus:   bluez.SystemBus,
+			Bus:   bluez.SessionBus,
 		},
 	)
 	
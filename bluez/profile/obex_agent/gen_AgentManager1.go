@@ -26,7 +26,7 @@ func NewAgentManager1() (*AgentManager1, error) {
 			Name:  "org.bluez.obex",
 			Iface: AgentManager1Interface,
 			Path:  dbus.ObjectPath("/org/bluez/obex"),
-			Bus:   bluez.SystemBus,
+			Bus:   bluez.SessionBus,
 		},
 	)
 	
@@ -0,0 +1,436 @@
+package obex_agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/obex"
+	log "github.com/sirupsen/logrus"
+)
+
+const ObexAgentBasePath = "/obex/agent%d"
+
+// DefaultAuthorizeTimeout is the time given to a Handler to authorize a push
+const DefaultAuthorizeTimeout = 30 * time.Second
+
+// ErrPushRejected is returned by a Handler to reject a push
+var ErrPushRejected = errors.New("Push rejected")
+
+var (
+	errRejected = dbus.Error{
+		Name: "org.bluez.obex.Error.Rejected",
+		Body: []interface{}{"Rejected"},
+	}
+	errCanceled = dbus.Error{
+		Name: "org.bluez.obex.Error.Canceled",
+		Body: []interface{}{"Canceled"},
+	}
+)
+
+// PushRequest describe an incoming Object Push transfer
+type PushRequest struct {
+	Transfer dbus.ObjectPath
+	Session  dbus.ObjectPath
+	// Source is the local adapter address
+	Source string
+	// Destination is the address of the sender
+	Destination string
+	Name        string
+	Type        string
+	Size        uint64
+}
+
+// Handler authorize the incoming pushes, returning the absolute path the
+// file is stored at. Returning an error rejects the push.
+type Handler interface {
+	AuthorizePush(ctx context.Context, req PushRequest) (string, error)
+}
+
+// HandlerFunc adapt a function to a Handler
+type HandlerFunc func(ctx context.Context, req PushRequest) (string, error)
+
+func (f HandlerFunc) AuthorizePush(ctx context.Context, req PushRequest) (string, error) {
+	return f(ctx, req)
+}
+
+// PushEvent is sent once an authorized push is done
+type PushEvent struct {
+	Request PushRequest
+	// Path the file has been stored at
+	Path     string
+	Progress obex.TransferProgress
+	Err      error
+}
+
+// NewObexAgent create an obexd agent delegating the pushes to handler
+func NewObexAgent(handler Handler) *ObexAgent {
+	return &ObexAgent{
+		path:    bluez.NewObjectPath(ObexAgentBasePath),
+		handler: handler,
+		Timeout: DefaultAuthorizeTimeout,
+		events:  make(chan PushEvent, 16),
+		pending: make(map[uint64]context.CancelFunc),
+		done:    make(chan struct{}),
+	}
+}
+
+// ObexAgent is an org.bluez.obex.Agent1 implementation
+type ObexAgent struct {
+	path    dbus.ObjectPath
+	handler Handler
+	conn    *dbus.Conn
+	// Timeout of an authorization, the push is rejected once elapsed
+	Timeout time.Duration
+
+	events chan PushEvent
+
+	lock    sync.Mutex
+	pending map[uint64]context.CancelFunc
+	nextID  uint64
+	done    chan struct{}
+	closed  bool
+}
+
+// Path return the agent object path
+func (a *ObexAgent) Path() dbus.ObjectPath {
+	return a.path
+}
+
+// Events return the channel of completed pushes, it is closed by Unregister
+func (a *ObexAgent) Events() <-chan PushEvent {
+	return a.events
+}
+
+// agentObject is the Agent1 object exported on DBus
+type agentObject struct {
+	agent *ObexAgent
+}
+
+func (o *agentObject) Release() *dbus.Error {
+	log.Debugf("ObexAgent: Release")
+	o.agent.cancelPending()
+	return nil
+}
+
+func (o *agentObject) Cancel() *dbus.Error {
+	log.Debugf("ObexAgent: Cancel")
+	o.agent.cancelPending()
+	return nil
+}
+
+func (o *agentObject) AuthorizePush(transfer dbus.ObjectPath) (string, *dbus.Error) {
+
+	// the clients share the session bus the agent is exported on, they
+	// must not be closed
+	t := obex.NewObexTransfer1(string(transfer))
+	props := t.Properties
+
+	req := PushRequest{
+		Transfer: transfer,
+		Session:  props.Session,
+		Name:     props.Name,
+		Type:     props.Type,
+		Size:     props.Size,
+	}
+	if props.Session != "" {
+		s := obex.NewObexSession1(string(props.Session))
+		req.Source = s.Properties.Source
+		req.Destination = s.Properties.Destination
+	}
+
+	path, err := o.agent.authorize(req)
+	if err != nil {
+		return "", err
+	}
+
+	// follow the transfer before replying, obexd may complete a small push
+	// before a later match is registered
+	ctx, cancel := o.agent.watchContext()
+	ch, perr := t.Progress(ctx)
+	if perr != nil {
+		cancel()
+		log.Warnf("ObexAgent: %s: %s", transfer, perr)
+		o.agent.send(PushEvent{
+			Request: req,
+			Path:    path,
+			Err:     perr,
+		})
+		return path, nil
+	}
+
+	go o.agent.watch(ctx, cancel, ch, req, path)
+
+	return path, nil
+}
+
+// authorize run the handler with a context done on timeout or Cancel
+func (a *ObexAgent) authorize(req PushRequest) (string, *dbus.Error) {
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if a.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), a.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	a.lock.Lock()
+	id := a.nextID
+	a.nextID++
+	a.pending[id] = cancel
+	a.lock.Unlock()
+
+	defer func() {
+		a.lock.Lock()
+		delete(a.pending, id)
+		a.lock.Unlock()
+		cancel()
+	}()
+
+	type result struct {
+		path string
+		err  error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		path, err := a.handler.AuthorizePush(ctx, req)
+		resc <- result{path, err}
+	}()
+
+	var res result
+	select {
+	case res = <-resc:
+	case <-ctx.Done():
+		res.err = ctx.Err()
+	}
+	if res.err == nil && ctx.Err() != nil {
+		res.err = ctx.Err()
+	}
+
+	if res.err != nil {
+		log.Debugf("ObexAgent: AuthorizePush %s: %s", req.Name, res.err)
+		if res.err == context.Canceled || res.err == context.DeadlineExceeded {
+			return "", &errCanceled
+		}
+		return "", &errRejected
+	}
+
+	if !filepath.IsAbs(res.path) {
+		log.Warnf("ObexAgent: AuthorizePush %s: %s is not an absolute path", req.Name, res.path)
+		return "", &errRejected
+	}
+
+	return res.path, nil
+}
+
+// watchContext return a context done once the agent is unregistered
+func (a *ObexAgent) watchContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-a.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// watch wait for the transfer to complete and send the event
+func (a *ObexAgent) watch(ctx context.Context, cancel context.CancelFunc, ch chan obex.TransferProgress, req PushRequest, path string) {
+
+	defer cancel()
+
+	var progress obex.TransferProgress
+	for p := range ch {
+		progress = p
+	}
+	if ctx.Err() != nil {
+		// unregistered
+		return
+	}
+
+	var err error
+	if progress.Status != obex.TransferStatusComplete {
+		err = obex.ErrTransferFailed
+	}
+
+	a.send(PushEvent{
+		Request:  req,
+		Path:     path,
+		Progress: progress,
+		Err:      err,
+	})
+}
+
+func (a *ObexAgent) send(ev PushEvent) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.closed {
+		return
+	}
+	select {
+	case a.events <- ev:
+	default:
+		log.Warnf("ObexAgent: events channel full, dropped %s", ev.Path)
+	}
+}
+
+func (a *ObexAgent) cancelPending() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, cancel := range a.pending {
+		cancel()
+	}
+}
+
+func newSessionAgentManager() *bluez.Client {
+	return bluez.NewClient(
+		&bluez.Config{
+			Name:  "org.bluez.obex",
+			Iface: AgentManager1Interface,
+			Path:  dbus.ObjectPath("/org/bluez/obex"),
+			Bus:   bluez.SessionBus,
+		},
+	)
+}
+
+// Register export the agent on the session bus and register it to obexd
+func (a *ObexAgent) Register() error {
+
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+
+	obj := &agentObject{agent: a}
+	err = conn.Export(obj, a.path, Agent1Interface)
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    Agent1Interface,
+				Methods: introspect.Methods(obj),
+			},
+		},
+	}
+	err = conn.Export(introspect.NewIntrospectable(node), a.path, bluez.Introspectable)
+	if err != nil {
+		a.unexport()
+		return err
+	}
+
+	err = newSessionAgentManager().Call("RegisterAgent", 0, a.path).Store()
+	if err != nil {
+		a.unexport()
+		return fmt.Errorf("RegisterAgent %s: %s", a.path, err)
+	}
+
+	return nil
+}
+
+func (a *ObexAgent) unexport() {
+	a.conn.Export(nil, a.path, Agent1Interface)
+	a.conn.Export(nil, a.path, bluez.Introspectable)
+}
+
+// Unregister the agent from obexd, stop the transfer watches and close the
+// events channel
+func (a *ObexAgent) Unregister() error {
+
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.done)
+	close(a.events)
+	a.lock.Unlock()
+
+	a.cancelPending()
+
+	defer bluez.ReleaseObjectPath(a.path)
+
+	if a.conn == nil {
+		return nil
+	}
+	defer a.unexport()
+
+	return newSessionAgentManager().Call("UnregisterAgent", 0, a.path).Store()
+}
+
+// DirectoryHandler accept the pushes storing them in Dir. Senders, Types and
+// MaxSize restrict the accepted pushes when set.
+type DirectoryHandler struct {
+	Dir string
+	// Senders addresses allowed to push
+	Senders []string
+	// Types MIME types allowed, eg. text/x-vcard
+	Types   []string
+	MaxSize uint64
+}
+
+// AuthorizePush return a free path in Dir for the pushed file name. The file
+// is created empty to reserve the name for concurrent pushes, it is left in
+// Dir if the transfer fails.
+func (h *DirectoryHandler) AuthorizePush(ctx context.Context, req PushRequest) (string, error) {
+
+	if len(h.Senders) > 0 && !containsFold(h.Senders, req.Destination) {
+		return "", ErrPushRejected
+	}
+	if len(h.Types) > 0 && !containsFold(h.Types, req.Type) {
+		return "", ErrPushRejected
+	}
+	if h.MaxSize > 0 && req.Size > h.MaxSize {
+		return "", ErrPushRejected
+	}
+
+	dir, err := filepath.Abs(h.Dir)
+	if err != nil {
+		return "", err
+	}
+
+	// never trust the remote name
+	name := filepath.Base(filepath.Clean("/" + req.Name))
+	if name == "/" || name == "." {
+		name = "push"
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return path, f.Close()
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package obex_agent
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObexAgentAuthorize(t *testing.T) {
+
+	a := NewObexAgent(HandlerFunc(func(ctx context.Context, req PushRequest) (string, error) {
+		switch req.Name {
+		case "reject.txt":
+			return "", ErrPushRejected
+		case "relative.txt":
+			return "relative.txt", nil
+		case "slow.txt":
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "/tmp/" + req.Name, nil
+	}))
+	a.Timeout = 10 * time.Millisecond
+	defer a.Unregister()
+
+	path, err := a.authorize(PushRequest{Name: "file.txt"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/tmp/file.txt", path)
+
+	_, err = a.authorize(PushRequest{Name: "reject.txt"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, errRejected.Name, err.Name)
+	}
+
+	_, err = a.authorize(PushRequest{Name: "relative.txt"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, errRejected.Name, err.Name)
+	}
+
+	_, err = a.authorize(PushRequest{Name: "slow.txt"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, errCanceled.Name, err.Name)
+	}
+}
+
+func TestObexAgentCancel(t *testing.T) {
+
+	started := make(chan bool)
+	a := NewObexAgent(HandlerFunc(func(ctx context.Context, req PushRequest) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}))
+	a.Timeout = 0
+	defer a.Unregister()
+
+	go func() {
+		<-started
+		(&agentObject{agent: a}).Cancel()
+	}()
+
+	_, err := a.authorize(PushRequest{Name: "file.txt"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, errCanceled.Name, err.Name)
+	}
+}
+
+func TestObexAgentEvents(t *testing.T) {
+	a := NewObexAgent(&DirectoryHandler{Dir: "/tmp"})
+	a.send(PushEvent{Path: "/tmp/file.txt"})
+	ev := <-a.Events()
+	assert.Equal(t, "/tmp/file.txt", ev.Path)
+
+	assert.Nil(t, a.Unregister())
+	_, ok := <-a.Events()
+	assert.False(t, ok)
+
+	// late events are dropped
+	a.send(PushEvent{Path: "/tmp/file.txt"})
+}
+
+func TestDirectoryHandler(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "obex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h := &DirectoryHandler{
+		Dir:     dir,
+		Senders: []string{"00:11:22:33:44:55"},
+		MaxSize: 1024,
+	}
+	ctx := context.Background()
+	req := PushRequest{
+		Destination: "00:11:22:33:44:55",
+		Name:        "photo.jpg",
+		Size:        100,
+	}
+
+	path, err := h.AuthorizePush(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "photo.jpg"), path)
+
+	// do not overwrite
+	err = ioutil.WriteFile(path, []byte{}, 0600)
+	assert.Nil(t, err)
+	path, err = h.AuthorizePush(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "photo (1).jpg"), path)
+
+	// stay in dir
+	req.Name = "../../etc/passwd"
+	path, err = h.AuthorizePush(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "passwd"), path)
+
+	req.Size = 2048
+	_, err = h.AuthorizePush(ctx, req)
+	assert.Equal(t, ErrPushRejected, err)
+
+	req.Size = 100
+	req.Destination = "66:77:88:99:AA:BB"
+	_, err = h.AuthorizePush(ctx, req)
+	assert.Equal(t, ErrPushRejected, err)
+}
+
+func TestDirectoryHandlerConcurrent(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "obex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h := &DirectoryHandler{Dir: dir}
+	req := PushRequest{Name: "photo.jpg"}
+
+	paths := make(chan string, 10)
+	for i := 0; i < cap(paths); i++ {
+		go func() {
+			path, err := h.AuthorizePush(context.Background(), req)
+			assert.Nil(t, err)
+			paths <- path
+		}()
+	}
+
+	// every push get its own file
+	seen := map[string]bool{}
+	for i := 0; i < cap(paths); i++ {
+		path := <-paths
+		assert.False(t, seen[path], path)
+		seen[path] = true
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	assert.Len(t, entries, cap(paths))
+}
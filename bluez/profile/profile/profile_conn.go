@@ -0,0 +1,170 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/adapter"
+)
+
+// Addr is the address of one end of a profile connection
+type Addr struct {
+	// Path is the device or adapter object path
+	Path    dbus.ObjectPath
+	Address string
+}
+
+func (a Addr) Network() string {
+	return "bluetooth"
+}
+
+func (a Addr) String() string {
+	if a.Address != "" {
+		return a.Address
+	}
+	return string(a.Path)
+}
+
+// NewConn wrap a connected socket received by NewConnection, the connection
+// take ownership of fd
+func NewConn(fd int, device dbus.ObjectPath, props map[string]dbus.Variant) (*Conn, error) {
+
+	// a non blocking fd is handled by the runtime poller, enabling deadlines
+	err := syscall.SetNonblock(fd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{
+		file:   os.NewFile(uintptr(fd), string(device)),
+		device: device,
+		props:  props,
+	}
+	if c.props == nil {
+		c.props = map[string]dbus.Variant{}
+	}
+
+	return c, nil
+}
+
+// Conn is a profile connection, implementing net.Conn
+type Conn struct {
+	file    *os.File
+	device  dbus.ObjectPath
+	props   map[string]dbus.Variant
+	once    sync.Once
+	onClose func()
+}
+
+// Device return the remote device object path
+func (c *Conn) Device() dbus.ObjectPath {
+	return c.device
+}
+
+// Properties return the fd_properties received with the connection
+func (c *Conn) Properties() map[string]dbus.Variant {
+	return c.props
+}
+
+// Version return the profile version, if reported
+func (c *Conn) Version() (uint16, bool) {
+	return c.uint16Property("Version")
+}
+
+// Features return the profile features, if reported
+func (c *Conn) Features() (uint16, bool) {
+	return c.uint16Property("Features")
+}
+
+func (c *Conn) uint16Property(name string) (uint16, bool) {
+	v, ok := c.props[name]
+	if !ok {
+		return 0, false
+	}
+	value, ok := v.Value().(uint16)
+	return value, ok
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	n, err := c.file.Read(b)
+	return n, c.opError("read", err)
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	n, err := c.file.Write(b)
+	return n, c.opError("write", err)
+}
+
+// opError wrap the file errors as net.Conn does, eg. to report timeouts
+func (c *Conn) opError(op string, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if perr, ok := err.(*os.PathError); ok {
+		err = perr.Err
+	}
+	return &net.OpError{
+		Op:     op,
+		Net:    "bluetooth",
+		Source: c.LocalAddr(),
+		Addr:   c.RemoteAddr(),
+		Err:    err,
+	}
+}
+
+// Close the connection
+func (c *Conn) Close() error {
+	err := c.file.Close()
+	c.once.Do(func() {
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return err
+}
+
+// LocalAddr return the adapter address
+func (c *Conn) LocalAddr() net.Addr {
+	adapterID, err := adapter.ParseAdapterID(c.device)
+	if err != nil {
+		return Addr{}
+	}
+	return Addr{
+		Path: c.device[:strings.Index(string(c.device), adapterID)+len(adapterID)],
+	}
+}
+
+// RemoteAddr return the device address
+func (c *Conn) RemoteAddr() net.Addr {
+	addr := Addr{
+		Path: c.device,
+	}
+	spath := string(c.device)
+	if i := strings.LastIndex(spath, "/dev_"); i > -1 {
+		addr.Address = strings.Replace(spath[i+len("/dev_"):], "_", ":", -1)
+	}
+	return addr
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.file.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.file.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.file.SetWriteDeadline(t)
+}
+
+func (c *Conn) String() string {
+	return fmt.Sprintf("Conn(%s)", c.RemoteAddr())
+}
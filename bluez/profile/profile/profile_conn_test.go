@@ -0,0 +1,106 @@
+package profile
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDevice = dbus.ObjectPath("/org/bluez/hci0/dev_00_11_22_33_44_55")
+
+func testConnPair(t *testing.T) (*Conn, *os.File) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewConn(fds[0], testDevice, map[string]dbus.Variant{
+		"Version": dbus.MakeVariant(uint16(0x0102)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, os.NewFile(uintptr(fds[1]), "peer")
+}
+
+func TestConn(t *testing.T) {
+	c, peer := testConnPair(t)
+	defer peer.Close()
+
+	var _ net.Conn = c
+
+	assert.Equal(t, "00:11:22:33:44:55", c.RemoteAddr().String())
+	assert.Equal(t, "/org/bluez/hci0", c.LocalAddr().String())
+
+	version, ok := c.Version()
+	assert.True(t, ok)
+	assert.Equal(t, uint16(0x0102), version)
+	_, ok = c.Features()
+	assert.False(t, ok)
+
+	_, err := peer.Write([]byte("AT\r"))
+	assert.Nil(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(c, buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "AT\r", string(buf))
+
+	// deadline
+	c.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	_, err = c.Read(buf)
+	if assert.Error(t, err) {
+		nerr, ok := err.(net.Error)
+		assert.True(t, ok && nerr.Timeout())
+	}
+
+	closed := 0
+	c.onClose = func() { closed++ }
+	assert.Nil(t, c.Close())
+	c.Close()
+	assert.Equal(t, 1, closed)
+
+	_, err = peer.Read(buf)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServerConnections(t *testing.T) {
+	served := make(chan *Conn, 1)
+	s := &Server{
+		handler: HandlerFunc(func(c *Conn) { served <- c }),
+		conns:   map[*Conn]bool{},
+	}
+
+	c, peer := testConnPair(t)
+	defer peer.Close()
+
+	s.serve(c)
+	assert.Equal(t, c, <-served)
+	assert.Len(t, s.conns, 1)
+
+	// other devices are left connected
+	s.closeConns("/org/bluez/hci0/dev_66_77_88_99_AA_BB")
+	assert.Len(t, s.conns, 1)
+
+	s.closeConns(testDevice)
+	assert.Len(t, s.conns, 0)
+}
+
+func TestServeOptions(t *testing.T) {
+	m := ServeOptions{
+		Name:                 "Serial Port",
+		Role:                 RoleServer,
+		Channel:              22,
+		RequireAuthorization: true,
+	}.ToMap()
+	assert.Equal(t, map[string]interface{}{
+		"Name":                 "Serial Port",
+		"Role":                 RoleServer,
+		"Channel":              uint16(22),
+		"RequireAuthorization": true,
+	}, m)
+}
@@ -0,0 +1,58 @@
+package profile
+
+import (
+	"context"
+
+	"github.com/woongchantonylee/go-bluetooth/bluez/profile/device"
+	log "github.com/sirupsen/logrus"
+)
+
+// Dial connect a profile of a device through Device1.ConnectProfile. A client
+// profile is registered to receive the connection and released once the
+// connection is closed.
+func Dial(ctx context.Context, dev *device.Device1, uuid string) (*Conn, error) {
+
+	conns := make(chan *Conn, 1)
+	s, err := serve(uuid, ServeOptions{Role: RoleClient}, HandlerFunc(func(c *Conn) {
+		select {
+		case conns <- c:
+		default:
+			// a single connection is expected
+			c.Close()
+		}
+	}), true)
+	if err != nil {
+		return nil, err
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- dev.ConnectProfile(uuid)
+	}()
+
+	for {
+		select {
+		case c := <-conns:
+			return c, nil
+		case err := <-errc:
+			if err != nil {
+				s.Close()
+				return nil, err
+			}
+			// connected, wait for NewConnection
+			errc = nil
+		case <-ctx.Done():
+			derr := dev.DisconnectProfile(uuid)
+			if derr != nil {
+				log.Debugf("DisconnectProfile %s: %s", uuid, derr)
+			}
+			s.Close()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// DialSerial open a Serial Port Profile link to a device
+func DialSerial(ctx context.Context, dev *device.Device1) (*Conn, error) {
+	return Dial(ctx, dev, SerialPortUUID)
+}
@@ -0,0 +1,262 @@
+package profile
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+	"github.com/woongchantonylee/go-bluetooth/bluez"
+	log "github.com/sirupsen/logrus"
+)
+
+const ProfileBasePath = "/profile/serve%d"
+
+// SerialPortUUID is the Serial Port Profile UUID
+const SerialPortUUID = "00001101-0000-1000-8000-00805f9b34fb"
+
+// Profile roles
+const (
+	RoleClient = "client"
+	RoleServer = "server"
+)
+
+// ServeOptions are the RegisterProfile options, zero values are not sent
+type ServeOptions struct {
+	Name                  string
+	Service               string
+	Role                  string
+	Channel               uint16
+	PSM                   uint16
+	RequireAuthentication bool
+	RequireAuthorization  bool
+	AutoConnect           bool
+	ServiceRecord         string
+	Version               uint16
+	Features              uint16
+}
+
+// ToMap convert the options to the RegisterProfile dictionary
+func (o ServeOptions) ToMap() map[string]interface{} {
+	m := map[string]interface{}{}
+	if o.Name != "" {
+		m["Name"] = o.Name
+	}
+	if o.Service != "" {
+		m["Service"] = o.Service
+	}
+	if o.Role != "" {
+		m["Role"] = o.Role
+	}
+	if o.Channel != 0 {
+		m["Channel"] = o.Channel
+	}
+	if o.PSM != 0 {
+		m["PSM"] = o.PSM
+	}
+	if o.RequireAuthentication {
+		m["RequireAuthentication"] = true
+	}
+	if o.RequireAuthorization {
+		m["RequireAuthorization"] = true
+	}
+	if o.AutoConnect {
+		m["AutoConnect"] = true
+	}
+	if o.ServiceRecord != "" {
+		m["ServiceRecord"] = o.ServiceRecord
+	}
+	if o.Version != 0 {
+		m["Version"] = o.Version
+	}
+	if o.Features != 0 {
+		m["Features"] = o.Features
+	}
+	return m
+}
+
+// Handler serve a profile connection, the handler own the connection and
+// must close it
+type Handler interface {
+	ServeConn(conn *Conn)
+}
+
+// HandlerFunc adapt a function to a Handler
+type HandlerFunc func(conn *Conn)
+
+func (f HandlerFunc) ServeConn(conn *Conn) {
+	f(conn)
+}
+
+// Server is a profile implementation registered on BlueZ
+type Server struct {
+	UUID    string
+	Options ServeOptions
+
+	path    dbus.ObjectPath
+	conn    *dbus.Conn
+	handler Handler
+
+	lock  sync.Mutex
+	conns map[*Conn]bool
+	// closeWithConn close the server with its first connection, used by Dial
+	closeWithConn bool
+}
+
+// profileObject is the Profile1 object exported on DBus
+type profileObject struct {
+	server *Server
+}
+
+func (p *profileObject) Release() *dbus.Error {
+	log.Debugf("Profile %s: Release", p.server.path)
+	p.server.closeConns("")
+	return nil
+}
+
+func (p *profileObject) NewConnection(device dbus.ObjectPath, fd dbus.UnixFD, props map[string]dbus.Variant) *dbus.Error {
+	log.Debugf("Profile %s: NewConnection %s", p.server.path, device)
+	c, err := NewConn(int(fd), device, props)
+	if err != nil {
+		log.Warnf("Profile %s: %s", p.server.path, err)
+		return dbus.MakeFailedError(err)
+	}
+	p.server.serve(c)
+	return nil
+}
+
+func (p *profileObject) RequestDisconnection(device dbus.ObjectPath) *dbus.Error {
+	log.Debugf("Profile %s: RequestDisconnection %s", p.server.path, device)
+	p.server.closeConns(device)
+	return nil
+}
+
+// Serve export a Profile1 implementation and register it for uuid, each
+// incoming connection is served by handler in a new goroutine
+func Serve(uuid string, options ServeOptions, handler Handler) (*Server, error) {
+	return serve(uuid, options, handler, false)
+}
+
+func serve(uuid string, options ServeOptions, handler Handler, closeWithConn bool) (*Server, error) {
+
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		UUID:    uuid,
+		Options: options,
+		path:    bluez.NewObjectPath(ProfileBasePath),
+		conn:    conn,
+		handler: handler,
+		conns:   map[*Conn]bool{},
+
+		closeWithConn: closeWithConn,
+	}
+
+	err = s.export()
+	if err != nil {
+		s.unexport()
+		return nil, err
+	}
+
+	pm, err := NewProfileManager1()
+	if err != nil {
+		s.unexport()
+		return nil, err
+	}
+	defer pm.Close()
+
+	err = pm.RegisterProfile(s.path, uuid, options.ToMap())
+	if err != nil {
+		s.unexport()
+		return nil, fmt.Errorf("RegisterProfile %s: %s", uuid, err)
+	}
+
+	return s, nil
+}
+
+func (s *Server) export() error {
+
+	obj := &profileObject{server: s}
+
+	err := s.conn.Export(obj, s.path, Profile1Interface)
+	if err != nil {
+		return err
+	}
+
+	node := &introspect.Node{
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    Profile1Interface,
+				Methods: introspect.Methods(obj),
+			},
+		},
+	}
+
+	return s.conn.Export(introspect.NewIntrospectable(node), s.path, bluez.Introspectable)
+}
+
+func (s *Server) unexport() {
+	s.conn.Export(nil, s.path, Profile1Interface)
+	s.conn.Export(nil, s.path, bluez.Introspectable)
+	bluez.ReleaseObjectPath(s.path)
+}
+
+// Path return the profile object path
+func (s *Server) Path() dbus.ObjectPath {
+	return s.path
+}
+
+func (s *Server) serve(c *Conn) {
+	c.onClose = func() {
+		s.lock.Lock()
+		delete(s.conns, c)
+		s.lock.Unlock()
+		if s.closeWithConn {
+			err := s.Close()
+			if err != nil {
+				log.Warnf("Profile %s: %s", s.path, err)
+			}
+		}
+	}
+
+	s.lock.Lock()
+	s.conns[c] = true
+	s.lock.Unlock()
+
+	go s.handler.ServeConn(c)
+}
+
+// closeConns close the connections of a device, or all with an empty path
+func (s *Server) closeConns(device dbus.ObjectPath) {
+	s.lock.Lock()
+	conns := []*Conn{}
+	for c := range s.conns {
+		if device == "" || c.device == device {
+			conns = append(conns, c)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// Close unregister the profile and close its connections
+func (s *Server) Close() error {
+
+	defer s.unexport()
+	defer s.closeConns("")
+
+	pm, err := NewProfileManager1()
+	if err != nil {
+		return err
+	}
+	defer pm.Close()
+
+	return pm.UnregisterProfile(s.path)
+}
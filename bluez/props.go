@@ -2,6 +2,7 @@ package bluez
 
 import (
 	"reflect"
+	"sync"
 
 	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/util"
@@ -16,7 +17,20 @@ type WatchableClient interface {
 	SetWatchPropertiesChannel(chan *dbus.Signal)
 }
 
-// WatchProperties updates on property changes
+// propertiesWatch stop a WatchProperties goroutine in two steps: done stops
+// the delivery, released stops draining the signals once unregistered
+type propertiesWatch struct {
+	done     chan struct{}
+	released chan struct{}
+}
+
+var (
+	watchesLock sync.Mutex
+	watches     = map[chan *PropertyChanged]*propertiesWatch{}
+)
+
+// WatchProperties updates on property changes, the returned channel is
+// closed by UnwatchProperties
 func WatchProperties(wprop WatchableClient) (chan *PropertyChanged, error) {
 
 	channel, err := wprop.Client().Register(wprop.Path(), PropertiesInterface)
@@ -27,28 +41,52 @@ func WatchProperties(wprop WatchableClient) (chan *PropertyChanged, error) {
 	wprop.SetWatchPropertiesChannel(channel)
 	ch := make(chan *PropertyChanged)
 
+	w := &propertiesWatch{
+		done:     make(chan struct{}),
+		released: make(chan struct{}),
+	}
+	watchesLock.Lock()
+	watches[ch] = w
+	watchesLock.Unlock()
+
 	go (func() {
-		for {
 
-			if channel == nil {
-				break
-			}
+		defer close(ch)
 
-			sig := <-channel
+		done := w.done
+		for {
 
-			if sig == nil {
+			var sig *dbus.Signal
+			select {
+			case sig = <-channel:
+			case <-done:
+				// keep draining until unregistered, godbus blocks
+				// RemoveSignal while a delivery is pending
+				done = nil
+				continue
+			case <-w.released:
 				return
 			}
 
+			if sig == nil || done == nil {
+				continue
+			}
+
 			if sig.Name != PropertiesChanged {
 				continue
 			}
-			if sig.Path != wprop.Path() {
+			if sig.Path != wprop.Path() || len(sig.Body) < 2 {
 				continue
 			}
 
-			iface := sig.Body[0].(string)
-			changes := sig.Body[1].(map[string]dbus.Variant)
+			iface, ok := sig.Body[0].(string)
+			if !ok {
+				continue
+			}
+			changes, ok := sig.Body[1].(map[string]dbus.Variant)
+			if !ok {
+				continue
+			}
 
 			for field, val := range changes {
 
@@ -66,6 +104,7 @@ func WatchProperties(wprop WatchableClient) (chan *PropertyChanged, error) {
 						// map[*]variant -> map[*]interface{}
 						ok, err := util.AssignMapVariantToInterface(f, x)
 						if err != nil {
+							wprop.ToProps().Unlock()
 							log.Errorf("Failed to set %s: %s", f.String(), err)
 							continue
 						}
@@ -82,7 +121,14 @@ func WatchProperties(wprop WatchableClient) (chan *PropertyChanged, error) {
 					Name:      field,
 					Value:     val.Value(),
 				}
-				ch <- propChanged
+				select {
+				case ch <- propChanged:
+				case <-done:
+					done = nil
+				}
+				if done == nil {
+					break
+				}
 			}
 
 		}
@@ -91,9 +137,21 @@ func WatchProperties(wprop WatchableClient) (chan *PropertyChanged, error) {
 	return ch, nil
 }
 
+// UnwatchProperties stop a watch started by WatchProperties, ch is closed
+// once the signals are unregistered
 func UnwatchProperties(wprop WatchableClient, ch chan *PropertyChanged) error {
-	ch <- nil
-	close(ch)
+
+	watchesLock.Lock()
+	w, ok := watches[ch]
+	delete(watches, ch)
+	watchesLock.Unlock()
+	if !ok {
+		return nil
+	}
+
+	close(w.done)
+	defer close(w.released)
+
 	if wprop.GetWatchPropertiesChannel() != nil {
 		err := wprop.Client().Unregister(wprop.Path(), PropertiesInterface, wprop.GetWatchPropertiesChannel())
 		if err != nil {
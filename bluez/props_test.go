@@ -0,0 +1,107 @@
+package bluez
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWatchPath = dbus.ObjectPath("/test/watch")
+
+type testWatchProps struct {
+	sync.Mutex
+	Name string
+}
+
+func (p *testWatchProps) ToMap() (map[string]interface{}, error) {
+	return map[string]interface{}{"Name": p.Name}, nil
+}
+
+type testWatchClient struct {
+	client  *Client
+	props   *testWatchProps
+	channel chan *dbus.Signal
+}
+
+func (c *testWatchClient) Client() *Client                                { return c.client }
+func (c *testWatchClient) Path() dbus.ObjectPath                          { return testWatchPath }
+func (c *testWatchClient) ToProps() Properties                            { return c.props }
+func (c *testWatchClient) GetWatchPropertiesChannel() chan *dbus.Signal   { return c.channel }
+func (c *testWatchClient) SetWatchPropertiesChannel(ch chan *dbus.Signal) { c.channel = ch }
+
+func TestUnwatchProperties(t *testing.T) {
+
+	emitter, err := dbus.SessionBusPrivate()
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+	defer emitter.Close()
+	if err = emitter.Auth(nil); err == nil {
+		err = emitter.Hello()
+	}
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+
+	c := &testWatchClient{
+		client: NewClient(&Config{Bus: SessionBus}),
+		props:  &testWatchProps{},
+	}
+	ch, err := WatchProperties(c)
+	if err != nil {
+		t.Skipf("session bus: %s", err)
+	}
+
+	emit := func(name string) {
+		err := emitter.Emit(testWatchPath, PropertiesChanged, "org.test", map[string]dbus.Variant{
+			"Name": dbus.MakeVariant(name),
+		}, []string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	emit("first")
+	select {
+	case changed := <-ch:
+		assert.Equal(t, "first", changed.Value)
+		assert.Equal(t, "first", c.props.Name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("change not received")
+	}
+
+	// the changes are no longer read, the signals keep coming
+	for i := 0; i < 10; i++ {
+		emit("pending")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		done <- UnwatchProperties(c, ch)
+	}()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("UnwatchProperties blocked")
+	}
+
+	// the channel is closed once unwatched
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				// a second call is a no-op
+				assert.Nil(t, UnwatchProperties(c, ch))
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed")
+		}
+	}
+}
@@ -11,8 +11,9 @@
 package obex_push_example
 
 import (
+	"context"
+	"fmt"
 	"sync"
-	"time"
 
 	"github.com/woongchantonylee/go-bluetooth/api"
 	"github.com/woongchantonylee/go-bluetooth/bluez/profile/obex"
@@ -113,17 +114,18 @@ func Run(targetAddress, filePath, adapterID string) error {
 	log.Debug("Transferred : ", transProps.Transferred)
 	log.Debug("Filename    : ", transProps.Filename)
 
-	for transProps.Transferred < transProps.Size {
-		time.Sleep(1 * time.Second)
-
-		obexTransfer := obex.NewObexTransfer1(transPath)
-		transProps, err = obexTransfer.GetProperties()
-		if err != nil {
-			return err
-		}
-		transferedPercent := (100 / float64(transProps.Size)) * float64(transProps.Transferred)
+	obexTransfer := obex.NewObexTransfer1(transPath)
+	progress, err := obexTransfer.Progress(context.Background())
+	if err != nil {
+		return err
+	}
 
-		log.Debug("Progress    : ", transferedPercent)
+	var last obex.TransferProgress
+	for last = range progress {
+		log.Debugf("Progress    : %.0f%% %.0fB/s ETA %s", last.Percent(), last.Rate, last.ETA)
+	}
+	if last.Status != obex.TransferStatusComplete {
+		return fmt.Errorf("Transfer %s: %s", transPath, last.Status)
 	}
 
 	obexClient.RemoveSession(sessionPath)
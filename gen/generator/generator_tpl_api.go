@@ -232,6 +232,7 @@ func ApiTemplate(filename string, api *types.Api, apiGroup *types.ApiGroup) erro
 		Methods:          methods,
 		Constructors:     ctrs,
 		ExposeProperties: exposeProps,
+		Bus:              override.GetBus(api.Interface),
 	}
 
 	tmpl := loadtpl("api")
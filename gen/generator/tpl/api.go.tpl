@@ -3,6 +3,7 @@
 package {{.Package}}
 {{$InterfaceName := .InterfaceName}}
 {{$ExposeProperties := .ExposeProperties}}
+{{$Bus := .Bus}}
 
 {{.Imports}}
 
@@ -18,7 +19,7 @@ func New{{$InterfaceName}}{{.Role}}({{.Args}}) (*{{$InterfaceName}}, error) {
 			Name:  {{.Service}},
 			Iface: {{$InterfaceName}}Interface,
 			Path:  dbus.ObjectPath({{.ObjectPath}}),
-			Bus:   bluez.SystemBus,
+			Bus:   bluez.{{$Bus}},
 		},
 	)
 	{{if $ExposeProperties }}
@@ -0,0 +1,11 @@
+package override
+
+import "strings"
+
+// GetBus return the bus an interface is exposed on, obexd use the session bus
+func GetBus(iface string) string {
+	if strings.HasPrefix(iface, "org.bluez.obex.") {
+		return "SessionBus"
+	}
+	return "SystemBus"
+}
@@ -52,6 +52,7 @@ type ApiDoc struct {
 	Imports          string
 	Constructors     []Constructor
 	ExposeProperties bool
+	Bus              string
 }
 
 type Constructor struct {
@@ -1,6 +1,7 @@
 package props
 
 import (
+	"github.com/godbus/dbus"
 	"github.com/woongchantonylee/go-bluetooth/bluez"
 )
 
@@ -19,3 +20,17 @@ func ToMap(a bluez.Properties) map[string]interface{} {
 
 	return res
 }
+
+// ToVariantMap wrap the values of a map returned by the generated clients,
+// which may already hold variants
+func ToVariantMap(props map[string]interface{}) map[string]dbus.Variant {
+	m := make(map[string]dbus.Variant, len(props))
+	for k, v := range props {
+		if variant, ok := v.(dbus.Variant); ok {
+			m[k] = variant
+			continue
+		}
+		m[k] = dbus.MakeVariant(v)
+	}
+	return m
+}